@@ -0,0 +1,127 @@
+// Package auditoria guarda as alterações feitas no Zabbix pela aplicação em
+// um arquivo local, um registro JSON por linha
+package auditoria
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Resultados de uma ação registrada
+const (
+	ResultadoSucesso = "sucesso"
+	ResultadoFalha   = "falha"
+)
+
+// Registro é uma ação executada no servidor Zabbix
+type Registro struct {
+	Momento   time.Time              `json:"momento"`
+	Perfil    string                 `json:"perfil"`            // Nome do perfil usado
+	Servidor  string                 `json:"servidor"`          // URL da API
+	Usuario   string                 `json:"usuario,omitempty"` // Usuário do perfil, quando autentica com senha
+	Origem    string                 `json:"origem,omitempty"`  // Endereço de quem fez o pedido pela interface web
+	Acao      string                 `json:"acao"`              // Método da API ou operação, ex: "event.acknowledge"
+	Alvos     []string               `json:"alvos"`             // IDs dos objetos alterados
+	Detalhes  map[string]interface{} `json:"detalhes,omitempty"`
+	Resultado string                 `json:"resultado"`
+	Erro      string                 `json:"erro,omitempty"`
+}
+
+// Registrador acrescenta registros ao arquivo de auditoria. É seguro para uso
+// simultâneo por vários pedidos.
+type Registrador struct {
+	mu      sync.Mutex
+	caminho string
+}
+
+// Novo cria um registrador que escreve em caminho
+func Novo(caminho string) *Registrador {
+	return &Registrador{caminho: caminho}
+}
+
+// CaminhoPadrao retorna o arquivo de auditoria ao lado da configuração
+func CaminhoPadrao() string {
+	diretorioHome, err := os.UserHomeDir()
+	if err != nil {
+		diretorioHome, _ = os.Getwd()
+	}
+	return filepath.Join(diretorioHome, ".zabbix-manager", "auditoria.log")
+}
+
+// Caminho retorna o arquivo onde os registros são gravados
+func (r *Registrador) Caminho() string {
+	return r.caminho
+}
+
+// Registrar acrescenta o registro ao fim do arquivo, preenchendo o momento se vazio
+func (r *Registrador) Registrar(registro Registro) error {
+	if registro.Momento.IsZero() {
+		registro.Momento = time.Now()
+	}
+
+	linha, err := json.Marshal(registro)
+	if err != nil {
+		return fmt.Errorf("erro ao codificar registro de auditoria: %w", err)
+	}
+	linha = append(linha, '\n')
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(r.caminho), 0755); err != nil {
+		return fmt.Errorf("erro ao criar diretório de auditoria: %w", err)
+	}
+	arquivo, err := os.OpenFile(r.caminho, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("erro ao abrir arquivo de auditoria: %w", err)
+	}
+	if _, err := arquivo.Write(linha); err != nil {
+		arquivo.Close()
+		return fmt.Errorf("erro ao gravar registro de auditoria: %w", err)
+	}
+	return arquivo.Close()
+}
+
+// Ultimos retorna até n registros, do mais recente para o mais antigo. Linhas
+// que não puderem ser lidas são ignoradas.
+func (r *Registrador) Ultimos(n int) ([]Registro, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	arquivo, err := os.Open(r.caminho)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao abrir arquivo de auditoria: %w", err)
+	}
+	defer arquivo.Close()
+
+	// Guarda apenas os n últimos enquanto percorre o arquivo
+	var ultimos []Registro
+	leitor := bufio.NewScanner(arquivo)
+	leitor.Buffer(make([]byte, 64*1024), 1024*1024)
+	for leitor.Scan() {
+		var registro Registro
+		if err := json.Unmarshal(leitor.Bytes(), &registro); err != nil {
+			continue
+		}
+		ultimos = append(ultimos, registro)
+		if len(ultimos) > n {
+			ultimos = ultimos[1:]
+		}
+	}
+	if err := leitor.Err(); err != nil {
+		return nil, fmt.Errorf("erro ao ler arquivo de auditoria: %w", err)
+	}
+
+	for i, j := 0, len(ultimos)-1; i < j; i, j = i+1, j-1 {
+		ultimos[i], ultimos[j] = ultimos[j], ultimos[i]
+	}
+	return ultimos, nil
+}
@@ -0,0 +1,185 @@
+// Package backup grava periodicamente a configuração dos servidores Zabbix em
+// arquivos JSON compactados, um diretório por perfil
+package backup
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Agenda é uma expressão cron de cinco campos já interpretada:
+// "minuto hora dia mês dia-da-semana"
+type Agenda struct {
+	texto string
+
+	// O bit i de cada campo marca o valor i
+	minutos, horas, dias, meses, diasSemana uint64
+
+	// Como no cron, se dia e dia da semana forem restritos basta um deles
+	diaRestrito, semanaRestrita bool
+}
+
+// campoCron descreve um campo da expressão e seus valores aceitos
+type campoCron struct {
+	nome     string
+	min, max int
+}
+
+var camposCron = [5]campoCron{
+	{"minuto", 0, 59},
+	{"hora", 0, 23},
+	{"dia", 1, 31},
+	{"mês", 1, 12},
+	{"dia da semana", 0, 7}, // 0 e 7 são domingo
+}
+
+// atalhosCron são as abreviações aceitas no lugar dos cinco campos
+var atalhosCron = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+}
+
+// ParseAgenda interpreta uma expressão cron, como "30 2 * * *" (todo dia às
+// 02:30) ou "0 */6 * * 1-5" (a cada 6 horas, de segunda a sexta). Cada campo
+// aceita "*", valores, faixas "a-b", passos "/n" e listas separadas por vírgula.
+func ParseAgenda(texto string) (*Agenda, error) {
+	texto = strings.TrimSpace(texto)
+	expressao := texto
+	if atalho, ok := atalhosCron[strings.ToLower(texto)]; ok {
+		expressao = atalho
+	}
+
+	partes := strings.Fields(expressao)
+	if len(partes) != len(camposCron) {
+		return nil, fmt.Errorf("agenda inválida %q: use cinco campos (minuto hora dia mês dia-da-semana)", texto)
+	}
+
+	var bits [5]uint64
+	for i, parte := range partes {
+		valor, err := parseCampo(parte, camposCron[i])
+		if err != nil {
+			return nil, fmt.Errorf("agenda inválida %q: %w", texto, err)
+		}
+		bits[i] = valor
+	}
+
+	// Domingo pode ser 0 ou 7
+	if bits[4]&(1<<7) != 0 {
+		bits[4] = bits[4]&^(1<<7) | 1
+	}
+
+	return &Agenda{
+		texto:          texto,
+		minutos:        bits[0],
+		horas:          bits[1],
+		dias:           bits[2],
+		meses:          bits[3],
+		diasSemana:     bits[4],
+		diaRestrito:    !strings.HasPrefix(partes[2], "*"),
+		semanaRestrita: !strings.HasPrefix(partes[4], "*"),
+	}, nil
+}
+
+// parseCampo interpreta um campo da expressão
+func parseCampo(texto string, campo campoCron) (uint64, error) {
+	var bits uint64
+	for _, parte := range strings.Split(texto, ",") {
+		passo := 1
+		comPasso := false
+		if i := strings.Index(parte, "/"); i >= 0 {
+			n, err := strconv.Atoi(parte[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("passo inválido no campo %s: %q", campo.nome, parte)
+			}
+			passo, comPasso = n, true
+			parte = parte[:i]
+		}
+
+		inicio, fim := campo.min, campo.max
+		switch {
+		case parte == "*":
+		case strings.Contains(parte, "-"):
+			limites := strings.SplitN(parte, "-", 2)
+			a, errA := strconv.Atoi(limites[0])
+			b, errB := strconv.Atoi(limites[1])
+			if errA != nil || errB != nil || a > b {
+				return 0, fmt.Errorf("faixa inválida no campo %s: %q", campo.nome, parte)
+			}
+			inicio, fim = a, b
+		default:
+			n, err := strconv.Atoi(parte)
+			if err != nil {
+				return 0, fmt.Errorf("valor inválido no campo %s: %q", campo.nome, parte)
+			}
+			// "5/15" vai de 5 até o fim, a cada 15
+			inicio = n
+			if !comPasso {
+				fim = n
+			}
+		}
+
+		if inicio < campo.min || fim > campo.max {
+			return 0, fmt.Errorf("o campo %s aceita de %d a %d: %q", campo.nome, campo.min, campo.max, parte)
+		}
+		for v := inicio; v <= fim; v += passo {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+// String retorna a expressão como foi informada
+func (a *Agenda) String() string {
+	return a.texto
+}
+
+// contem informa se o bit do valor está marcado
+func contem(bits uint64, valor int) bool {
+	return bits&(1<<valor) != 0
+}
+
+// diaConfere informa se o dia de t atende aos campos de dia e dia da semana
+func (a *Agenda) diaConfere(t time.Time) bool {
+	dia := contem(a.dias, t.Day())
+	semana := contem(a.diasSemana, int(t.Weekday()))
+	if a.diaRestrito && a.semanaRestrita {
+		return dia || semana
+	}
+	return dia && semana
+}
+
+// Proxima retorna o primeiro minuto depois de t que atende à agenda, no fuso
+// de t. Retorna o instante zero se nenhum minuto dos próximos cinco anos
+// atender, como em "0 0 30 2 *".
+func (a *Agenda) Proxima(t time.Time) time.Time {
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, t.Location()).Add(time.Minute)
+	limite := t.AddDate(5, 0, 0)
+
+	for t.Before(limite) {
+		if !contem(a.meses, int(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !a.diaConfere(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !contem(a.horas, t.Hour()) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if !contem(a.minutos, t.Minute()) {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
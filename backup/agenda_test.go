@@ -0,0 +1,87 @@
+package backup
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseAgendaInvalida(t *testing.T) {
+	casos := []struct {
+		nome, expressao string
+	}{
+		{"vazia", ""},
+		{"quatro campos", "0 * * *"},
+		{"seis campos", "0 0 * * * *"},
+		{"minuto acima do limite", "60 * * * *"},
+		{"hora acima do limite", "0 24 * * *"},
+		{"dia zero", "0 0 0 * *"},
+		{"mês acima do limite", "0 0 * 13 *"},
+		{"dia da semana acima do limite", "0 0 * * 8"},
+		{"faixa invertida", "0 0 * * 5-1"},
+		{"faixa incompleta", "0 1- * * *"},
+		{"passo zero", "*/0 * * * *"},
+		{"passo inválido", "*/x * * * *"},
+		{"valor não numérico", "a * * * *"},
+		{"item vazio na lista", "0 1,,2 * * *"},
+		{"atalho desconhecido", "@semanal"},
+	}
+	for _, caso := range casos {
+		t.Run(caso.nome, func(t *testing.T) {
+			if _, err := ParseAgenda(caso.expressao); err == nil {
+				t.Errorf("ParseAgenda(%q) deveria falhar", caso.expressao)
+			}
+		})
+	}
+}
+
+func TestProxima(t *testing.T) {
+	// Sexta-feira, 1º de março de 2024
+	agora := time.Date(2024, time.March, 1, 10, 17, 30, 0, time.UTC)
+	data := func(mes time.Month, dia, hora, minuto int) time.Time {
+		return time.Date(2024, mes, dia, hora, minuto, 0, 0, time.UTC)
+	}
+
+	casos := []struct {
+		nome      string
+		expressao string
+		esperado  time.Time
+	}{
+		{"todo dia", "30 2 * * *", data(time.March, 2, 2, 30)},
+		{"mesmo minuto vai para o dia seguinte", "17 10 * * *", data(time.March, 2, 10, 17)},
+		{"passo", "*/15 * * * *", data(time.March, 1, 10, 30)},
+		{"passo a partir de um valor", "5/20 * * * *", data(time.March, 1, 10, 25)},
+		{"faixa com passo", "0 9-17/4 * * *", data(time.March, 1, 13, 0)},
+		{"lista", "0 8,12,20 * * *", data(time.March, 1, 12, 0)},
+		{"lista de dias", "0 0 1,15 * *", data(time.March, 15, 0, 0)},
+		{"dias úteis", "0 0 * * 1-5", data(time.March, 4, 0, 0)},
+		{"domingo como 7", "0 12 * * 7", data(time.March, 3, 12, 0)},
+		{"domingo como 0", "0 12 * * 0", data(time.March, 3, 12, 0)},
+		{"mês restrito", "0 0 1 6 *", data(time.June, 1, 0, 0)},
+		// Com dia e dia da semana restritos, basta um deles: a próxima sexta
+		// chega antes do dia 13
+		{"dia ou dia da semana", "0 0 13 * 5", data(time.March, 8, 0, 0)},
+		{"dia ou dia da semana, dia primeiro", "0 0 4 * 6", data(time.March, 2, 0, 0)},
+		// Com o dia da semana em "*", só o dia conta
+		{"dia com semana livre", "0 0 13 * *", data(time.March, 13, 0, 0)},
+		{"dia da semana com dia em passo", "0 0 */1 * 3", data(time.March, 6, 0, 0)},
+		{"29 de fevereiro", "0 0 29 2 *", time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{"data inexistente", "0 0 30 2 *", time.Time{}},
+		{"atalho", "@daily", data(time.March, 2, 0, 0)},
+		{"atalho semanal", "@weekly", data(time.March, 3, 0, 0)},
+		{"atalho em maiúsculas", "@MONTHLY", data(time.April, 1, 0, 0)},
+	}
+	for _, caso := range casos {
+		t.Run(caso.nome, func(t *testing.T) {
+			agenda, err := ParseAgenda(caso.expressao)
+			if err != nil {
+				t.Fatalf("ParseAgenda(%q): %v", caso.expressao, err)
+			}
+			if obtido := agenda.Proxima(agora); !obtido.Equal(caso.esperado) {
+				t.Errorf("Proxima = %s, esperava %s", obtido, caso.esperado)
+			}
+			if agenda.String() != caso.expressao {
+				t.Errorf("String = %q, esperava %q", agenda.String(), caso.expressao)
+			}
+		})
+	}
+}
@@ -0,0 +1,286 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"zabbix-manager/config"
+	"zabbix-manager/zabbix"
+)
+
+// Alvo é um perfil cuja configuração é copiada. Cliente é chamado a cada cópia
+// e retorna também a função que encerra o uso do cliente.
+type Alvo struct {
+	Perfil  string
+	Cliente func() (*zabbix.ClienteAPI, func(), error)
+}
+
+// Estado é a situação das cópias de um perfil
+type Estado struct {
+	Perfil          string
+	Diretorio       string
+	Arquivos        []Arquivo // Da mais recente para a mais antiga
+	ErroDiretorio   string    // Falha ao ler o diretório do perfil
+	UltimaTentativa time.Time // Zero se nenhuma cópia foi tentada desde o início da aplicação
+	UltimoErro      string
+	MomentoErro     time.Time
+}
+
+// UltimaCopia retorna a cópia mais recente do perfil ou nil se não houver
+func (e Estado) UltimaCopia() *Arquivo {
+	if len(e.Arquivos) == 0 {
+		return nil
+	}
+	return &e.Arquivos[0]
+}
+
+// Falhando informa se a última tentativa de cópia falhou
+func (e Estado) Falhando() bool {
+	return e.UltimoErro != "" && e.MomentoErro.Equal(e.UltimaTentativa)
+}
+
+// Situacao é a configuração do agendador e o estado de cada perfil
+type Situacao struct {
+	Agenda     string // Vazia com as cópias agendadas desativadas
+	Diretorio  string
+	Retencao   Retencao
+	Proxima    time.Time // Zero sem agenda
+	EmExecucao bool
+	Perfis     []Estado
+}
+
+// falhaPerfil guarda a última tentativa e o último erro de um perfil
+type falhaPerfil struct {
+	tentativa time.Time
+	erro      string
+	momento   time.Time
+}
+
+// Agendador executa as cópias de todos os perfis na agenda configurada, uma
+// de cada vez. É seguro para uso simultâneo.
+type Agendador struct {
+	alvos func() []Alvo
+
+	mu         sync.Mutex
+	config     config.ConfiguracaoBackup
+	agenda     *Agenda
+	proxima    time.Time
+	emExecucao bool
+	falhas     map[string]*falhaPerfil
+
+	reconfigurado chan struct{}
+	pedidos       chan struct{}
+}
+
+// NovoAgendador cria um agendador sem agenda; alvos retorna os perfis atuais
+// a cada execução
+func NovoAgendador(alvos func() []Alvo) *Agendador {
+	return &Agendador{
+		alvos:         alvos,
+		falhas:        make(map[string]*falhaPerfil),
+		reconfigurado: make(chan struct{}, 1),
+		pedidos:       make(chan struct{}, 1),
+	}
+}
+
+// Configurar aplica uma nova configuração e recalcula a próxima execução
+func (a *Agendador) Configurar(c config.ConfiguracaoBackup) error {
+	var agenda *Agenda
+	if c.Ativo() {
+		var err error
+		if agenda, err = ParseAgenda(c.Agenda); err != nil {
+			return err
+		}
+		if agenda.Proxima(time.Now()).IsZero() {
+			return fmt.Errorf("a agenda %q nunca é executada", c.Agenda)
+		}
+	}
+	if c.ManterQuantidade < 0 || c.ManterDias < 0 {
+		return errors.New("a retenção não pode ser negativa")
+	}
+
+	a.mu.Lock()
+	a.config = c
+	a.agenda = agenda
+	a.mu.Unlock()
+
+	sinalizar(a.reconfigurado)
+	return nil
+}
+
+// Solicitar pede uma cópia imediata de todos os perfis, mesmo sem agenda.
+// Um pedido feito durante uma execução é atendido ao fim dela.
+func (a *Agendador) Solicitar() {
+	sinalizar(a.pedidos)
+}
+
+// sinalizar avisa o laço do agendador sem bloquear; avisos repetidos se juntam
+func sinalizar(canal chan struct{}) {
+	select {
+	case canal <- struct{}{}:
+	default:
+	}
+}
+
+// Iniciar executa as cópias em segundo plano até o contexto ser cancelado
+func (a *Agendador) Iniciar(ctx context.Context) {
+	go a.laco(ctx)
+}
+
+// laco espera o próximo horário da agenda, um pedido ou uma nova configuração
+func (a *Agendador) laco(ctx context.Context) {
+	for {
+		a.mu.Lock()
+		a.proxima = time.Time{}
+		if a.agenda != nil {
+			a.proxima = a.agenda.Proxima(time.Now())
+		}
+		proxima := a.proxima
+		a.mu.Unlock()
+
+		var temporizador *time.Timer
+		var horario <-chan time.Time
+		if !proxima.IsZero() {
+			temporizador = time.NewTimer(time.Until(proxima))
+			horario = temporizador.C
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-a.reconfigurado:
+		case <-a.pedidos:
+			a.executar(ctx)
+		case <-horario:
+			a.executar(ctx)
+		}
+		if temporizador != nil {
+			temporizador.Stop()
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// executar copia a configuração de cada perfil e aplica a retenção
+func (a *Agendador) executar(ctx context.Context) {
+	a.mu.Lock()
+	a.emExecucao = true
+	base := a.diretorio()
+	retencao := a.retencao()
+	a.mu.Unlock()
+
+	defer func() {
+		a.mu.Lock()
+		a.emExecucao = false
+		a.mu.Unlock()
+	}()
+
+	for _, alvo := range a.alvos() {
+		if ctx.Err() != nil {
+			return
+		}
+
+		diretorio := DiretorioPerfil(base, alvo.Perfil)
+		agora := time.Now()
+		arquivo, err := copiarAlvo(ctx, alvo, diretorio, agora)
+		if err == nil {
+			log.Printf("Cópia de segurança do perfil %q gravada em %s (%d bytes)", alvo.Perfil, arquivo.Caminho, arquivo.Tamanho)
+			var removidos []Arquivo
+			removidos, err = AplicarRetencao(diretorio, retencao, agora)
+			if len(removidos) > 0 {
+				log.Printf("%d cópia(s) antiga(s) do perfil %q removida(s)", len(removidos), alvo.Perfil)
+			}
+		}
+		if err != nil {
+			log.Printf("Falha na cópia de segurança do perfil %q: %v", alvo.Perfil, err)
+		}
+		a.registrarTentativa(alvo.Perfil, agora, err)
+	}
+}
+
+// copiarAlvo obtém o cliente do perfil e grava a cópia
+func copiarAlvo(ctx context.Context, alvo Alvo, diretorio string, agora time.Time) (Arquivo, error) {
+	cliente, encerrar, err := alvo.Cliente()
+	if err != nil {
+		return Arquivo{}, err
+	}
+	defer encerrar()
+	return Copiar(ctx, cliente, diretorio, agora)
+}
+
+// registrarTentativa guarda o resultado da última cópia de um perfil
+func (a *Agendador) registrarTentativa(perfil string, agora time.Time, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	falha, ok := a.falhas[perfil]
+	if !ok {
+		falha = &falhaPerfil{}
+		a.falhas[perfil] = falha
+	}
+	falha.tentativa = agora
+	if err != nil {
+		falha.erro = err.Error()
+		falha.momento = agora
+	}
+}
+
+// diretorio retorna o diretório base configurado ou o padrão; requer a.mu
+func (a *Agendador) diretorio() string {
+	if a.config.Diretorio != "" {
+		return a.config.Diretorio
+	}
+	return DiretorioPadrao()
+}
+
+// retencao converte a retenção configurada; requer a.mu
+func (a *Agendador) retencao() Retencao {
+	return Retencao{
+		Quantidade: a.config.ManterQuantidade,
+		Idade:      time.Duration(a.config.ManterDias) * 24 * time.Hour,
+	}
+}
+
+// Situacao retorna a configuração, a próxima execução e o estado de cada
+// perfil atual, com as cópias encontradas no disco
+func (a *Agendador) Situacao() Situacao {
+	a.mu.Lock()
+	situacao := Situacao{
+		Agenda:     a.config.Agenda,
+		Diretorio:  a.diretorio(),
+		Retencao:   a.retencao(),
+		Proxima:    a.proxima,
+		EmExecucao: a.emExecucao,
+	}
+	if a.agenda == nil {
+		situacao.Agenda = ""
+	}
+	falhas := make(map[string]falhaPerfil, len(a.falhas))
+	for perfil, falha := range a.falhas {
+		falhas[perfil] = *falha
+	}
+	a.mu.Unlock()
+
+	for _, alvo := range a.alvos() {
+		falha := falhas[alvo.Perfil]
+		estado := Estado{
+			Perfil:          alvo.Perfil,
+			Diretorio:       DiretorioPerfil(situacao.Diretorio, alvo.Perfil),
+			UltimaTentativa: falha.tentativa,
+			UltimoErro:      falha.erro,
+			MomentoErro:     falha.momento,
+		}
+		arquivos, err := Listar(estado.Diretorio)
+		if err != nil {
+			estado.ErroDiretorio = err.Error()
+		}
+		estado.Arquivos = arquivos
+		situacao.Perfis = append(situacao.Perfis, estado)
+	}
+	return situacao
+}
@@ -0,0 +1,217 @@
+package backup
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"zabbix-manager/zabbix"
+)
+
+// Nome dos arquivos de cópia: zbx_backup_AAAAMMDD-HHMMSS.json.gz
+const (
+	prefixoArquivo  = "zbx_backup_"
+	extensaoArquivo = ".json.gz"
+	formatoMomento  = "20060102-150405"
+)
+
+// DiretorioPadrao retorna o diretório das cópias ao lado da configuração
+func DiretorioPadrao() string {
+	diretorioHome, err := os.UserHomeDir()
+	if err != nil {
+		diretorioHome, _ = os.Getwd()
+	}
+	return filepath.Join(diretorioHome, ".zabbix-manager", "backups")
+}
+
+// DiretorioPerfil retorna o diretório das cópias de um perfil. Separadores de
+// caminho no nome do perfil são trocados por "_".
+func DiretorioPerfil(base, perfil string) string {
+	nome := strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ':' || r < ' ' {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(perfil))
+	if nome == "" || strings.Trim(nome, ".") == "" {
+		nome = "_" + nome
+	}
+	return filepath.Join(base, nome)
+}
+
+// Retencao limita as cópias guardadas de cada perfil; zero não limita
+type Retencao struct {
+	Quantidade int           // Cópias mais recentes mantidas
+	Idade      time.Duration // Cópias mais antigas que isso são removidas
+}
+
+// Arquivo é uma cópia gravada no disco
+type Arquivo struct {
+	Caminho string
+	Momento time.Time // Momento da cópia, tirado do nome do arquivo
+	Tamanho int64     // Bytes compactados
+}
+
+// Nome retorna o nome do arquivo, sem o diretório
+func (a Arquivo) Nome() string {
+	return filepath.Base(a.Caminho)
+}
+
+// Copiar exporta toda a configuração do servidor (templates, grupos de hosts,
+// hosts, mapas e tipos de mídia) e a grava em diretorio, compactada
+func Copiar(ctx context.Context, cliente *zabbix.ClienteAPI, diretorio string, agora time.Time) (Arquivo, error) {
+	objetos, err := cliente.ObterTodosObjetosConfiguracaoCtx(ctx)
+	if err != nil {
+		return Arquivo{}, fmt.Errorf("erro ao listar objetos: %w", err)
+	}
+	if objetos.Vazio() {
+		return Arquivo{}, errors.New("o servidor não tem objetos para exportar")
+	}
+
+	conteudo, err := cliente.ExportarConfiguracaoCtx(ctx, objetos, zabbix.FormatoJSON)
+	if err != nil {
+		return Arquivo{}, fmt.Errorf("erro ao exportar configuração: %w", err)
+	}
+	return gravar(diretorio, agora, conteudo)
+}
+
+// gravar escreve o conteúdo compactado em um arquivo temporário e o renomeia
+// ao final, para que uma cópia interrompida não pareça completa
+func gravar(diretorio string, agora time.Time, conteudo string) (Arquivo, error) {
+	if err := os.MkdirAll(diretorio, 0700); err != nil {
+		return Arquivo{}, fmt.Errorf("erro ao criar diretório de cópias: %w", err)
+	}
+
+	nome := prefixoArquivo + agora.Format(formatoMomento) + extensaoArquivo
+	temporario, err := os.CreateTemp(diretorio, nome+".*.tmp")
+	if err != nil {
+		return Arquivo{}, fmt.Errorf("erro ao criar arquivo de cópia: %w", err)
+	}
+	descartar := func(err error) (Arquivo, error) {
+		temporario.Close()
+		os.Remove(temporario.Name())
+		return Arquivo{}, fmt.Errorf("erro ao gravar arquivo de cópia: %w", err)
+	}
+
+	compactador := gzip.NewWriter(temporario)
+	compactador.Name = strings.TrimSuffix(nome, ".gz")
+	compactador.ModTime = agora
+	if _, err := io.WriteString(compactador, conteudo); err != nil {
+		return descartar(err)
+	}
+	if err := compactador.Close(); err != nil {
+		return descartar(err)
+	}
+	if err := temporario.Close(); err != nil {
+		return descartar(err)
+	}
+
+	caminho := filepath.Join(diretorio, nome)
+	if err := os.Rename(temporario.Name(), caminho); err != nil {
+		os.Remove(temporario.Name())
+		return Arquivo{}, fmt.Errorf("erro ao gravar arquivo de cópia: %w", err)
+	}
+
+	info, err := os.Stat(caminho)
+	if err != nil {
+		return Arquivo{}, fmt.Errorf("erro ao gravar arquivo de cópia: %w", err)
+	}
+	return Arquivo{Caminho: caminho, Momento: agora, Tamanho: info.Size()}, nil
+}
+
+// Listar retorna as cópias de um diretório, da mais recente para a mais
+// antiga. Outros arquivos são ignorados.
+func Listar(diretorio string) ([]Arquivo, error) {
+	entradas, err := os.ReadDir(diretorio)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler diretório de cópias: %w", err)
+	}
+
+	var arquivos []Arquivo
+	for _, entrada := range entradas {
+		nome := entrada.Name()
+		if entrada.IsDir() || !strings.HasPrefix(nome, prefixoArquivo) || !strings.HasSuffix(nome, extensaoArquivo) {
+			continue
+		}
+		momento, err := time.ParseInLocation(formatoMomento, strings.TrimSuffix(strings.TrimPrefix(nome, prefixoArquivo), extensaoArquivo), time.Local)
+		if err != nil {
+			continue
+		}
+		info, err := entrada.Info()
+		if err != nil {
+			continue
+		}
+		arquivos = append(arquivos, Arquivo{
+			Caminho: filepath.Join(diretorio, nome),
+			Momento: momento,
+			Tamanho: info.Size(),
+		})
+	}
+
+	sort.Slice(arquivos, func(i, j int) bool {
+		return arquivos[i].Momento.After(arquivos[j].Momento)
+	})
+	return arquivos, nil
+}
+
+// AplicarRetencao remove as cópias além da quantidade ou mais antigas que a
+// idade da retenção e retorna as removidas. A cópia mais recente nunca é
+// removida, para que falhas seguidas não apaguem todas.
+func AplicarRetencao(diretorio string, retencao Retencao, agora time.Time) ([]Arquivo, error) {
+	arquivos, err := Listar(diretorio)
+	if err != nil {
+		return nil, err
+	}
+
+	var removidos []Arquivo
+	var primeiroErro error
+	for i, arquivo := range arquivos {
+		if i == 0 {
+			continue
+		}
+		excedente := retencao.Quantidade > 0 && i >= retencao.Quantidade
+		antigo := retencao.Idade > 0 && agora.Sub(arquivo.Momento) > retencao.Idade
+		if !excedente && !antigo {
+			continue
+		}
+		if err := os.Remove(arquivo.Caminho); err != nil {
+			if primeiroErro == nil {
+				primeiroErro = fmt.Errorf("erro ao remover cópia antiga: %w", err)
+			}
+			continue
+		}
+		removidos = append(removidos, arquivo)
+	}
+	return removidos, primeiroErro
+}
+
+// Ler retorna o conteúdo descompactado de uma cópia
+func Ler(caminho string) ([]byte, error) {
+	arquivo, err := os.Open(caminho)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao abrir cópia: %w", err)
+	}
+	defer arquivo.Close()
+
+	descompactador, err := gzip.NewReader(arquivo)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao descompactar cópia: %w", err)
+	}
+	defer descompactador.Close()
+
+	conteudo, err := io.ReadAll(descompactador)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao descompactar cópia: %w", err)
+	}
+	return conteudo, nil
+}
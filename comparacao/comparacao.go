@@ -0,0 +1,266 @@
+// Package comparacao compara dois documentos exportados por configuration.export
+// em JSON. Objetos em listas são pareados pela chave, pela macro ou pelo nome,
+// e não pela posição, e os UUIDs são ignorados; assim só aparecem as mudanças
+// de configuração, e não diferenças de ordem ou de identificadores internos.
+package comparacao
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Tipos de mudança
+const (
+	Adicionado = "adicionado"
+	Removido   = "removido"
+	Alterado   = "alterado"
+)
+
+// Mudanca é uma diferença entre os documentos. O caminho é formado pelos nomes
+// dos campos e, nas listas, pela identidade do objeto, como a chave de um item:
+// ["templates", "Linux by Zabbix agent", "items", "system.cpu.util", "delay"].
+type Mudanca struct {
+	Caminho []string    `json:"caminho"`
+	Tipo    string      `json:"tipo"`
+	Antes   interface{} `json:"antes,omitempty"`
+	Depois  interface{} `json:"depois,omitempty"`
+}
+
+// Texto retorna o caminho separado por " › "
+func (m Mudanca) Texto() string {
+	return strings.Join(m.Caminho, " › ")
+}
+
+// Resultado são as mudanças do primeiro documento para o segundo
+type Resultado struct {
+	VersaoAntes  string    `json:"versaoAntes"` // Versão do formato de exportação
+	VersaoDepois string    `json:"versaoDepois"`
+	Mudancas     []Mudanca `json:"mudancas"`
+}
+
+// camposIdentidade são os campos que identificam um objeto dentro de uma
+// lista, na ordem de preferência. Objetos sem nenhum deles são pareados pela
+// posição, como os passos de pré-processamento, em que a ordem importa.
+var camposIdentidade = [][]string{
+	{"key"},           // Itens, regras de descoberta e protótipos
+	{"macro"},         // Macros de usuário
+	{"interface_ref"}, // Interfaces de hosts
+	{"host"},          // Hosts
+	{"template"},      // Templates
+	{"tag", "value"},  // Tags; a mesma tag pode aparecer com valores diferentes
+	{"name"},          // Triggers, gráficos, grupos, templates vinculados, mapas etc.
+}
+
+// camposIgnorados não são comparados em nenhum nível do documento
+var camposIgnorados = map[string]bool{
+	"uuid": true,
+}
+
+// Comparar compara dois documentos JSON de configuration.export
+func Comparar(antes, depois []byte) (Resultado, error) {
+	documentoAntes, versaoAntes, err := lerDocumento(antes)
+	if err != nil {
+		return Resultado{}, fmt.Errorf("primeiro documento: %w", err)
+	}
+	documentoDepois, versaoDepois, err := lerDocumento(depois)
+	if err != nil {
+		return Resultado{}, fmt.Errorf("segundo documento: %w", err)
+	}
+
+	resultado := Resultado{VersaoAntes: versaoAntes, VersaoDepois: versaoDepois}
+	comparar(nil, documentoAntes, documentoDepois, &resultado.Mudancas)
+	return resultado, nil
+}
+
+// lerDocumento decodifica o documento e retorna o conteúdo de "zabbix_export",
+// sem a versão e a data da exportação
+func lerDocumento(dados []byte) (map[string]interface{}, string, error) {
+	decodificador := json.NewDecoder(bytes.NewReader(dados))
+	decodificador.UseNumber()
+
+	var raiz map[string]interface{}
+	if err := decodificador.Decode(&raiz); err != nil {
+		return nil, "", fmt.Errorf("JSON inválido: %w", err)
+	}
+	documento, ok := raiz["zabbix_export"].(map[string]interface{})
+	if !ok {
+		return nil, "", errors.New("não é um documento exportado pelo Zabbix em JSON")
+	}
+
+	versao, _ := documento["version"].(string)
+	delete(documento, "version")
+	delete(documento, "date")
+	removerIgnorados(documento)
+	return documento, versao, nil
+}
+
+// removerIgnorados apaga os campos de camposIgnorados em todos os níveis, para
+// que não apareçam nem nos objetos adicionados ou removidos
+func removerIgnorados(valor interface{}) {
+	switch v := valor.(type) {
+	case map[string]interface{}:
+		for campo, filho := range v {
+			if camposIgnorados[campo] {
+				delete(v, campo)
+				continue
+			}
+			removerIgnorados(filho)
+		}
+	case []interface{}:
+		for _, filho := range v {
+			removerIgnorados(filho)
+		}
+	}
+}
+
+// comparar acrescenta as diferenças entre a e b, no caminho, às mudanças
+func comparar(caminho []string, a, b interface{}, mudancas *[]Mudanca) {
+	switch valorA := a.(type) {
+	case map[string]interface{}:
+		if valorB, ok := b.(map[string]interface{}); ok {
+			compararObjetos(caminho, valorA, valorB, mudancas)
+			return
+		}
+	case []interface{}:
+		if valorB, ok := b.([]interface{}); ok {
+			compararListas(caminho, valorA, valorB, mudancas)
+			return
+		}
+	}
+	if !reflect.DeepEqual(a, b) {
+		*mudancas = append(*mudancas, Mudanca{Caminho: copiar(caminho), Tipo: Alterado, Antes: a, Depois: b})
+	}
+}
+
+// compararObjetos compara os campos de dois objetos, em ordem alfabética
+func compararObjetos(caminho []string, a, b map[string]interface{}, mudancas *[]Mudanca) {
+	campos := make([]string, 0, len(a)+len(b))
+	for campo := range a {
+		campos = append(campos, campo)
+	}
+	for campo := range b {
+		if _, ok := a[campo]; !ok {
+			campos = append(campos, campo)
+		}
+	}
+	sort.Strings(campos)
+
+	for _, campo := range campos {
+		valorA, temA := a[campo]
+		valorB, temB := b[campo]
+		subcaminho := append(caminho, campo)
+		switch {
+		case !temB:
+			*mudancas = append(*mudancas, Mudanca{Caminho: copiar(subcaminho), Tipo: Removido, Antes: valorA})
+		case !temA:
+			*mudancas = append(*mudancas, Mudanca{Caminho: copiar(subcaminho), Tipo: Adicionado, Depois: valorB})
+		default:
+			comparar(subcaminho, valorA, valorB, mudancas)
+		}
+	}
+}
+
+// compararListas pareia os objetos das listas pela identidade. Listas de
+// valores simples são comparadas inteiras, pois a ordem pode importar.
+func compararListas(caminho []string, a, b []interface{}, mudancas *[]Mudanca) {
+	idsA, objetosA, okA := identificar(a)
+	idsB, objetosB, okB := identificar(b)
+	if !okA || !okB {
+		if !reflect.DeepEqual(a, b) {
+			*mudancas = append(*mudancas, Mudanca{Caminho: copiar(caminho), Tipo: Alterado, Antes: a, Depois: b})
+		}
+		return
+	}
+
+	for _, id := range idsA {
+		subcaminho := append(caminho, id)
+		if objetoB, ok := objetosB[id]; ok {
+			comparar(subcaminho, objetosA[id], objetoB, mudancas)
+		} else {
+			*mudancas = append(*mudancas, Mudanca{Caminho: copiar(subcaminho), Tipo: Removido, Antes: objetosA[id]})
+		}
+	}
+	for _, id := range idsB {
+		if _, ok := objetosA[id]; !ok {
+			*mudancas = append(*mudancas, Mudanca{Caminho: copiar(append(caminho, id)), Tipo: Adicionado, Depois: objetosB[id]})
+		}
+	}
+}
+
+// identificar retorna a identidade de cada objeto da lista, na ordem, e os
+// objetos por identidade. Retorna false se algum elemento não for um objeto.
+func identificar(lista []interface{}) ([]string, map[string]interface{}, bool) {
+	ids := make([]string, 0, len(lista))
+	objetos := make(map[string]interface{}, len(lista))
+	for i, elemento := range lista {
+		objeto, ok := elemento.(map[string]interface{})
+		if !ok {
+			return nil, nil, false
+		}
+		id, ok := identidade(objeto)
+		if !ok {
+			id = "#" + strconv.Itoa(i+1)
+		}
+		// Identidades repetidas, como triggers de mesmo nome, recebem um número
+		if _, repetida := objetos[id]; repetida {
+			for n := 2; ; n++ {
+				if _, existe := objetos[fmt.Sprintf("%s (%d)", id, n)]; !existe {
+					id = fmt.Sprintf("%s (%d)", id, n)
+					break
+				}
+			}
+		}
+		ids = append(ids, id)
+		objetos[id] = objeto
+	}
+	return ids, objetos, true
+}
+
+// identidade monta a identidade do objeto com o primeiro grupo de campos
+// de camposIdentidade cujo primeiro campo estiver presente
+func identidade(objeto map[string]interface{}) (string, bool) {
+	for _, campos := range camposIdentidade {
+		if _, ok := objeto[campos[0]]; !ok {
+			continue
+		}
+		partes := make([]string, len(campos))
+		for i, campo := range campos {
+			partes[i] = Formatar(objeto[campo])
+		}
+		return strings.Join(partes, "="), true
+	}
+	return "", false
+}
+
+// copiar evita que mudanças compartilhem o vetor do caminho
+func copiar(caminho []string) []string {
+	return append([]string(nil), caminho...)
+}
+
+// Formatar retorna um valor do documento como texto: textos e números como
+// estão e objetos e listas em JSON indentado
+func Formatar(valor interface{}) string {
+	switch v := valor.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	}
+	// Sem escapar "<" e "&", que aparecem em expressões de triggers
+	var saida bytes.Buffer
+	codificador := json.NewEncoder(&saida)
+	codificador.SetEscapeHTML(false)
+	codificador.SetIndent("", "  ")
+	if err := codificador.Encode(valor); err != nil {
+		return fmt.Sprint(valor)
+	}
+	return strings.TrimSuffix(saida.String(), "\n")
+}
@@ -0,0 +1,87 @@
+package comparacao
+
+import "strings"
+
+// NomesTipos traduz as listas de primeiro nível do documento exportado
+var NomesTipos = map[string]string{
+	"templates":       "Template",
+	"hosts":           "Host",
+	"groups":          "Grupo",
+	"host_groups":     "Grupo de hosts",
+	"template_groups": "Grupo de templates",
+	"triggers":        "Trigger",
+	"graphs":          "Gráfico",
+	"maps":            "Mapa",
+	"media_types":     "Tipo de mídia",
+	"value_maps":      "Mapa de valores",
+	"images":          "Imagem",
+}
+
+// Grupo são as mudanças de um objeto de primeiro nível, como um template ou
+// um host, com os caminhos relativos ao objeto
+type Grupo struct {
+	Tipo     string // Lista de primeiro nível, ex: "templates"
+	Nome     string // Identidade do objeto; vazia para mudanças fora de objetos
+	Situacao string // Adicionado, Removido ou Alterado
+	Mudancas []Mudanca
+}
+
+// NomeTipo retorna o nome do tipo para exibição
+func (g Grupo) NomeTipo() string {
+	if nome, ok := NomesTipos[g.Tipo]; ok {
+		return nome
+	}
+	return g.Tipo
+}
+
+// Grupos separa as mudanças por objeto de primeiro nível, na ordem em que
+// aparecem. Um objeto inteiro adicionado ou removido forma um grupo com uma
+// única mudança de caminho vazio.
+func (r Resultado) Grupos() []Grupo {
+	var grupos []Grupo
+	indices := make(map[string]int)
+	for _, mudanca := range r.Mudancas {
+		tipo, nome := "", ""
+		if len(mudanca.Caminho) > 0 {
+			tipo = mudanca.Caminho[0]
+		}
+		if len(mudanca.Caminho) > 1 {
+			nome = mudanca.Caminho[1]
+		}
+
+		chave := tipo + "\x00" + nome
+		i, ok := indices[chave]
+		if !ok {
+			i = len(grupos)
+			indices[chave] = i
+			grupos = append(grupos, Grupo{Tipo: tipo, Nome: nome, Situacao: Alterado})
+		}
+
+		relativa := mudanca
+		if len(mudanca.Caminho) == 2 {
+			grupos[i].Situacao = mudanca.Tipo
+		}
+		if len(mudanca.Caminho) >= 2 {
+			relativa.Caminho = mudanca.Caminho[2:]
+		}
+		grupos[i].Mudancas = append(grupos[i].Mudancas, relativa)
+	}
+	return grupos
+}
+
+// Filtrar mantém apenas as mudanças dos objetos de primeiro nível cujo nome
+// contém o termo, sem diferenciar maiúsculas
+func (r Resultado) Filtrar(termo string) Resultado {
+	termo = strings.ToLower(strings.TrimSpace(termo))
+	if termo == "" {
+		return r
+	}
+
+	filtrado := Resultado{VersaoAntes: r.VersaoAntes, VersaoDepois: r.VersaoDepois}
+	for _, mudanca := range r.Mudancas {
+		if len(mudanca.Caminho) > 1 && strings.Contains(strings.ToLower(mudanca.Caminho[1]), termo) {
+			filtrado.Mudancas = append(filtrado.Mudancas, mudanca)
+		}
+	}
+	return filtrado
+}
@@ -0,0 +1,134 @@
+// Package declarativo lê as definições de hosts e templates mantidas em
+// arquivos YAML, normalmente em um repositório git, para que o plano de
+// zabbix.PlanejarDeclaracao deixe o servidor como os arquivos.
+//
+//	templates:
+//	  - template: Template App Web
+//	    groups: [Templates/Aplicações]
+//	    templates: [Linux by Zabbix agent]
+//	hosts:
+//	  - host: web-01
+//	    groups: [Servidores Web]
+//	    templates: [Template App Web]
+//	    macros:
+//	      - macro: "{$HTTP.PORT}"
+//	        value: "8080"
+//	    tags:
+//	      - tag: ambiente
+//	        value: produção
+//	    interfaces:
+//	      - type: agent
+//	        ip: 10.0.0.11
+//	delete_missing:
+//	  host_groups: [Servidores Web]
+package declarativo
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"zabbix-manager/zabbix"
+)
+
+// Carregar lê os arquivos e diretórios informados e junta as definições. Em
+// diretórios, os arquivos .yaml e .yml são lidos em ordem, incluindo os
+// subdiretórios, exceto os ocultos como .git. Retorna também os arquivos lidos.
+func Carregar(caminhos ...string) (zabbix.Declaracao, []string, error) {
+	var declaracao zabbix.Declaracao
+	if len(caminhos) == 0 {
+		return declaracao, nil, errors.New("nenhum arquivo ou diretório informado")
+	}
+
+	var arquivos []string
+	for _, caminho := range caminhos {
+		encontrados, err := listarArquivos(caminho)
+		if err != nil {
+			return declaracao, nil, err
+		}
+		arquivos = append(arquivos, encontrados...)
+	}
+	if len(arquivos) == 0 {
+		return declaracao, nil, fmt.Errorf("nenhum arquivo YAML encontrado em %s", strings.Join(caminhos, ", "))
+	}
+
+	for _, arquivo := range arquivos {
+		lida, err := LerArquivo(arquivo)
+		if err != nil {
+			return declaracao, nil, err
+		}
+		declaracao.Juntar(lida)
+	}
+	if err := declaracao.Validar(); err != nil {
+		return declaracao, nil, err
+	}
+	return declaracao, arquivos, nil
+}
+
+// listarArquivos retorna o próprio arquivo ou os arquivos YAML do diretório
+func listarArquivos(caminho string) ([]string, error) {
+	info, err := os.Stat(caminho)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao abrir %s: %w", caminho, err)
+	}
+	if !info.IsDir() {
+		return []string{caminho}, nil
+	}
+
+	var arquivos []string
+	err = filepath.WalkDir(caminho, func(atual string, entrada fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entrada.IsDir() {
+			if atual != caminho && strings.HasPrefix(entrada.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if extensao := strings.ToLower(filepath.Ext(atual)); extensao == ".yaml" || extensao == ".yml" {
+			arquivos = append(arquivos, atual)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler diretório %s: %w", caminho, err)
+	}
+	sort.Strings(arquivos)
+	return arquivos, nil
+}
+
+// LerArquivo lê um arquivo YAML, que pode ter vários documentos separados por
+// "---". Campos desconhecidos são recusados, para que erros de digitação não
+// passem como campos omitidos.
+func LerArquivo(caminho string) (zabbix.Declaracao, error) {
+	var declaracao zabbix.Declaracao
+
+	arquivo, err := os.Open(caminho)
+	if err != nil {
+		return declaracao, fmt.Errorf("erro ao abrir %s: %w", caminho, err)
+	}
+	defer arquivo.Close()
+
+	decodificador := yaml.NewDecoder(arquivo)
+	decodificador.KnownFields(true)
+	for {
+		var documento zabbix.Declaracao
+		err := decodificador.Decode(&documento)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return declaracao, fmt.Errorf("%s: %w", caminho, err)
+		}
+		declaracao.Juntar(documento)
+	}
+	return declaracao, nil
+}
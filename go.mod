@@ -2,6 +2,4 @@ module zabbix-manager
 
 go 1.21
 
-require (
-
-)
\ No newline at end of file
+require gopkg.in/yaml.v3 v3.0.1
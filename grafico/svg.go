@@ -0,0 +1,337 @@
+// Package grafico desenha gráficos de séries temporais em SVG no servidor,
+// sem depender de bibliotecas JavaScript no navegador
+package grafico
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Ponto é um valor da série. Quando os valores vêm resumidos (tendências),
+// Minimo e Maximo formam a faixa desenhada em volta da linha da média.
+type Ponto struct {
+	Momento time.Time
+	Minimo  float64
+	Media   float64
+	Maximo  float64
+}
+
+// Opcoes controla o tamanho, os textos e o período do gráfico
+type Opcoes struct {
+	Largura int
+	Altura  int
+	Titulo  string
+	Unidade string
+
+	// Período exibido no eixo horizontal; se vazio, vai do primeiro ao último ponto
+	Inicio time.Time
+	Fim    time.Time
+
+	// Desenha a faixa entre mínimo e máximo
+	Faixa bool
+}
+
+// Margens da área de desenho, em pixels
+const (
+	margemEsquerda = 70
+	margemDireita  = 15
+	margemSuperior = 28
+	margemInferior = 30
+)
+
+// Linha escreve em w um gráfico de linha com os pontos, que devem estar em
+// ordem cronológica. Trechos sem coleta aparecem como interrupções da linha.
+func Linha(w io.Writer, pontos []Ponto, opcoes Opcoes) error {
+	if opcoes.Largura <= 0 {
+		opcoes.Largura = 800
+	}
+	if opcoes.Altura <= 0 {
+		opcoes.Altura = 300
+	}
+	if opcoes.Inicio.IsZero() && len(pontos) > 0 {
+		opcoes.Inicio = pontos[0].Momento
+	}
+	if opcoes.Fim.IsZero() && len(pontos) > 0 {
+		opcoes.Fim = pontos[len(pontos)-1].Momento
+	}
+
+	largura := opcoes.Largura - margemEsquerda - margemDireita
+	altura := opcoes.Altura - margemSuperior - margemInferior
+
+	var svg strings.Builder
+	fmt.Fprintf(&svg, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" width="100%%" font-family="sans-serif" font-size="11">`,
+		opcoes.Largura, opcoes.Altura)
+	fmt.Fprintf(&svg, `<rect x="0" y="0" width="%d" height="%d" fill="#fff"/>`, opcoes.Largura, opcoes.Altura)
+	if opcoes.Titulo != "" {
+		fmt.Fprintf(&svg, `<text x="%d" y="17" font-size="13" font-weight="bold">%s</text>`,
+			margemEsquerda, html.EscapeString(opcoes.Titulo))
+	}
+
+	pontos = reduzir(pontos, opcoes.Inicio, opcoes.Fim, largura)
+	if len(pontos) == 0 || !opcoes.Fim.After(opcoes.Inicio) {
+		fmt.Fprintf(&svg, `<text x="%d" y="%d" text-anchor="middle" fill="#6c757d">Sem dados no período</text>`,
+			opcoes.Largura/2, opcoes.Altura/2)
+		svg.WriteString(`</svg>`)
+		_, err := io.WriteString(w, svg.String())
+		return err
+	}
+
+	// Escala vertical com valores "redondos"
+	minimo, maximo := limites(pontos, opcoes.Faixa)
+	marcas, passo := marcasValores(minimo, maximo, 5)
+	minimo, maximo = marcas[0], marcas[len(marcas)-1]
+
+	duracao := opcoes.Fim.Sub(opcoes.Inicio).Seconds()
+	x := func(t time.Time) float64 {
+		return margemEsquerda + t.Sub(opcoes.Inicio).Seconds()/duracao*float64(largura)
+	}
+	y := func(v float64) float64 {
+		return margemSuperior + (maximo-v)/(maximo-minimo)*float64(altura)
+	}
+
+	// Grade e rótulos do eixo vertical
+	for _, valor := range marcas {
+		fmt.Fprintf(&svg, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" stroke="#e9ecef"/>`,
+			margemEsquerda, y(valor), margemEsquerda+largura, y(valor))
+		fmt.Fprintf(&svg, `<text x="%d" y="%.1f" text-anchor="end" dominant-baseline="middle" fill="#495057">%s</text>`,
+			margemEsquerda-6, y(valor), html.EscapeString(FormatarValor(valor, opcoes.Unidade, passo)))
+	}
+
+	// Grade e rótulos do eixo horizontal
+	intervalo, formato := marcasTempo(opcoes.Fim.Sub(opcoes.Inicio))
+	for t := primeiraMarca(opcoes.Inicio, intervalo); t.Before(opcoes.Fim); t = t.Add(intervalo) {
+		fmt.Fprintf(&svg, `<line x1="%.1f" y1="%d" x2="%.1f" y2="%d" stroke="#e9ecef"/>`,
+			x(t), margemSuperior, x(t), margemSuperior+altura)
+		fmt.Fprintf(&svg, `<text x="%.1f" y="%d" text-anchor="middle" fill="#495057">%s</text>`,
+			x(t), margemSuperior+altura+16, t.Format(formato))
+	}
+	fmt.Fprintf(&svg, `<rect x="%d" y="%d" width="%d" height="%d" fill="none" stroke="#adb5bd"/>`,
+		margemEsquerda, margemSuperior, largura, altura)
+
+	for _, trecho := range trechos(pontos) {
+		if opcoes.Faixa {
+			var faixa strings.Builder
+			for _, p := range trecho {
+				fmt.Fprintf(&faixa, "%.1f,%.1f ", x(p.Momento), y(p.Maximo))
+			}
+			for i := len(trecho) - 1; i >= 0; i-- {
+				fmt.Fprintf(&faixa, "%.1f,%.1f ", x(trecho[i].Momento), y(trecho[i].Minimo))
+			}
+			fmt.Fprintf(&svg, `<polygon points="%s" fill="#0d6efd" fill-opacity="0.15" stroke="none"/>`,
+				strings.TrimSpace(faixa.String()))
+		}
+
+		if len(trecho) == 1 {
+			fmt.Fprintf(&svg, `<circle cx="%.1f" cy="%.1f" r="2" fill="#0d6efd"/>`, x(trecho[0].Momento), y(trecho[0].Media))
+			continue
+		}
+		var linha strings.Builder
+		for _, p := range trecho {
+			fmt.Fprintf(&linha, "%.1f,%.1f ", x(p.Momento), y(p.Media))
+		}
+		fmt.Fprintf(&svg, `<polyline points="%s" fill="none" stroke="#0d6efd" stroke-width="1.5"/>`,
+			strings.TrimSpace(linha.String()))
+	}
+
+	svg.WriteString(`</svg>`)
+	_, err := io.WriteString(w, svg.String())
+	return err
+}
+
+// reduzir agrupa os pontos em no máximo um por pixel da largura, mantendo o
+// mínimo, o máximo e a média de cada grupo. Pontos fora do período são descartados.
+func reduzir(pontos []Ponto, inicio, fim time.Time, largura int) []Ponto {
+	var dentro []Ponto
+	for _, p := range pontos {
+		if !p.Momento.Before(inicio) && !p.Momento.After(fim) {
+			dentro = append(dentro, p)
+		}
+	}
+	if len(dentro) <= largura || largura <= 0 {
+		return dentro
+	}
+
+	tamanho := fim.Sub(inicio) / time.Duration(largura)
+	var reduzidos []Ponto
+	var grupo []Ponto
+	fechar := func() {
+		if len(grupo) == 0 {
+			return
+		}
+		r := Ponto{Momento: grupo[len(grupo)/2].Momento, Minimo: grupo[0].Minimo, Maximo: grupo[0].Maximo}
+		soma := 0.0
+		for _, p := range grupo {
+			r.Minimo = math.Min(r.Minimo, p.Minimo)
+			r.Maximo = math.Max(r.Maximo, p.Maximo)
+			soma += p.Media
+		}
+		r.Media = soma / float64(len(grupo))
+		reduzidos = append(reduzidos, r)
+		grupo = grupo[:0]
+	}
+
+	atual := int64(-1)
+	for _, p := range dentro {
+		indice := int64(p.Momento.Sub(inicio) / tamanho)
+		if indice != atual {
+			fechar()
+			atual = indice
+		}
+		grupo = append(grupo, p)
+	}
+	fechar()
+	return reduzidos
+}
+
+// trechos separa a série onde o intervalo entre dois pontos passa de três
+// vezes o intervalo típico, indicando que não houve coleta
+func trechos(pontos []Ponto) [][]Ponto {
+	if len(pontos) < 3 {
+		return [][]Ponto{pontos}
+	}
+
+	intervalos := make([]time.Duration, 0, len(pontos)-1)
+	for i := 1; i < len(pontos); i++ {
+		intervalos = append(intervalos, pontos[i].Momento.Sub(pontos[i-1].Momento))
+	}
+	sort.Slice(intervalos, func(i, j int) bool { return intervalos[i] < intervalos[j] })
+	limite := 3 * intervalos[len(intervalos)/2]
+
+	var resultado [][]Ponto
+	inicio := 0
+	for i := 1; i < len(pontos); i++ {
+		if pontos[i].Momento.Sub(pontos[i-1].Momento) > limite {
+			resultado = append(resultado, pontos[inicio:i])
+			inicio = i
+		}
+	}
+	return append(resultado, pontos[inicio:])
+}
+
+// limites retorna o menor e o maior valor desenhado
+func limites(pontos []Ponto, faixa bool) (float64, float64) {
+	minimo, maximo := math.Inf(1), math.Inf(-1)
+	for _, p := range pontos {
+		if faixa {
+			minimo = math.Min(minimo, p.Minimo)
+			maximo = math.Max(maximo, p.Maximo)
+		} else {
+			minimo = math.Min(minimo, p.Media)
+			maximo = math.Max(maximo, p.Media)
+		}
+	}
+	return minimo, maximo
+}
+
+// marcasValores escolhe cerca de quantidade marcas com passo 1, 2 ou 5 vezes
+// uma potência de dez que cubram o intervalo entre minimo e maximo
+func marcasValores(minimo, maximo float64, quantidade int) ([]float64, float64) {
+	if minimo == maximo {
+		ajuste := math.Abs(minimo) / 10
+		if ajuste == 0 {
+			ajuste = 1
+		}
+		minimo, maximo = minimo-ajuste, maximo+ajuste
+	}
+
+	bruto := (maximo - minimo) / float64(quantidade)
+	potencia := math.Pow(10, math.Floor(math.Log10(bruto)))
+	passo := potencia * 10
+	for _, fator := range []float64{1, 2, 5} {
+		if fator*potencia >= bruto {
+			passo = fator * potencia
+			break
+		}
+	}
+
+	// Contar os passos em inteiros evita resíduos de ponto flutuante nos rótulos.
+	// Passos fracionários (0,1, 0,2, 0,5...) são divisões por um inteiro, já que
+	// 3*0.1 não é exatamente 0.3 mas 3/10 é.
+	marca := func(i float64) float64 { return i * passo }
+	if passo < 1 {
+		divisor := math.Round(1 / passo)
+		marca = func(i float64) float64 { return i / divisor }
+	}
+	primeiro := math.Floor(minimo / passo)
+	ultimo := math.Ceil(maximo / passo)
+	var marcas []float64
+	for i := primeiro; i <= ultimo; i++ {
+		marcas = append(marcas, marca(i)+0) // +0 troca "-0" por 0
+	}
+	return marcas, passo
+}
+
+// marcasTempo escolhe o intervalo entre as marcas do eixo horizontal e o
+// formato dos rótulos conforme a duração do período
+func marcasTempo(duracao time.Duration) (time.Duration, string) {
+	opcoes := []struct {
+		intervalo time.Duration
+		formato   string
+	}{
+		{5 * time.Minute, "15:04"},
+		{10 * time.Minute, "15:04"},
+		{30 * time.Minute, "15:04"},
+		{time.Hour, "15:04"},
+		{3 * time.Hour, "02/01 15h"},
+		{6 * time.Hour, "02/01 15h"},
+		{12 * time.Hour, "02/01 15h"},
+		{24 * time.Hour, "02/01"},
+		{2 * 24 * time.Hour, "02/01"},
+		{7 * 24 * time.Hour, "02/01"},
+		{30 * 24 * time.Hour, "02/01/06"},
+	}
+	for _, o := range opcoes {
+		if duracao/o.intervalo <= 8 {
+			return o.intervalo, o.formato
+		}
+	}
+	ultima := opcoes[len(opcoes)-1]
+	return ultima.intervalo, ultima.formato
+}
+
+// primeiraMarca é o primeiro múltiplo do intervalo depois do início. Marcas
+// de um dia ou mais começam à meia-noite do fuso local.
+func primeiraMarca(inicio time.Time, intervalo time.Duration) time.Time {
+	if intervalo < 24*time.Hour {
+		return inicio.Truncate(intervalo).Add(intervalo)
+	}
+	ano, mes, dia := inicio.Date()
+	return time.Date(ano, mes, dia+1, 0, 0, 0, 0, inicio.Location())
+}
+
+// FormatarValor formata um valor com prefixos K, M, G e T. Unidades em bytes
+// usam potências de 1024, como no frontend do Zabbix. O passo indica a precisão
+// necessária para diferenciar valores vizinhos.
+func FormatarValor(valor float64, unidade string, passo float64) string {
+	base := 1000.0
+	if unidade == "B" || unidade == "Bps" {
+		base = 1024
+	}
+
+	prefixo := ""
+	if unidade != "" && unidade != "%" && unidade != "s" {
+		for _, p := range []string{"K", "M", "G", "T"} {
+			if math.Abs(valor) < base && math.Abs(passo) < base {
+				break
+			}
+			valor /= base
+			passo /= base
+			prefixo = p
+		}
+	}
+
+	casas := 0
+	if passo > 0 && passo < 1 {
+		casas = int(math.Ceil(-math.Log10(passo)))
+	}
+	texto := fmt.Sprintf("%.*f", casas, valor)
+	if unidade == "" {
+		return texto
+	}
+	return texto + " " + prefixo + unidade
+}
@@ -0,0 +1,120 @@
+package grafico
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// instanteGrafico é a origem dos horários dos testes de gráfico
+var instanteGrafico = time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+// minutos retorna o instante m minutos depois de instanteGrafico
+func minutos(m int) time.Time {
+	return instanteGrafico.Add(time.Duration(m) * time.Minute)
+}
+
+// valor é um ponto de histórico, com mínimo, média e máximo iguais
+func valor(m int, v float64) Ponto {
+	return Ponto{Momento: minutos(m), Minimo: v, Media: v, Maximo: v}
+}
+
+func TestReduzir(t *testing.T) {
+	casos := []struct {
+		nome     string
+		pontos   []Ponto
+		largura  int
+		esperado []Ponto
+	}{
+		{"sem pontos", nil, 10, nil},
+		{
+			"cabe na largura",
+			[]Ponto{valor(0, 1), valor(30, 2), valor(60, 3)},
+			10,
+			[]Ponto{valor(0, 1), valor(30, 2), valor(60, 3)},
+		},
+		{
+			"fora do período descartados",
+			[]Ponto{valor(-10, 9), valor(10, 1), valor(70, 9)},
+			10,
+			[]Ponto{valor(10, 1)},
+		},
+		{
+			"largura zero não reduz",
+			[]Ponto{valor(0, 1), valor(1, 2), valor(2, 3)},
+			0,
+			[]Ponto{valor(0, 1), valor(1, 2), valor(2, 3)},
+		},
+		{
+			// Dois grupos de 30 minutos; o momento é o do ponto do meio de cada um
+			"agrupa por pixel",
+			[]Ponto{valor(0, 1), valor(10, 5), valor(20, 3), valor(30, 2), valor(50, 4)},
+			2,
+			[]Ponto{
+				{Momento: minutos(10), Minimo: 1, Media: 3, Maximo: 5},
+				{Momento: minutos(50), Minimo: 2, Media: 3, Maximo: 4},
+			},
+		},
+		{
+			// Tendências já resumidas mantêm a faixa mais larga do grupo
+			"mantém a faixa das tendências",
+			[]Ponto{
+				{Momento: minutos(0), Minimo: 2, Media: 4, Maximo: 6},
+				{Momento: minutos(20), Minimo: 1, Media: 2, Maximo: 3},
+				{Momento: minutos(40), Minimo: 5, Media: 8, Maximo: 9},
+			},
+			2,
+			[]Ponto{
+				{Momento: minutos(20), Minimo: 1, Media: 3, Maximo: 6},
+				{Momento: minutos(40), Minimo: 5, Media: 8, Maximo: 9},
+			},
+		},
+		{
+			"pula pixels sem pontos",
+			[]Ponto{valor(0, 1), valor(5, 3), valor(55, 7)},
+			2,
+			[]Ponto{
+				{Momento: minutos(5), Minimo: 1, Media: 2, Maximo: 3},
+				valor(55, 7),
+			},
+		},
+	}
+	for _, caso := range casos {
+		t.Run(caso.nome, func(t *testing.T) {
+			obtido := reduzir(caso.pontos, minutos(0), minutos(60), caso.largura)
+			if !reflect.DeepEqual(obtido, caso.esperado) {
+				t.Errorf("reduzir = %v, esperava %v", obtido, caso.esperado)
+			}
+		})
+	}
+}
+
+func TestMarcasValores(t *testing.T) {
+	casos := []struct {
+		nome           string
+		minimo, maximo float64
+		quantidade     int
+		marcas         []float64
+		passo          float64
+	}{
+		{"passo 1", 0, 5, 5, []float64{0, 1, 2, 3, 4, 5}, 1},
+		{"passo 2", 0, 9, 5, []float64{0, 2, 4, 6, 8, 10}, 2},
+		{"passo 5", 3, 22, 5, []float64{0, 5, 10, 15, 20, 25}, 5},
+		{"pontas arredondadas para fora", 13, 87, 5, []float64{0, 20, 40, 60, 80, 100}, 20},
+		{"frações", 0, 0.5, 5, []float64{0, 0.1, 0.2, 0.3, 0.4, 0.5}, 0.1},
+		{"negativos", -7, 3, 5, []float64{-8, -6, -4, -2, 0, 2, 4}, 2},
+		{"valor constante", 50, 50, 5, []float64{44, 46, 48, 50, 52, 54, 56}, 2},
+		{"zero constante", 0, 0, 4, []float64{-1, -0.5, 0, 0.5, 1}, 0.5},
+	}
+	for _, caso := range casos {
+		t.Run(caso.nome, func(t *testing.T) {
+			marcas, passo := marcasValores(caso.minimo, caso.maximo, caso.quantidade)
+			if passo != caso.passo {
+				t.Errorf("passo = %v, esperava %v", passo, caso.passo)
+			}
+			if !reflect.DeepEqual(marcas, caso.marcas) {
+				t.Errorf("marcas = %v, esperava %v", marcas, caso.marcas)
+			}
+		})
+	}
+}
@@ -1,17 +1,33 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"html/template"
+	"io"
 	"log"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
+	"zabbix-manager/auditoria"
+	"zabbix-manager/backup"
+	"zabbix-manager/comparacao"
 	"zabbix-manager/config"
+	"zabbix-manager/declarativo"
+	"zabbix-manager/grafico"
 	"zabbix-manager/zabbix"
 )
 
@@ -23,23 +39,293 @@ type PaginaLogin struct {
 	PerfilAtivo  int
 	ModoEdicao   bool
 	PerfilEditar *config.ConfiguracaoPerfil
+	IndiceEditar int
+
+	// Cópias de segurança agendadas, exibidas na página de configuração
+	ConfigBackup config.ConfiguracaoBackup
+	Backup       *backup.Situacao
 }
 
 type PaginaPrincipal struct {
-	NomeServidor    string
-	URLServidor     string
-	Hosts           []zabbix.Host
-	TermoBusca      string
-	MensagemErro    string
-	MensagemSucesso string
+	NomeServidor     string
+	URLServidor      string
+	IndicePerfil     int
+	Hosts            []zabbix.ResumoHost
+	TermoBusca       string
+	Grupos           []zabbix.GrupoHost
+	Templates        []zabbix.TemplateVinculado
+	FiltroGrupo      string
+	FiltroTemplate   string
+	FiltroTag        string
+	ParametrosFiltro string // Filtros atuais codificados; vazio sem filtros
+	URLAtual         string // Endereço da listagem, para voltar após atualizar
+
+	MensagensPagina
+}
+
+// MensagensPagina são os avisos comuns às páginas que consultam o servidor
+type MensagensPagina struct {
+	MensagemErro     string
+	MensagemSucesso  string
+	ErroAutenticacao bool
+	TentarEm         int // Segundos até a próxima tentativa com o disjuntor aberto
+}
+
+// PaginaHost são os dados da página de detalhes de um host
+type PaginaHost struct {
+	NomeServidor string
+	IndicePerfil int
+	Detalhe      *zabbix.DetalheHost
+	URLAtual     string
+
+	PeriodoConsulta // Período da linha do tempo de eventos
+	MensagensPagina
+}
+
+// PaginaItem são os dados da página de um item, com o gráfico do período
+type PaginaItem struct {
+	NomeServidor string
+	IndicePerfil int
+	Item         *zabbix.Item
+	URLAtual     string
+	Grafico      template.HTML              // SVG da série, para itens numéricos
+	Tendencias   bool                       // O gráfico usa trend.get
+	Truncada     bool                       // O gráfico omite os valores mais antigos do período
+	Valores      []zabbix.RegistroHistorico // Últimos valores de itens de texto
+
+	PeriodoConsulta
+	MensagensPagina
+}
+
+// PaginaSLA são os dados do relatório de disponibilidade de um mês
+type PaginaSLA struct {
+	NomeServidor string
+	IndicePerfil int
+	URLAtual     string
+	SLAs         []zabbix.SLA // SLAs cadastrados; vazio antes do Zabbix 6.0
+	SLASuportado bool
+
+	// Filtros, devolvidos ao formulário como foram informados
+	Mes   string // Formato AAAA-MM
+	SLAID string // Vazio usa as triggers de disponibilidade
+	Meta  string // SLO informado; vazio usa o do SLA ou o da configuração
+	Tags  string
+
+	SLO       float64 // Meta aplicada ao relatório
+	Relatorio *zabbix.RelatorioDisponibilidade
+
+	MensagensPagina
+}
+
+// PaginaProblemas são os dados da lista de problemas ativos
+type PaginaProblemas struct {
+	NomeServidor string
+	IndicePerfil int
+	URLAtual     string
+	Problemas    []zabbix.ProblemaAtivo
+	Agora        time.Time
+	Filtro       zabbix.FiltroProblemas
+	Versao       zabbix.Versao
+	Auditoria    []auditoria.Registro // Últimas ações registradas neste computador
+
+	MensagensPagina
+}
+
+// PaginaManutencoes são os dados da página de janelas de manutenção
+type PaginaManutencoes struct {
+	NomeServidor     string
+	IndicePerfil     int
+	URLAtual         string
+	Agora            time.Time
+	Ativas           []zabbix.Manutencao
+	Proximas         []zabbix.Manutencao
+	Expiradas        []zabbix.Manutencao
+	MostrarExpiradas bool
+	Grupos           []zabbix.GrupoHost // Opções do formulário de criação
+	Hosts            []zabbix.Host
+	Auditoria        []auditoria.Registro
+
+	MensagensPagina
+}
+
+// PaginaAlteracaoHosts é a prévia de uma alteração em massa, antes de aplicá-la
+type PaginaAlteracaoHosts struct {
+	NomeServidor string
+	IndicePerfil int
+	URLAtual     string
+	Previsoes    []zabbix.PrevisaoHost
+	ComMudancas  int        // Hosts que seriam alterados
+	Formulario   url.Values // Campos reenviados ao confirmar
+	Assinatura   string     // Confere, ao aplicar, que a alteração é a da prévia
+	Voltar       string
+
+	MensagensPagina
+}
+
+// PaginaExportacao são os dados da página de exportação, importação e cópia
+// de configuração entre perfis
+type PaginaExportacao struct {
+	NomeServidor string
+	IndicePerfil int
+	URLAtual     string
+	Versao       zabbix.Versao
+	Templates    []zabbix.TemplateVinculado
+	Grupos       []zabbix.GrupoHost
+	Hosts        []zabbix.Host
+	Regras       zabbix.RegrasImportacao // Regras marcadas de início
+
+	// Cópia entre perfis
+	Perfis          []config.ConfiguracaoPerfil
+	Origem          int                        // Perfil de onde os templates são copiados
+	TemplatesOrigem []zabbix.TemplateVinculado // Templates do perfil de origem
+	ErroOrigem      string
+
+	Auditoria []auditoria.Registro
+
+	MensagensPagina
+}
+
+// PaginaComparacao são os dados da comparação de configuração entre duas
+// cópias de segurança de um perfil ou entre dois perfis
+type PaginaComparacao struct {
+	NomeServidor string
+	IndicePerfil int
+	URLAtual     string
+	Modo         string // modoComparacaoCopias ou modoComparacaoPerfis
+
+	// Entre cópias de segurança
+	Copias       []backup.Estado // Perfis com cópias gravadas
+	PerfilCopias string
+	Antes        string // Nome do arquivo mais antigo
+	Depois       string
+
+	// Entre dois perfis, com a configuração atual de cada um
+	Perfis  []config.ConfiguracaoPerfil
+	Origem  int
+	Destino int
+	Escopo  string // escopoComparacaoTemplates ou escopoComparacaoTudo
+
+	Filtro string // Parte do nome dos templates, hosts etc. mostrados
+
+	Comparado       bool
+	DescricaoAntes  string
+	DescricaoDepois string
+	Resultado       comparacao.Resultado
+	Grupos          []comparacao.Grupo
+	URLJSON         string // Mesma comparação em JSON
+
+	MensagensPagina
+}
+
+// PaginaDeclarativa é o plano que deixa o perfil ativo como os arquivos YAML
+// de hosts e templates e, depois de aplicado, o resultado de cada operação
+type PaginaDeclarativa struct {
+	NomeServidor string
+	IndicePerfil int
+	URLAtual     string
+	Base         string   // Diretório configurado em caminhoDeclarativo
+	Caminho      string   // Arquivo ou subdiretório dentro da base; vazio usa a base inteira
+	Arquivos     []string // Arquivos lidos
+	Plano        *zabbix.Plano
+	Aplicado     bool
+
+	MensagensPagina
+}
+
+// PaginaMacros mostra o valor efetivo de uma macro em cada host, com a cadeia
+// de herança, e as macros globais do perfil ativo
+type PaginaMacros struct {
+	NomeServidor string
+	IndicePerfil int
+	URLAtual     string
+	Macro        string // Macro consultada
+	GrupoID      string
+	Grupos       []zabbix.GrupoHost
+	Templates    []zabbix.TemplateVinculado
+	Efetivas     []zabbix.MacroEfetiva
+	Consultada   bool
+	NoHost       int // Hosts que definem a macro no próprio nível
+	Herdada      int // Hosts que recebem o valor de um template ou global
+	NaoDefinida  int
+	Globais      []zabbix.MacroUsuario
+
+	MensagensPagina
+}
+
+// PeriodoConsulta é o intervalo escolhido com os períodos prontos ou com datas
+type PeriodoConsulta struct {
+	Periodo     string
+	DataInicial string
+	DataFinal   string
+	Inicio      time.Time
+	Fim         time.Time
+}
+
+// definirErro preenche a mensagem de erro da página a partir de um erro do cliente
+func (p *MensagensPagina) definirErro(contexto string, err error) {
+	var disjuntorAberto *zabbix.ErroDisjuntorAberto
+	if errors.As(err, &disjuntorAberto) {
+		p.TentarEm = disjuntorAberto.SegundosRestantes()
+		return
+	}
+
+	p.MensagemErro = contexto + ": " + descreverErro(err)
+	p.ErroAutenticacao = zabbix.ClassificarErro(err) == zabbix.CategoriaAutenticacao
+}
+
+// definirFalha preenche a mensagem de erro a partir do parâmetro "falha" da URL,
+// usado pelos redirecionamentos após erros do cliente
+func (p *MensagensPagina) definirFalha(codigo string) {
+	if mensagem, ok := mensagensFalha[codigo]; ok {
+		p.MensagemErro = mensagem
+		p.ErroAutenticacao = codigo == zabbix.CategoriaAutenticacao.String()
+	}
+}
+
+// mensagensFalha traduz as categorias de erro do cliente em mensagens para o usuário
+var mensagensFalha = map[string]string{
+	zabbix.CategoriaAutenticacao.String(): "Token expirado ou credenciais inválidas. Edite o perfil para atualizar a autenticação.",
+	zabbix.CategoriaPermissao.String():    "O usuário do perfil não tem permissão para esta operação no Zabbix.",
+	zabbix.CategoriaParametros.String():   "A API do Zabbix rejeitou os parâmetros da requisição.",
+	zabbix.CategoriaTransporte.String():   "Não foi possível comunicar com o servidor Zabbix.",
+	zabbix.CategoriaDesconhecida.String(): "Erro inesperado ao consultar o servidor Zabbix.",
+}
+
+// descreverErro traduz um erro do cliente da API em uma mensagem para o usuário,
+// mantendo os detalhes técnicos quando ajudam a corrigir o problema
+func descreverErro(err error) string {
+	if mensagem, ok := zabbix.DescreverErroTLS(err); ok {
+		return "Falha de TLS: " + mensagem
+	}
+	var erroTransporte *zabbix.ErroTransporte
+	if errors.As(err, &erroTransporte) && erroTransporte.StatusCode == http.StatusProxyAuthRequired {
+		return "O proxy recusou a autenticação. Verifique o usuário e a senha na URL do proxy."
+	}
+
+	categoria := zabbix.ClassificarErro(err)
+	switch categoria {
+	case zabbix.CategoriaAutenticacao, zabbix.CategoriaPermissao:
+		return mensagensFalha[categoria.String()]
+	case zabbix.CategoriaParametros, zabbix.CategoriaTransporte:
+		return fmt.Sprintf("%s (%v)", mensagensFalha[categoria.String()], err)
+	}
+	return err.Error()
+}
+
+// urlFalha monta o redirecionamento para uma página com a categoria do erro
+func urlFalha(caminho string, err error) string {
+	return caminho + "?falha=" + zabbix.ClassificarErro(err).String()
 }
 
 var (
-	cfg            *config.Configuração
-	arquivoConfig  string
-	clienteAPI     *zabbix.ClienteAPI
-	templatesCache map[string]*template.Template
-	funcMap        template.FuncMap
+	cfg             *config.Configuração
+	arquivoConfig   string
+	clienteAPI      *zabbix.ClienteAPI
+	cacheAPI        = zabbix.NovoCache()
+	auditoriaLocal  = auditoria.Novo(auditoria.CaminhoPadrao())
+	agendadorBackup *backup.Agendador
+	templatesCache  map[string]*template.Template
+	funcMap         template.FuncMap
 )
 
 func init() {
@@ -54,10 +340,103 @@ func init() {
 		"subtract": func(a, b int) int {
 			return a - b
 		},
+		// Formata um horário devolvido pela API; horários não definidos viram "-"
+		"dataHora": func(instante zabbix.Instante) string {
+			if instante.IsZero() {
+				return "-"
+			}
+			return instante.Format("02/01/2006 15:04:05")
+		},
+		"severidade": func(codigo string) string {
+			if nome, ok := zabbix.NomesSeveridade[codigo]; ok {
+				return nome
+			}
+			return "Desconhecida"
+		},
+		// Classe Bootstrap usada para a cor de cada severidade
+		"corSeveridade": func(codigo string) string {
+			switch codigo {
+			case "5", "4":
+				return "bg-danger"
+			case "3":
+				return "bg-warning text-dark"
+			case "2":
+				return "bg-warning text-dark bg-opacity-50"
+			case "1":
+				return "bg-info text-dark"
+			}
+			return "bg-secondary"
+		},
+		"duracao": zabbix.FormatarDuracao,
+		"janelaAnteriorAnalise": func() string {
+			return zabbix.FormatarDuracao(zabbix.JanelaAnteriorAnalise)
+		},
+		// Códigos de severidade, da menor para a maior
+		"severidades": func() []string {
+			return []string{"0", "1", "2", "3", "4", "5"}
+		},
+		"periodoSLA": func(codigo string) string {
+			if nome, ok := zabbix.PeriodosSLA[codigo]; ok {
+				return nome
+			}
+			return "Desconhecido"
+		},
+		// Agrupa os dados de uma tabela do relatório de disponibilidade
+		"tabelaDisponibilidade": func(itens []zabbix.Disponibilidade, slo float64, grupos, linkHosts bool) map[string]interface{} {
+			return map[string]interface{}{
+				"Itens":     itens,
+				"SLO":       slo,
+				"Grupos":    grupos,
+				"LinkHosts": linkHosts,
+			}
+		},
+		"tipoInterface": func(codigo string) string {
+			if nome, ok := zabbix.TiposInterface[codigo]; ok {
+				return nome
+			}
+			return "Desconhecido"
+		},
+		// Agrupa os dados de uma tabela de manutenções; ativas mostra a ação de encerrar
+		"tabelaManutencoes": func(itens []zabbix.Manutencao, ativas bool) map[string]interface{} {
+			return map[string]interface{}{
+				"Itens":  itens,
+				"Ativas": ativas,
+			}
+		},
+		// Regras de importação de um formulário; o sufixo distingue os IDs dos campos
+		"regrasImportacao": func(regras zabbix.RegrasImportacao, sufixo string) map[string]interface{} {
+			return map[string]interface{}{
+				"Regras": regras,
+				"Sufixo": sufixo,
+			}
+		},
+		// Dias da semana do formulário de manutenção, com o bit de dayofweek
+		"diasSemana": func() []map[string]interface{} {
+			dias := make([]map[string]interface{}, len(zabbix.DiasSemana))
+			for i, nome := range zabbix.DiasSemana {
+				dias[i] = map[string]interface{}{"Bit": 1 << i, "Nome": nome}
+			}
+			return dias
+		},
+		"valorComparacao": comparacao.Formatar,
+		// Tamanho de um arquivo em KiB ou MiB
+		"tamanhoArquivo": func(bytes int64) string {
+			if bytes < 1024*1024 {
+				return fmt.Sprintf("%.1f KiB", float64(bytes)/1024)
+			}
+			return fmt.Sprintf("%.1f MiB", float64(bytes)/(1024*1024))
+		},
+		// Indica se o perfil ativo aceita qualquer certificado do servidor
+		"verificacaoTLSDesativada": func() bool {
+			perfil, err := cfg.PerfilAtivo()
+			return err == nil && perfil.IgnorarVerificacaoTLS
+		},
 	}
+}
 
-	// Load templates
-	templates := []string{"login", "principal", "config", "analise"}
+// carregarTemplates carrega as páginas da interface web
+func carregarTemplates() {
+	templates := []string{"login", "principal", "config", "analise", "host", "item", "sla", "problemas", "manutencoes", "alteracao", "exportacao", "comparacao", "declarativo", "macros"}
 	for _, nome := range templates {
 		carregarTemplate(nome)
 	}
@@ -107,20 +486,174 @@ func renderizarTemplate(w http.ResponseWriter, nome string, dados interface{}) {
 	}
 }
 
+// configAPIPerfil monta a configuração do cliente a partir de um perfil salvo
+func configAPIPerfil(perfil *config.ConfiguracaoPerfil) zabbix.ConfigAPI {
+	configAPI := zabbix.ConfigAPI{
+		URL:            perfil.URL,
+		TempoLimite:    cfg.TempoLimite,
+		Tentativas:     cfg.Tentativas,
+		EsperaInicial:  cfg.EsperaInicial,
+		EsperaMaxima:   cfg.EsperaMaxima,
+		LimiteFalhas:   cfg.LimiteFalhas,
+		TempoDisjuntor: cfg.TempoDisjuntor,
+
+		ArquivoCA:             perfil.ArquivoCA,
+		CertificadoCliente:    perfil.CertificadoCliente,
+		ChaveCliente:          perfil.ChaveCliente,
+		VersaoTLSMinima:       perfil.VersaoTLSMinima,
+		IgnorarVerificacaoTLS: perfil.IgnorarVerificacaoTLS,
+
+		Proxy:            perfil.Proxy,
+		SemProxy:         perfil.SemProxy,
+		CabecalhosExtras: perfil.CabecalhosExtras,
+
+		Cache:    cacheAPI,
+		Perfil:   perfil.IdentidadeCache(),
+		TTLCache: cfg.TTLCache,
+	}
+	if perfil.UsaCredenciais() {
+		configAPI.Usuario = perfil.Usuario
+		configAPI.Senha = perfil.Senha
+	} else {
+		configAPI.Token = perfil.Token
+	}
+	return configAPI
+}
+
+// encerrarClienteAPI faz logout da sessão do cliente atual, se houver
+func encerrarClienteAPI() {
+	if clienteAPI == nil {
+		return
+	}
+	if err := clienteAPI.EncerrarSessao(); err != nil {
+		log.Printf("Error closing Zabbix session: %v", err)
+	}
+}
+
+// testarPerfil verifica o TLS, a conexão e, para perfis com usuário e senha, o login
+func testarPerfil(ctx context.Context, perfil *config.ConfiguracaoPerfil) error {
+	cliente := zabbix.NovoClienteAPI(configAPIPerfil(perfil))
+	if err := cliente.TestarTLSCtx(ctx); err != nil {
+		return err
+	}
+	if err := cliente.TestarConexaoCtx(ctx); err != nil {
+		return err
+	}
+	if !perfil.UsaCredenciais() {
+		return nil
+	}
+	if err := cliente.IniciarSessaoCtx(ctx); err != nil {
+		return err
+	}
+	return cliente.EncerrarSessaoCtx(ctx)
+}
+
+// perfilDoFormulario lê os campos de um perfil enviados pelo formulário de configuração
+func perfilDoFormulario(r *http.Request) config.ConfiguracaoPerfil {
+	perfil := config.ConfiguracaoPerfil{
+		Nome:             r.Form.Get("nome"),
+		URL:              r.Form.Get("url"),
+		ModoAutenticacao: r.Form.Get("modo_autenticacao"),
+
+		ArquivoCA:             strings.TrimSpace(r.Form.Get("arquivo_ca")),
+		CertificadoCliente:    strings.TrimSpace(r.Form.Get("certificado_cliente")),
+		ChaveCliente:          strings.TrimSpace(r.Form.Get("chave_cliente")),
+		VersaoTLSMinima:       r.Form.Get("versao_tls_minima"),
+		IgnorarVerificacaoTLS: r.Form.Get("ignorar_verificacao_tls") == "on",
+
+		Proxy:            strings.TrimSpace(r.Form.Get("proxy")),
+		SemProxy:         listaDoTexto(r.Form.Get("sem_proxy")),
+		CabecalhosExtras: cabecalhosDoTexto(r.Form.Get("cabecalhos")),
+	}
+	if perfil.UsaCredenciais() {
+		perfil.Usuario = r.Form.Get("usuario")
+		perfil.Senha = r.Form.Get("senha")
+	} else {
+		perfil.ModoAutenticacao = config.AutenticacaoToken
+		perfil.Token = r.Form.Get("token")
+	}
+	return perfil
+}
+
+// listaDoTexto separa uma lista digitada com vírgulas ou quebras de linha
+func listaDoTexto(texto string) []string {
+	var lista []string
+	for _, item := range strings.FieldsFunc(texto, func(r rune) bool {
+		return r == ',' || r == '\n' || r == '\r'
+	}) {
+		if item = strings.TrimSpace(item); item != "" {
+			lista = append(lista, item)
+		}
+	}
+	return lista
+}
+
+// cabecalhosDoTexto lê cabeçalhos no formato "Nome: valor", um por linha. Uma
+// linha sem ":" fica sem valor e é rejeitada por validarPerfil.
+func cabecalhosDoTexto(texto string) map[string]string {
+	cabecalhos := make(map[string]string)
+	for _, linha := range strings.Split(texto, "\n") {
+		linha = strings.TrimSpace(linha)
+		if linha == "" {
+			continue
+		}
+		nome, valor, _ := strings.Cut(linha, ":")
+		cabecalhos[strings.TrimSpace(nome)] = strings.TrimSpace(valor)
+	}
+	if len(cabecalhos) == 0 {
+		return nil
+	}
+	return cabecalhos
+}
+
+// validarPerfil retorna uma mensagem de erro se faltar algum campo obrigatório
+func validarPerfil(perfil config.ConfiguracaoPerfil) string {
+	if perfil.Nome == "" || perfil.URL == "" {
+		return "Todos os campos são obrigatórios"
+	}
+	if perfil.UsaCredenciais() {
+		if perfil.Usuario == "" || perfil.Senha == "" {
+			return "Informe usuário e senha"
+		}
+	} else if perfil.Token == "" {
+		return "Informe o token de API"
+	}
+	if (perfil.CertificadoCliente == "") != (perfil.ChaveCliente == "") {
+		return "Informe o certificado e a chave do cliente"
+	}
+	if perfil.Proxy != "" {
+		if _, err := zabbix.ValidarProxy(perfil.Proxy); err != nil {
+			return "Proxy: " + err.Error()
+		}
+	}
+	for nome, valor := range perfil.CabecalhosExtras {
+		if valor == "" {
+			return fmt.Sprintf("Cabeçalho %q sem valor; use o formato \"Nome: valor\"", nome)
+		}
+	}
+	if err := zabbix.ValidarCabecalhos(perfil.CabecalhosExtras); err != nil {
+		return "Cabeçalhos extras: " + err.Error()
+	}
+	return ""
+}
+
 func inicializarClienteAPI() {
 	perfilAtivo, err := cfg.PerfilAtivo()
 	if err != nil {
 		log.Printf("Warning: %v", err)
+		encerrarClienteAPI()
 		clienteAPI = nil
 		return
 	}
 
-	configAPI := zabbix.ConfigAPI{
-		URL:         perfilAtivo.URL,
-		Token:       perfilAtivo.Token,
-		TempoLimite: cfg.TempoLimite,
+	encerrarClienteAPI()
+	clienteAPI = zabbix.NovoClienteAPI(configAPIPerfil(perfilAtivo))
+	if perfilAtivo.IgnorarVerificacaoTLS {
+		log.Printf("Warning: TLS certificate verification is disabled for profile %q", perfilAtivo.Nome)
+	}
+	if perfilAtivo.Proxy != "" {
+		log.Printf("Using proxy %s for profile %q", perfilAtivo.ProxyMascarado(), perfilAtivo.Nome)
 	}
-	clienteAPI = zabbix.NovoClienteAPI(configAPI)
 
 	// Optional: Add monthly analysis
 	ano := time.Now().Year()
@@ -134,7 +667,16 @@ func inicializarClienteAPI() {
 
 	if err := clienteAPI.TestarConexao(); err != nil {
 		log.Printf("Error testing Zabbix server connection: %v", err)
-		clienteAPI = nil
+		// Com o servidor temporariamente fora, manter o cliente para que as
+		// páginas informem a indisponibilidade e tentem novamente
+		if !errors.Is(err, zabbix.ErrTransporte) {
+			clienteAPI = nil
+		}
+		return
+	}
+
+	if versao, err := clienteAPI.ObterVersao(); err == nil {
+		log.Printf("Connected to Zabbix API %s (bearer auth: %t)", versao, versao.SuportaBearer())
 	}
 }
 
@@ -162,10 +704,15 @@ func manipuladorLogin(w http.ResponseWriter, r *http.Request) {
 }
 
 func manipuladorConfig(w http.ResponseWriter, r *http.Request) {
+	situacao := agendadorBackup.Situacao()
 	pagina := PaginaLogin{
-		ListaPerfis: cfg.Perfis,
-		PerfilAtivo: cfg.PerfilAtual,
-		ModoEdicao:  false,
+		ListaPerfis:  cfg.Perfis,
+		PerfilAtivo:  cfg.PerfilAtual,
+		ModoEdicao:   false,
+		Erro:         r.URL.Query().Get("erro"),
+		Sucesso:      r.URL.Query().Get("sucesso"),
+		ConfigBackup: cfg.Backup,
+		Backup:       &situacao,
 	}
 	renderizarTemplate(w, "config", pagina)
 }
@@ -181,41 +728,28 @@ func manipuladorAdicionarPerfil(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	nome := r.Form.Get("nome")
-	url := r.Form.Get("url")
-	token := r.Form.Get("token")
+	perfil := perfilDoFormulario(r)
 
-	if nome == "" || url == "" || token == "" {
+	if erro := validarPerfil(perfil); erro != "" {
 		pagina := PaginaLogin{
 			ListaPerfis: cfg.Perfis,
 			PerfilAtivo: cfg.PerfilAtual,
-			Erro:        "Todos os campos são obrigatórios",
+			Erro:        erro,
 		}
 		renderizarTemplate(w, "config", pagina)
 		return
 	}
 
-	configAPI := zabbix.ConfigAPI{
-		URL:         url,
-		Token:       token,
-		TempoLimite: cfg.TempoLimite,
-	}
-	clienteTemporario := zabbix.NovoClienteAPI(configAPI)
-	if err := clienteTemporario.TestarConexao(); err != nil {
+	if err := testarPerfil(r.Context(), &perfil); err != nil {
 		pagina := PaginaLogin{
 			ListaPerfis: cfg.Perfis,
 			PerfilAtivo: cfg.PerfilAtual,
-			Erro:        fmt.Sprintf("Erro ao conectar: %v", err),
+			Erro:        "Erro ao conectar: " + descreverErro(err),
 		}
 		renderizarTemplate(w, "config", pagina)
 		return
 	}
 
-	perfil := config.ConfiguracaoPerfil{
-		Nome:  nome,
-		URL:   url,
-		Token: token,
-	}
 	cfg.AdicionarPerfil(perfil)
 
 	if err := cfg.Salvar(arquivoConfig); err != nil {
@@ -252,6 +786,7 @@ func manipuladorEditarPerfil(w http.ResponseWriter, r *http.Request) {
 			PerfilAtivo:  cfg.PerfilAtual,
 			ModoEdicao:   true,
 			PerfilEditar: &cfg.Perfis[indice],
+			IndiceEditar: indice,
 		}
 		renderizarTemplate(w, "config", pagina)
 		return
@@ -264,21 +799,31 @@ func manipuladorEditarPerfil(w http.ResponseWriter, r *http.Request) {
 		}
 
 		indiceStr := r.Form.Get("indice")
-		nome := r.Form.Get("nome")
-		url := r.Form.Get("url")
-		token := r.Form.Get("token")
+		perfil := perfilDoFormulario(r)
 
 		var indice int
 		fmt.Sscanf(indiceStr, "%d", &indice)
 
-		if indice < 0 || indice >= len(cfg.Perfis) || nome == "" || url == "" || token == "" {
+		if indice < 0 || indice >= len(cfg.Perfis) {
+			http.Redirect(w, r, "/config?erro=Dados inválidos", http.StatusFound)
+			return
+		}
+
+		// Senha em branco mantém a senha já salva
+		if perfil.UsaCredenciais() && perfil.Senha == "" {
+			perfil.Senha = cfg.Perfis[indice].Senha
+		}
+
+		if validarPerfil(perfil) != "" {
 			http.Redirect(w, r, "/config?erro=Dados inválidos", http.StatusFound)
 			return
 		}
 
-		cfg.Perfis[indice].Nome = nome
-		cfg.Perfis[indice].URL = url
-		cfg.Perfis[indice].Token = token
+		// Outro servidor ou usuário muda a identidade e as chaves do cache; o
+		// que ficou guardado com a anterior não será mais lido
+		zabbix.LimparCachePerfil(cacheAPI, cfg.Perfis[indice].IdentidadeCache())
+		zabbix.LimparCachePerfil(cacheAPI, perfil.IdentidadeCache())
+		cfg.Perfis[indice] = perfil
 
 		if err := cfg.Salvar(arquivoConfig); err != nil {
 			http.Redirect(w, r, fmt.Sprintf("/config?erro=%s", err), http.StatusFound)
@@ -316,6 +861,10 @@ func manipuladorRemoverPerfil(w http.ResponseWriter, r *http.Request) {
 	var indice int
 	fmt.Sscanf(indiceStr, "%d", &indice)
 
+	if indice >= 0 && indice < len(cfg.Perfis) {
+		zabbix.LimparCachePerfil(cacheAPI, cfg.Perfis[indice].IdentidadeCache())
+	}
+
 	if err := cfg.RemoverPerfil(indice); err != nil {
 		http.Redirect(w, r, fmt.Sprintf("/config?erro=%s", err), http.StatusFound)
 		return
@@ -355,6 +904,9 @@ func manipuladorSelecionarPerfil(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Ao voltar para um perfil, buscar os dados atuais em vez dos guardados antes da troca
+	zabbix.LimparCachePerfil(cacheAPI, cfg.Perfis[indice].IdentidadeCache())
+
 	if err := cfg.Salvar(arquivoConfig); err != nil {
 		http.Redirect(w, r, fmt.Sprintf("/config?erro=%s", err), http.StatusFound)
 		return
@@ -364,7 +916,84 @@ func manipuladorSelecionarPerfil(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "/?sucesso=Perfil selecionado com sucesso", http.StatusFound)
 }
 
+// manipuladorLimparCache descarta os dados em cache do perfil ativo e volta à
+// página de origem, que então consulta o servidor novamente
+func manipuladorLimparCache(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/hosts", http.StatusFound)
+		return
+	}
+
+	if clienteAPI != nil {
+		clienteAPI.LimparCache()
+	}
+
+	http.Redirect(w, r, caminhoLocal(r.FormValue("voltar"), "/hosts"), http.StatusFound)
+}
+
+// caminhoLocal retorna destino se for um caminho desta aplicação, ou padrao.
+// Aceitar apenas caminhos locais evita redirecionar a outro site.
+func caminhoLocal(destino, padrao string) string {
+	if !strings.HasPrefix(destino, "/") || strings.HasPrefix(destino, "//") {
+		return padrao
+	}
+	return destino
+}
+
+// comMensagem acrescenta um aviso (sucesso ou erro) à query string de destino
+func comMensagem(destino, tipo, mensagem string) string {
+	endereco, err := url.Parse(destino)
+	if err != nil {
+		return destino
+	}
+	consulta := endereco.Query()
+	consulta.Set(tipo, mensagem)
+	endereco.RawQuery = consulta.Encode()
+	return endereco.String()
+}
+
 func manipuladorHosts(w http.ResponseWriter, r *http.Request) {
+	listarHosts(w, r, "")
+}
+
+func manipuladorBuscarHosts(w http.ResponseWriter, r *http.Request) {
+	termo := r.URL.Query().Get("termo")
+	if termo == "" {
+		http.Redirect(w, r, "/hosts?"+parametrosFiltro(r).Encode(), http.StatusFound)
+		return
+	}
+	listarHosts(w, r, termo)
+}
+
+// filtroHostsDaURL lê os filtros de grupo, template e tag da query string
+func filtroHostsDaURL(r *http.Request) zabbix.FiltroHosts {
+	consulta := r.URL.Query()
+	var filtro zabbix.FiltroHosts
+	if grupo := consulta.Get("grupo"); grupo != "" {
+		filtro.GrupoIDs = []string{grupo}
+	}
+	if template := consulta.Get("template"); template != "" {
+		filtro.TemplateIDs = []string{template}
+	}
+	filtro.Tags = zabbix.ParseFiltroTags(consulta.Get("tag"))
+	return filtro
+}
+
+// parametrosFiltro retorna apenas os filtros da listagem que foram informados
+func parametrosFiltro(r *http.Request) url.Values {
+	parametros := url.Values{}
+	for _, nome := range []string{"grupo", "template", "tag"} {
+		if valor := r.URL.Query().Get(nome); valor != "" {
+			parametros.Set(nome, valor)
+		}
+	}
+	return parametros
+}
+
+// listarHosts monta a listagem de hosts com os filtros da URL. O termo de
+// busca, quando informado, é conferido enquanto as páginas são lidas, de modo
+// que apenas os hosts encontrados ficam na memória.
+func listarHosts(w http.ResponseWriter, r *http.Request, termo string) {
 	if clienteAPI == nil {
 		http.Redirect(w, r, "/login", http.StatusFound)
 		return
@@ -376,29 +1005,96 @@ func manipuladorHosts(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	hosts, err := clienteAPI.ObterHosts()
+	pagina := PaginaPrincipal{
+		NomeServidor:     perfilAtivo.Nome,
+		URLServidor:      perfilAtivo.URL,
+		IndicePerfil:     cfg.PerfilAtual,
+		Hosts:            []zabbix.ResumoHost{},
+		TermoBusca:       termo,
+		FiltroGrupo:      r.URL.Query().Get("grupo"),
+		FiltroTemplate:   r.URL.Query().Get("template"),
+		FiltroTag:        r.URL.Query().Get("tag"),
+		ParametrosFiltro: parametrosFiltro(r).Encode(),
+		URLAtual:         r.URL.RequestURI(),
+	}
+
+	// As listas dos filtros não impedem a listagem se falharem
+	pagina.Grupos, pagina.Templates, err = clienteAPI.ObterGruposETemplatesCtx(r.Context())
 	if err != nil {
-		pagina := PaginaPrincipal{
-			NomeServidor: perfilAtivo.Nome,
-			URLServidor:  perfilAtivo.URL,
-			Hosts:        []zabbix.Host{},
-			MensagemErro: fmt.Sprintf("Erro ao obter hosts: %v", err),
+		log.Printf("Error loading host filters: %v", err)
+	}
+
+	err = clienteAPI.PercorrerResumosHostsCtx(r.Context(), filtroHostsDaURL(r), cfg.TamanhoPaginaHosts, func(host zabbix.ResumoHost) error {
+		if termo == "" || host.CorrespondeBusca(termo) {
+			pagina.Hosts = append(pagina.Hosts, host)
 		}
+		return nil
+	})
+	if err != nil {
+		pagina.Hosts = []zabbix.ResumoHost{}
+		pagina.definirErro("Erro ao obter hosts", err)
 		renderizarTemplate(w, "principal", pagina)
 		return
 	}
 
-	pagina := PaginaPrincipal{
-		NomeServidor:    perfilAtivo.Nome,
-		URLServidor:     perfilAtivo.URL,
-		Hosts:           hosts,
-		MensagemSucesso: r.URL.Query().Get("sucesso"),
-		MensagemErro:    r.URL.Query().Get("erro"),
+	if termo == "" {
+		pagina.MensagemSucesso = r.URL.Query().Get("sucesso")
+		pagina.MensagemErro = r.URL.Query().Get("erro")
+		pagina.definirFalha(r.URL.Query().Get("falha"))
 	}
 	renderizarTemplate(w, "principal", pagina)
 }
 
-func manipuladorBuscarHosts(w http.ResponseWriter, r *http.Request) {
+// periodosProntos são os períodos oferecidos nas páginas de host e de item
+var periodosProntos = map[string]time.Duration{
+	"1h":  time.Hour,
+	"24h": 24 * time.Hour,
+	"7d":  7 * 24 * time.Hour,
+	"30d": 30 * 24 * time.Hour,
+}
+
+// lerPeriodo interpreta o período da query string. Datas informadas têm
+// prioridade sobre os períodos prontos; se forem inválidas, o período padrão é
+// usado e a mensagem de erro é retornada.
+func lerPeriodo(r *http.Request, padrao string) (PeriodoConsulta, string) {
+	periodo := PeriodoConsulta{
+		Periodo:     r.URL.Query().Get("periodo"),
+		DataInicial: r.URL.Query().Get("data_inicial"),
+		DataFinal:   r.URL.Query().Get("data_final"),
+		Fim:         time.Now(),
+	}
+
+	var mensagem string
+	if periodo.DataInicial != "" || periodo.DataFinal != "" {
+		inicio, errInicio := time.ParseInLocation("2006-01-02", periodo.DataInicial, time.Local)
+		fim, errFim := time.ParseInLocation("2006-01-02", periodo.DataFinal, time.Local)
+		switch {
+		case errInicio != nil || errFim != nil:
+			mensagem = "Informe a data inicial e a data final do período."
+		case fim.Before(inicio):
+			mensagem = "A data final deve ser igual ou posterior à data inicial."
+		default:
+			periodo.Periodo = ""
+			periodo.Inicio, periodo.Fim = inicio, fim.AddDate(0, 0, 1)
+			return periodo, ""
+		}
+	}
+
+	if _, ok := periodosProntos[periodo.Periodo]; !ok {
+		periodo.Periodo = padrao
+	}
+	periodo.Inicio = periodo.Fim.Add(-periodosProntos[periodo.Periodo])
+	return periodo, mensagem
+}
+
+// manipuladorHost exibe a página de um host em /hosts/{id}
+func manipuladorHost(w http.ResponseWriter, r *http.Request) {
+	hostID := strings.TrimPrefix(r.URL.Path, "/hosts/")
+	if hostID == "" || strings.Trim(hostID, "0123456789") != "" {
+		http.NotFound(w, r)
+		return
+	}
+
 	if clienteAPI == nil {
 		http.Redirect(w, r, "/login", http.StatusFound)
 		return
@@ -410,41 +1106,124 @@ func manipuladorBuscarHosts(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	termo := r.URL.Query().Get("termo")
-	if termo == "" {
-		http.Redirect(w, r, "/hosts", http.StatusFound)
+	pagina := PaginaHost{
+		NomeServidor: perfilAtivo.Nome,
+		IndicePerfil: cfg.PerfilAtual,
+		URLAtual:     r.URL.RequestURI(),
+	}
+	pagina.PeriodoConsulta, pagina.MensagemErro = lerPeriodo(r, "24h")
+
+	pagina.Detalhe, err = clienteAPI.ObterDetalheHostCtx(r.Context(), hostID, pagina.Inicio, pagina.Fim)
+	if errors.Is(err, zabbix.ErrHostNaoEncontrado) {
+		w.WriteHeader(http.StatusNotFound)
+		pagina.MensagemErro = "Host " + hostID + " não encontrado ou sem permissão de leitura."
+	} else if err != nil {
+		pagina.definirErro("Erro ao obter o host", err)
+	}
+
+	renderizarTemplate(w, "host", pagina)
+}
+
+// manipuladorItem exibe a página de um item em /itens/{id}, com o gráfico do
+// período para itens numéricos e os últimos valores para os demais. Em
+// /itens/{id}/grafico.svg responde apenas o gráfico.
+func manipuladorItem(w http.ResponseWriter, r *http.Request) {
+	caminho := strings.TrimPrefix(r.URL.Path, "/itens/")
+	itemID, recurso, _ := strings.Cut(caminho, "/")
+	if itemID == "" || strings.Trim(itemID, "0123456789") != "" || (recurso != "" && recurso != "grafico.svg") {
+		http.NotFound(w, r)
+		return
+	}
+
+	if clienteAPI == nil {
+		http.Redirect(w, r, "/login", http.StatusFound)
 		return
 	}
 
-	hosts, err := clienteAPI.ObterHosts()
+	perfilAtivo, err := cfg.PerfilAtivo()
 	if err != nil {
-		pagina := PaginaPrincipal{
-			NomeServidor: perfilAtivo.Nome,
-			URLServidor:  perfilAtivo.URL,
-			TermoBusca:   termo,
-			Hosts:        []zabbix.Host{},
-			MensagemErro: fmt.Sprintf("Erro ao obter hosts: %v", err),
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+
+	pagina := PaginaItem{
+		NomeServidor: perfilAtivo.Nome,
+		IndicePerfil: cfg.PerfilAtual,
+		URLAtual:     r.URL.RequestURI(),
+	}
+	pagina.PeriodoConsulta, pagina.MensagemErro = lerPeriodo(r, "24h")
+
+	item, err := clienteAPI.ObterItemCtx(r.Context(), itemID)
+	if err != nil {
+		if recurso != "" {
+			http.Error(w, descreverErro(err), http.StatusBadGateway)
+			return
 		}
-		renderizarTemplate(w, "principal", pagina)
+		if errors.Is(err, zabbix.ErrItemNaoEncontrado) {
+			w.WriteHeader(http.StatusNotFound)
+			pagina.MensagemErro = "Item " + itemID + " não encontrado ou sem permissão de leitura."
+		} else {
+			pagina.definirErro("Erro ao obter o item", err)
+		}
+		renderizarTemplate(w, "item", pagina)
 		return
 	}
+	pagina.Item = &item
 
-	termoLower := strings.ToLower(termo)
-	var hostsFiltrados []zabbix.Host
-	for _, host := range hosts {
-		if strings.Contains(strings.ToLower(host.Nome), termoLower) ||
-			strings.Contains(strings.ToLower(host.ID), termoLower) {
-			hostsFiltrados = append(hostsFiltrados, host)
+	if !item.Numerico() {
+		if recurso != "" {
+			http.Error(w, "O item não é numérico", http.StatusNotFound)
+			return
+		}
+		pagina.Valores, err = clienteAPI.ObterUltimosValoresCtx(r.Context(), item, 100)
+		if err != nil {
+			pagina.definirErro("Erro ao obter os valores do item", err)
 		}
+		renderizarTemplate(w, "item", pagina)
+		return
 	}
 
-	pagina := PaginaPrincipal{
-		NomeServidor: perfilAtivo.Nome,
-		URLServidor:  perfilAtivo.URL,
-		TermoBusca:   termo,
-		Hosts:        hostsFiltrados,
+	serie, err := clienteAPI.ObterSerieCtx(r.Context(), item, pagina.Inicio, pagina.Fim)
+	if err != nil {
+		if recurso != "" {
+			http.Error(w, descreverErro(err), http.StatusBadGateway)
+			return
+		}
+		pagina.definirErro("Erro ao obter o histórico do item", err)
+		renderizarTemplate(w, "item", pagina)
+		return
 	}
-	renderizarTemplate(w, "principal", pagina)
+	pagina.Tendencias = serie.Tendencias
+	pagina.Truncada = serie.Truncada
+
+	pontos := make([]grafico.Ponto, len(serie.Pontos))
+	for i, p := range serie.Pontos {
+		pontos[i] = grafico.Ponto{Momento: p.Momento, Minimo: p.Minimo, Media: p.Media, Maximo: p.Maximo}
+	}
+	opcoes := grafico.Opcoes{
+		Titulo:  item.Nome,
+		Unidade: item.Unidades,
+		Inicio:  pagina.Inicio,
+		Fim:     pagina.Fim,
+		Faixa:   serie.Tendencias,
+	}
+
+	if recurso != "" {
+		w.Header().Set("Content-Type", "image/svg+xml")
+		if err := grafico.Linha(w, pontos, opcoes); err != nil {
+			log.Printf("Error writing chart: %v", err)
+		}
+		return
+	}
+
+	var svg strings.Builder
+	if err := grafico.Linha(&svg, pontos, opcoes); err != nil {
+		pagina.definirErro("Erro ao desenhar o gráfico", err)
+	}
+	// O SVG é gerado pelo pacote grafico, que escapa todos os textos
+	pagina.Grafico = template.HTML(svg.String())
+
+	renderizarTemplate(w, "item", pagina)
 }
 
 func manipuladorExportarCSV(w http.ResponseWriter, r *http.Request) {
@@ -459,22 +1238,70 @@ func manipuladorExportarCSV(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	hosts, err := clienteAPI.ObterHosts()
-	if err != nil {
-		http.Redirect(w, r, fmt.Sprintf("/hosts?erro=%s", err), http.StatusFound)
-		return
-	}
-
+	// O relatório é escrito à medida que os hosts chegam. Os cabeçalhos HTTP só
+	// são enviados com o primeiro bloco do CSV, então uma falha logo no início
+	// ainda pode ser redirecionada
 	nomeArquivo := fmt.Sprintf("relatorio_%s_%s.csv",
 		perfilAtivo.Nome,
 		time.Now().Format("2006-01-02_15-04-05"))
-	w.Header().Set("Content-Type", "text/csv")
-	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", nomeArquivo))
+	saida := &respostaCSV{w: w, nomeArquivo: nomeArquivo}
+
+	escritor, err := zabbix.NovoEscritorRelatorioCSV(saida)
+	if err == nil {
+		// O tempo médio de resolução e a disponibilidade vêm dos eventos dos
+		// últimos 30 dias, lidos por inteiro antes da primeira linha; por isso só
+		// são calculados quando pedidos. Sem eles as colunas ficam em "N/A"
+		if r.URL.Query().Get("indicadores") == "1" {
+			fim := time.Now()
+			analises, errAnalise := clienteAPI.AnalisarProblemasPeriodoCtx(r.Context(), fim.AddDate(0, 0, -30), fim)
+			if errAnalise != nil {
+				log.Printf("Error analysing problems for CSV report: %v", errAnalise)
+			}
+			escritor.UsarAnalises(analises)
+
+			disponibilidade, errDisponibilidade := clienteAPI.DisponibilidadeTriggersCtx(r.Context(),
+				zabbix.ParseFiltroTags(cfg.TagsDeDisponibilidade()), fim.AddDate(0, 0, -30), fim)
+			if errDisponibilidade != nil {
+				log.Printf("Error computing availability for CSV report: %v", errDisponibilidade)
+			}
+			escritor.UsarDisponibilidade(disponibilidade.Hosts)
+		}
 
-	if err := zabbix.GerarRelatorioCSVStream(hosts, w); err != nil {
-		http.Redirect(w, r, fmt.Sprintf("/hosts?erro=%s", err), http.StatusFound)
+		params := zabbix.ParamsHostRelatorio()
+		filtroHostsDaURL(r).Aplicar(&params)
+		err = clienteAPI.PercorrerHostsCtx(r.Context(), params, cfg.TamanhoPaginaHosts, escritor.Escrever)
+	}
+	if err == nil {
+		err = escritor.Finalizar()
+	}
+	if err == nil {
+		return
+	}
+
+	if !saida.iniciado {
+		log.Printf("Error exporting hosts: %v", err)
+		http.Redirect(w, r, urlFalha("/hosts", err), http.StatusFound)
 		return
 	}
+
+	// Os cabeçalhos já foram enviados; não é mais possível redirecionar
+	log.Printf("Error writing CSV report: %v", err)
+}
+
+// respostaCSV adia o envio dos cabeçalhos do download até a primeira escrita
+type respostaCSV struct {
+	w           http.ResponseWriter
+	nomeArquivo string
+	iniciado    bool
+}
+
+func (s *respostaCSV) Write(p []byte) (int, error) {
+	if !s.iniciado {
+		s.iniciado = true
+		s.w.Header().Set("Content-Type", "text/csv")
+		s.w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", s.nomeArquivo))
+	}
+	return s.w.Write(p)
 }
 
 func main() {
@@ -490,10 +1317,29 @@ func main() {
 		}
 	}
 
+	// Command-line subcommands run instead of the web server
+	if len(os.Args) > 1 {
+		os.Exit(executarComando(os.Args[1], os.Args[2:]))
+	}
+
+	// Load templates
+	carregarTemplates()
+
 	// Initialize API if active profile exists
 	inicializarClienteAPI()
 
-	// Configure routes
+	// Start scheduled configuration backups
+	ctxBackup, pararBackup := context.WithCancel(context.Background())
+	defer pararBackup()
+	agendadorBackup = backup.NovoAgendador(alvosBackup)
+	if err := agendadorBackup.Configurar(cfg.Backup); err != nil {
+		log.Printf("Error in backup schedule, scheduled backups disabled: %v", err)
+	} else if cfg.Backup.Ativo() {
+		log.Printf("Scheduled backups enabled (%s)", cfg.Backup.Agenda)
+	}
+	agendadorBackup.Iniciar(ctxBackup)
+
+	// Configure routes
 	http.HandleFunc("/", manipuladorHome)
 	http.HandleFunc("/login", manipuladorLogin)
 	http.HandleFunc("/config", manipuladorConfig)
@@ -501,10 +1347,37 @@ func main() {
 	http.HandleFunc("/perfil/editar", manipuladorEditarPerfil)
 	http.HandleFunc("/perfil/remover", manipuladorRemoverPerfil)
 	http.HandleFunc("/perfil/selecionar", manipuladorSelecionarPerfil)
+	http.HandleFunc("/backup/configurar", manipuladorConfigurarBackup)
+	http.HandleFunc("/backup/executar", manipuladorExecutarBackup)
 	http.HandleFunc("/hosts", manipuladorHosts)
 	http.HandleFunc("/hosts/buscar", manipuladorBuscarHosts)
+	http.HandleFunc("/hosts/alterar", manipuladorAlterarHosts)
+	http.HandleFunc("/hosts/", manipuladorHost)
+	http.HandleFunc("/itens/", manipuladorItem)
 	http.HandleFunc("/exportar", manipuladorExportarCSV)
+	http.HandleFunc("/cache/limpar", manipuladorLimparCache)
 	http.HandleFunc("/analise", manipuladorAnalise)
+	http.HandleFunc("/problemas", manipuladorProblemas)
+	http.HandleFunc("/problemas/atualizar", manipuladorAtualizarProblemas)
+	http.HandleFunc("/manutencoes", manipuladorManutencoes)
+	http.HandleFunc("/manutencoes/criar", manipuladorCriarManutencao)
+	http.HandleFunc("/manutencoes/rapida", manipuladorManutencaoRapida)
+	http.HandleFunc("/manutencoes/encerrar", manipuladorEncerrarManutencao)
+	http.HandleFunc("/manutencoes/remover", manipuladorRemoverManutencao)
+	http.HandleFunc("/exportacao", manipuladorExportacao)
+	http.HandleFunc("/exportacao/baixar", manipuladorBaixarExportacao)
+	http.HandleFunc("/exportacao/importar", manipuladorImportarConfiguracao)
+	http.HandleFunc("/exportacao/copiar", manipuladorCopiarTemplates)
+	http.HandleFunc("/comparacao", manipuladorComparacao)
+	http.HandleFunc("/declarativo", manipuladorDeclarativo)
+	http.HandleFunc("/declarativo/aplicar", manipuladorAplicarDeclarativo)
+	http.HandleFunc("/macros", manipuladorMacros)
+	http.HandleFunc("/macros/exportar", manipuladorExportarMacros)
+	http.HandleFunc("/macros/alterar", manipuladorAlterarMacros)
+	http.HandleFunc("/macros/global/salvar", manipuladorSalvarMacroGlobal)
+	http.HandleFunc("/macros/global/remover", manipuladorRemoverMacroGlobal)
+	http.HandleFunc("/sla", manipuladorSLA)
+	http.HandleFunc("/sla/exportar", manipuladorExportarSLA)
 
 	// Serve static files
 	fs := http.FileServer(http.Dir("static"))
@@ -512,52 +1385,2035 @@ func main() {
 
 	// Start server
 	porta := "5000"
-	log.Printf("Zabbix Manager Web starting on port %s...", porta)
-	addr := fmt.Sprintf("0.0.0.0:%s", porta)
-	if err := http.ListenAndServe(addr, nil); err != nil {
+	log.Printf("Zabbix Manager Web starting on %s...", net.JoinHostPort(cfg.Escuta(), porta))
+	addr := net.JoinHostPort(cfg.Escuta(), porta)
+	servidor := &http.Server{Addr: addr, Handler: exigirMesmaOrigem(http.DefaultServeMux)}
+
+	// Encerrar a sessão do Zabbix ao receber sinal de término
+	go func() {
+		sinais := make(chan os.Signal, 1)
+		signal.Notify(sinais, os.Interrupt, syscall.SIGTERM)
+		<-sinais
+
+		log.Printf("Shutting down...")
+		pararBackup()
+		ctx, cancelar := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancelar()
+		if err := servidor.Shutdown(ctx); err != nil {
+			log.Printf("Error shutting down server: %v", err)
+		}
+	}()
+
+	if err := servidor.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 		log.Fatalf("Error starting server: %v", err)
 	}
+
+	encerrarClienteAPI()
+}
+
+// exigirMesmaOrigem recusa requisições que alteram estado vindas de outra
+// origem, para que uma página qualquer aberta no navegador do operador não
+// consiga enviar formulários à aplicação. Os navegadores informam a origem em
+// Sec-Fetch-Site ou Origin; clientes sem esses cabeçalhos, como o curl, não
+// estão sujeitos a esse ataque e são aceitos.
+func exigirMesmaOrigem(proximo http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			proximo.ServeHTTP(w, r)
+			return
+		}
+
+		if !mesmaOrigem(r) {
+			log.Printf("Rejected cross-origin %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+			http.Error(w, "Requisição de outra origem recusada", http.StatusForbidden)
+			return
+		}
+		proximo.ServeHTTP(w, r)
+	})
+}
+
+// mesmaOrigem informa se a requisição veio de uma página servida pela própria aplicação
+func mesmaOrigem(r *http.Request) bool {
+	switch r.Header.Get("Sec-Fetch-Site") {
+	case "same-origin", "none":
+		return true
+	case "":
+	default:
+		return false
+	}
+
+	origem := r.Header.Get("Origin")
+	if origem == "" {
+		// Navegadores antigos enviam apenas o Referer
+		origem = r.Header.Get("Referer")
+	}
+	if origem == "" {
+		return true
+	}
+
+	u, err := url.Parse(origem)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+// periodoRapido é um atalho de período da página de análise
+type periodoRapido struct {
+	Valor  string
+	Rotulo string
 }
+
+// periodosAnalise são os atalhos oferecidos na página de análise, na ordem exibida
+var periodosAnalise = []periodoRapido{
+	{"24h", "Últimas 24 horas"},
+	{"7d", "Últimos 7 dias"},
+	{"semana", "Semana anterior"},
+	{"30d", "Últimos 30 dias"},
+	{"trimestre", "Este trimestre"},
+}
+
+// duracaoMaximaAnalise limita o período específico, pois a análise lê pelo
+// event.get todos os eventos de problema do período, mais uma consulta por lote
+// de recuperações, e mantém as ocorrências em memória durante a requisição
+const duracaoMaximaAnalise = 366 * 24 * time.Hour
+
+// intervaloRapido calcula [inicio, fim) de um atalho de período em relação a agora.
+// A semana anterior vai de segunda a segunda; o trimestre começa no primeiro dia
+// do seu primeiro mês.
+func intervaloRapido(nome string, agora time.Time) (time.Time, time.Time, bool) {
+	hoje := time.Date(agora.Year(), agora.Month(), agora.Day(), 0, 0, 0, 0, agora.Location())
+	switch nome {
+	case "24h":
+		return agora.Add(-24 * time.Hour), agora, true
+	case "7d":
+		return agora.AddDate(0, 0, -7), agora, true
+	case "30d":
+		return agora.AddDate(0, 0, -30), agora, true
+	case "semana":
+		diasDesdeSegunda := (int(hoje.Weekday()) + 6) % 7
+		segunda := hoje.AddDate(0, 0, -diasDesdeSegunda)
+		return segunda.AddDate(0, 0, -7), segunda, true
+	case "trimestre":
+		mesInicial := time.Month((int(agora.Month())-1)/3*3 + 1)
+		return time.Date(agora.Year(), mesInicial, 1, 0, 0, 0, 0, agora.Location()), agora, true
+	}
+	return time.Time{}, time.Time{}, false
+}
+
 func manipuladorAnalise(w http.ResponseWriter, r *http.Request) {
 	if clienteAPI == nil {
 		http.Redirect(w, r, "/login", http.StatusFound)
 		return
 	}
 
-	ano := time.Now().Year()
-	mes := int(time.Now().Month())
+	agora := time.Now()
+	ano := agora.Year()
+	mes := int(agora.Month())
 
 	// Pegar parâmetros da query
-	if anoStr := r.URL.Query().Get("ano"); anoStr != "" {
+	consulta := r.URL.Query()
+	if anoStr := consulta.Get("ano"); anoStr != "" {
 		if anoInt, err := strconv.Atoi(anoStr); err == nil {
 			ano = anoInt
 		}
 	}
-	if mesStr := r.URL.Query().Get("mes"); mesStr != "" {
-		if mesInt, err := strconv.Atoi(mesStr); err == nil {
+	if mesStr := consulta.Get("mes"); mesStr != "" {
+		if mesInt, err := strconv.Atoi(mesStr); err == nil && mesInt >= 1 && mesInt <= 12 {
 			mes = mesInt
 		}
 	}
 
-	analises, err := clienteAPI.AnalisarProblemasMensais(ano, mes)
+	tipoFiltro := consulta.Get("tipo_filtro")
+	dados := map[string]interface{}{
+		"AnoSelecionado":  ano,
+		"MesSelecionado":  mes,
+		"Anos":            []int{agora.Year() - 1, agora.Year(), agora.Year() + 1},
+		"Meses":           []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12},
+		"NomesMeses":      []string{"Janeiro", "Fevereiro", "Março", "Abril", "Maio", "Junho", "Julho", "Agosto", "Setembro", "Outubro", "Novembro", "Dezembro"},
+		"DataInicial":     consulta.Get("data_inicial"),
+		"DataFinal":       consulta.Get("data_final"),
+		"PeriodoRapido":   consulta.Get("periodo"),
+		"PeriodosRapidos": periodosAnalise,
+		"IndicePerfil":    cfg.PerfilAtual,
+	}
+
+	// Período analisado, sempre no formato [inicio, fim)
+	var inicio, fim time.Time
+	var erroPeriodo string
+	switch tipoFiltro {
+	case "periodo":
+		dataInicial, errInicial := time.ParseInLocation("2006-01-02", consulta.Get("data_inicial"), time.Local)
+		dataFinal, errFinal := time.ParseInLocation("2006-01-02", consulta.Get("data_final"), time.Local)
+		switch {
+		case errInicial != nil || errFinal != nil:
+			erroPeriodo = "Informe a data inicial e a data final do período."
+		case dataFinal.Before(dataInicial):
+			erroPeriodo = "A data final deve ser igual ou posterior à data inicial."
+		case dataInicial.After(agora):
+			erroPeriodo = "A data inicial não pode estar no futuro."
+		case dataFinal.AddDate(0, 0, 1).Sub(dataInicial) > duracaoMaximaAnalise:
+			erroPeriodo = "O período específico pode ter no máximo 366 dias."
+		default:
+			// A data final é incluída por inteiro
+			inicio, fim = dataInicial, dataFinal.AddDate(0, 0, 1)
+		}
+	case "rapido":
+		var ok bool
+		inicio, fim, ok = intervaloRapido(consulta.Get("periodo"), agora)
+		if !ok {
+			erroPeriodo = "Período rápido desconhecido."
+		}
+	default:
+		tipoFiltro = "mensal"
+		inicio = time.Date(ano, time.Month(mes), 1, 0, 0, 0, 0, time.Local)
+		fim = inicio.AddDate(0, 1, 0)
+	}
+	dados["TipoFiltro"] = tipoFiltro
+
+	if erroPeriodo != "" {
+		dados["Erro"] = erroPeriodo
+		renderizarTemplate(w, "analise", dados)
+		return
+	}
+	dados["Inicio"] = inicio
+	// O fim exibido é o último instante incluído
+	dados["FimInclusivo"] = fim.Add(-time.Second)
+
+	analises, err := clienteAPI.AnalisarProblemasPeriodoCtx(r.Context(), inicio, fim)
 	if err != nil {
 		log.Printf("Erro ao analisar problemas: %v", err)
-		renderizarTemplate(w, "analise", map[string]interface{}{
-			"Erro": fmt.Sprintf("Erro ao analisar problemas: %v", err),
-		})
+		dados["Erro"] = "Erro ao analisar problemas: " + descreverErro(err)
+		dados["ErroAutenticacao"] = zabbix.ClassificarErro(err) == zabbix.CategoriaAutenticacao
+		renderizarTemplate(w, "analise", dados)
 		return
 	}
 
-	// Preparar dados para o template
-	dados := map[string]interface{}{
-		"Analises":       analises,
-		"AnoSelecionado": ano,
-		"MesSelecionado": mes,
-		"Anos":          []int{ano - 1, ano, ano + 1},
-		"Meses":         []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12},
-		"NomesMeses":    []string{"Janeiro", "Fevereiro", "Março", "Abril", "Maio", "Junho", "Julho", "Agosto", "Setembro", "Outubro", "Novembro", "Dezembro"},
-		"TipoFiltro":    "mensal",
+	dados["Analises"] = analises
+	renderizarTemplate(w, "analise", dados)
+}
+
+// consultaSLA lê os filtros do relatório de disponibilidade para a página.
+// Sem mês informado usa o mês atual, apurado até agora.
+func consultaSLA(r *http.Request, pagina *PaginaSLA) (inicio, fim time.Time, erro string) {
+	consulta := r.URL.Query()
+	pagina.Mes = consulta.Get("mes")
+	pagina.SLAID = consulta.Get("sla")
+	pagina.Meta = strings.TrimSpace(consulta.Get("slo"))
+	pagina.Tags = consulta.Get("tags")
+	if !consulta.Has("tags") {
+		pagina.Tags = cfg.TagsDeDisponibilidade()
 	}
 
-	renderizarTemplate(w, "analise", dados)
+	agora := time.Now()
+	if pagina.Mes == "" {
+		pagina.Mes = agora.Format("2006-01")
+	}
+	inicio, err := time.ParseInLocation("2006-01", pagina.Mes, time.Local)
+	if err != nil {
+		return inicio, fim, "Mês inválido, use o formato AAAA-MM."
+	}
+	if inicio.After(agora) {
+		return inicio, fim, "O mês não pode estar no futuro."
+	}
+	fim = inicio.AddDate(0, 1, 0)
+
+	pagina.SLO = cfg.MetaSLO()
+	if pagina.Meta != "" {
+		slo, err := strconv.ParseFloat(strings.Replace(pagina.Meta, ",", ".", 1), 64)
+		if err != nil || slo <= 0 || slo > 100 {
+			return inicio, fim, "O SLO deve ser uma porcentagem entre 0 e 100."
+		}
+		pagina.SLO = slo
+	}
+	return inicio, fim, ""
+}
+
+// relatorioSLA apura a disponibilidade do mês pelo SLA escolhido ou, sem SLA,
+// pelas triggers de disponibilidade. O SLO do SLA vale quando nenhum é informado.
+func relatorioSLA(r *http.Request, pagina *PaginaSLA, inicio, fim time.Time) (zabbix.RelatorioDisponibilidade, error) {
+	if pagina.SLAID == "" {
+		return clienteAPI.DisponibilidadeTriggersCtx(r.Context(), zabbix.ParseFiltroTags(pagina.Tags), inicio, fim)
+	}
+
+	relatorio, err := clienteAPI.DisponibilidadeSLACtx(r.Context(), pagina.SLAID, inicio, fim)
+	if err == nil && pagina.Meta == "" && relatorio.SLA.MetaSLO() > 0 {
+		pagina.SLO = relatorio.SLA.MetaSLO()
+	}
+	return relatorio, err
+}
+
+// manipuladorSLA exibe a disponibilidade de um mês por serviço, com um SLA do
+// Zabbix 6.0+, ou por host e grupo, com as triggers de disponibilidade
+func manipuladorSLA(w http.ResponseWriter, r *http.Request) {
+	if clienteAPI == nil {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+
+	perfilAtivo, err := cfg.PerfilAtivo()
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+
+	pagina := PaginaSLA{
+		NomeServidor: perfilAtivo.Nome,
+		IndicePerfil: cfg.PerfilAtual,
+		URLAtual:     r.URL.RequestURI(),
+	}
+	inicio, fim, erro := consultaSLA(r, &pagina)
+
+	pagina.SLAs, err = clienteAPI.ObterSLAsCtx(r.Context())
+	switch {
+	case errors.Is(err, zabbix.ErrSLANaoSuportado):
+	case err != nil:
+		pagina.definirErro("Erro ao listar os SLAs", err)
+		renderizarTemplate(w, "sla", pagina)
+		return
+	default:
+		pagina.SLASuportado = true
+	}
+
+	if erro != "" {
+		pagina.MensagemErro = erro
+		renderizarTemplate(w, "sla", pagina)
+		return
+	}
+
+	relatorio, err := relatorioSLA(r, &pagina, inicio, fim)
+	if errors.Is(err, zabbix.ErrSLANaoEncontrado) {
+		pagina.MensagemErro = "SLA " + pagina.SLAID + " não encontrado ou sem permissão de leitura."
+	} else if err != nil {
+		log.Printf("Error computing availability: %v", err)
+		pagina.definirErro("Erro ao apurar a disponibilidade", err)
+	} else {
+		pagina.Relatorio = &relatorio
+	}
+
+	renderizarTemplate(w, "sla", pagina)
+}
+
+// manipuladorExportarSLA baixa o relatório de disponibilidade do mês em CSV,
+// com os mesmos filtros da página
+func manipuladorExportarSLA(w http.ResponseWriter, r *http.Request) {
+	if clienteAPI == nil {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+
+	perfilAtivo, err := cfg.PerfilAtivo()
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+
+	var pagina PaginaSLA
+	inicio, fim, erro := consultaSLA(r, &pagina)
+	if erro != "" {
+		http.Error(w, erro, http.StatusBadRequest)
+		return
+	}
+
+	relatorio, err := relatorioSLA(r, &pagina, inicio, fim)
+	if err != nil {
+		log.Printf("Error exporting availability: %v", err)
+		http.Redirect(w, r, urlFalha("/sla", err), http.StatusFound)
+		return
+	}
+
+	nomeArquivo := fmt.Sprintf("sla_%s_%s.csv", perfilAtivo.Nome, pagina.Mes)
+	saida := &respostaCSV{w: w, nomeArquivo: nomeArquivo}
+	if err := zabbix.EscreverRelatorioDisponibilidadeCSV(saida, relatorio, pagina.SLO); err != nil {
+		log.Printf("Error writing availability CSV: %v", err)
+	}
+}
+
+// registrarAuditoria grava uma alteração feita no servidor do perfil ativo.
+// Falhas ao gravar não desfazem a alteração e vão apenas para o log.
+func registrarAuditoria(r *http.Request, acao string, alvos []string, detalhes map[string]interface{}, err error) {
+	perfil, _ := cfg.PerfilAtivo()
+	registrarAuditoriaPerfil(r, perfil, clienteAPI, acao, alvos, detalhes, err)
+}
+
+// registrarAuditoriaPerfil grava uma alteração feita no servidor de um perfil
+// que pode não ser o ativo, como o destino de uma cópia entre perfis. O
+// cliente é o que fez a alteração.
+func registrarAuditoriaPerfil(r *http.Request, perfil *config.ConfiguracaoPerfil, cliente *zabbix.ClienteAPI, acao string, alvos []string, detalhes map[string]interface{}, err error) {
+	registrarAuditoriaOrigem(r.RemoteAddr, perfil, cliente, acao, alvos, detalhes, err)
+}
+
+// registrarAuditoriaOrigem grava o registro com uma origem que não é uma
+// requisição, como a linha de comando
+func registrarAuditoriaOrigem(origem string, perfil *config.ConfiguracaoPerfil, cliente *zabbix.ClienteAPI, acao string, alvos []string, detalhes map[string]interface{}, err error) {
+	registro := auditoria.Registro{
+		Origem:    origem,
+		Acao:      acao,
+		Alvos:     alvos,
+		Detalhes:  detalhes,
+		Resultado: auditoria.ResultadoSucesso,
+	}
+	if perfil != nil {
+		registro.Perfil = perfil.Nome
+		registro.Servidor = perfil.URL
+		registro.Usuario = usuarioDoPerfil(perfil, cliente)
+	}
+	if err != nil {
+		registro.Resultado = auditoria.ResultadoFalha
+		registro.Erro = err.Error()
+	}
+
+	if errAuditoria := auditoriaLocal.Registrar(registro); errAuditoria != nil {
+		log.Printf("Error writing audit record: %v", errAuditoria)
+	}
+}
+
+// usuarioDoPerfil retorna o usuário do Zabbix que faz as alterações pelo
+// perfil: o configurado ou, em perfis com token, o dono do token. O cliente
+// guarda o usuário do token depois da primeira consulta.
+func usuarioDoPerfil(perfil *config.ConfiguracaoPerfil, cliente *zabbix.ClienteAPI) string {
+	if perfil.UsaCredenciais() || cliente == nil {
+		return perfil.Usuario
+	}
+
+	usuario, err := cliente.UsuarioAutenticadoCtx(context.Background())
+	if err != nil {
+		log.Printf("Error identifying the API token user of profile %q: %v", perfil.Nome, err)
+	}
+	return usuario
+}
+
+// filtroProblemas lê os filtros da lista de problemas e os devolve também como
+// parâmetros de URL, para voltar à mesma lista depois de uma ação
+func filtroProblemas(valores url.Values) (zabbix.FiltroProblemas, url.Values) {
+	var filtro zabbix.FiltroProblemas
+	parametros := url.Values{}
+	if severidade, err := strconv.Atoi(valores.Get("severidade")); err == nil && severidade > 0 && severidade <= 5 {
+		filtro.SeveridadeMinima = severidade
+		parametros.Set("severidade", strconv.Itoa(severidade))
+	}
+	if valores.Get("nao_reconhecidos") == "1" {
+		filtro.NaoReconhecidos = true
+		parametros.Set("nao_reconhecidos", "1")
+	}
+	if valores.Get("suprimidos") == "1" {
+		filtro.Suprimidos = true
+		parametros.Set("suprimidos", "1")
+	}
+	return filtro, parametros
+}
+
+// manipuladorProblemas lista os problemas abertos, com as ações de operador
+func manipuladorProblemas(w http.ResponseWriter, r *http.Request) {
+	if clienteAPI == nil {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+
+	perfilAtivo, err := cfg.PerfilAtivo()
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+
+	pagina := PaginaProblemas{
+		NomeServidor: perfilAtivo.Nome,
+		IndicePerfil: cfg.PerfilAtual,
+		URLAtual:     r.URL.RequestURI(),
+		Agora:        time.Now(),
+	}
+	pagina.Filtro, _ = filtroProblemas(r.URL.Query())
+
+	pagina.Auditoria, err = auditoriaLocal.Ultimos(10)
+	if err != nil {
+		log.Printf("Error reading audit trail: %v", err)
+	}
+
+	pagina.Versao, err = clienteAPI.ObterVersaoCtx(r.Context())
+	if err == nil {
+		pagina.Problemas, err = clienteAPI.ObterProblemasAtivosCtx(r.Context(), pagina.Filtro)
+	}
+	if err != nil {
+		pagina.definirErro("Erro ao obter problemas", err)
+	} else {
+		pagina.MensagemSucesso = r.URL.Query().Get("sucesso")
+		pagina.MensagemErro = r.URL.Query().Get("erro")
+	}
+
+	renderizarTemplate(w, "problemas", pagina)
+}
+
+// manipuladorAtualizarProblemas aplica uma ação de operador aos problemas
+// marcados com event.acknowledge e registra o resultado na auditoria
+func manipuladorAtualizarProblemas(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/problemas", http.StatusFound)
+		return
+	}
+	if clienteAPI == nil {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Erro ao processar formulário", http.StatusBadRequest)
+		return
+	}
+
+	_, voltar := filtroProblemas(r.PostForm)
+	eventos := r.PostForm["evento"]
+
+	atualizacao := zabbix.AtualizacaoProblemas{
+		Reconhecer:         r.PostForm.Get("reconhecer") == "1",
+		DesfazerReconhecer: r.PostForm.Get("desfazer_reconhecer") == "1",
+		Fechar:             r.PostForm.Get("fechar") == "1",
+		Mensagem:           r.PostForm.Get("mensagem"),
+		Severidade:         r.PostForm.Get("nova_severidade"),
+		Suprimir:           r.PostForm.Get("suprimir") == "1",
+		RemoverSupressao:   r.PostForm.Get("remover_supressao") == "1",
+	}
+	if ate := r.PostForm.Get("suprimir_ate"); atualizacao.Suprimir && ate != "" {
+		momento, err := time.ParseInLocation("2006-01-02T15:04", ate, time.Local)
+		if err != nil {
+			voltar.Set("erro", "Data de fim da supressão inválida.")
+			http.Redirect(w, r, "/problemas?"+voltar.Encode(), http.StatusFound)
+			return
+		}
+		atualizacao.SuprimirAte = momento
+	}
+
+	detalhes := map[string]interface{}{
+		"acoes": zabbix.DescreverAcoes(atualizacao.Acoes()),
+	}
+	if atualizacao.Mensagem != "" {
+		detalhes["mensagem"] = atualizacao.Mensagem
+	}
+	if atualizacao.Severidade != "" {
+		detalhes["severidade"] = zabbix.NomesSeveridade[atualizacao.Severidade]
+	}
+	if !atualizacao.SuprimirAte.IsZero() {
+		detalhes["suprimirAte"] = atualizacao.SuprimirAte
+	}
+
+	alterados, err := clienteAPI.AtualizarProblemasCtx(r.Context(), eventos, atualizacao)
+	registrarAuditoria(r, "event.acknowledge", eventos, detalhes, err)
+	if err != nil {
+		log.Printf("Error updating problems %v: %v", eventos, err)
+		voltar.Set("erro", "Erro ao atualizar problemas: "+descreverErro(err))
+	} else {
+		voltar.Set("sucesso", fmt.Sprintf("%d problema(s) atualizado(s): %s.",
+			len(alterados), strings.Join(zabbix.DescreverAcoes(atualizacao.Acoes()), ", ")))
+	}
+	http.Redirect(w, r, "/problemas?"+voltar.Encode(), http.StatusFound)
+}
+
+// manipuladorManutencoes lista as manutenções em vigor e as próximas, com o
+// formulário para criar uma nova janela
+func manipuladorManutencoes(w http.ResponseWriter, r *http.Request) {
+	if clienteAPI == nil {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+
+	perfilAtivo, err := cfg.PerfilAtivo()
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+
+	pagina := PaginaManutencoes{
+		NomeServidor:     perfilAtivo.Nome,
+		IndicePerfil:     cfg.PerfilAtual,
+		URLAtual:         r.URL.RequestURI(),
+		Agora:            time.Now(),
+		MostrarExpiradas: r.URL.Query().Get("expiradas") == "1",
+	}
+
+	pagina.Auditoria, err = auditoriaLocal.Ultimos(10)
+	if err != nil {
+		log.Printf("Error reading audit trail: %v", err)
+	}
+
+	manutencoes, err := clienteAPI.ObterManutencoesCtx(r.Context())
+	if err != nil {
+		pagina.definirErro("Erro ao obter manutenções", err)
+		renderizarTemplate(w, "manutencoes", pagina)
+		return
+	}
+	for _, m := range manutencoes {
+		switch m.Estado(pagina.Agora) {
+		case zabbix.ManutencaoAtiva:
+			pagina.Ativas = append(pagina.Ativas, m)
+		case zabbix.ManutencaoProxima:
+			pagina.Proximas = append(pagina.Proximas, m)
+		default:
+			pagina.Expiradas = append(pagina.Expiradas, m)
+		}
+	}
+
+	// As opções do formulário não impedem a listagem se falharem
+	pagina.Grupos, _, err = clienteAPI.ObterGruposETemplatesCtx(r.Context())
+	if err == nil {
+		pagina.Hosts, err = hostsParaSelecao(r.Context(), clienteAPI)
+	}
+	if err != nil {
+		log.Printf("Error loading maintenance form options: %v", err)
+	}
+
+	pagina.MensagemSucesso = r.URL.Query().Get("sucesso")
+	pagina.MensagemErro = r.URL.Query().Get("erro")
+	renderizarTemplate(w, "manutencoes", pagina)
+}
+
+// hostsParaSelecao lista apenas ID e nomes dos hosts, em ordem alfabética,
+// para as listas de seleção dos formulários
+func hostsParaSelecao(ctx context.Context, cliente *zabbix.ClienteAPI) ([]zabbix.Host, error) {
+	var hosts []zabbix.Host
+	err := cliente.PercorrerHostsCtx(ctx, zabbix.ParamsHostGet{
+		Output: []string{"hostid", "host", "name"},
+	}, cfg.TamanhoPaginaHosts, func(h zabbix.Host) error {
+		hosts = append(hosts, h)
+		return nil
+	})
+	sort.Slice(hosts, func(i, j int) bool {
+		return strings.ToLower(hosts[i].NomeExibicao()) < strings.ToLower(hosts[j].NomeExibicao())
+	})
+	return hosts, err
+}
+
+// manutencaoDoFormulario monta a manutenção pedida no formulário da página de
+// manutenções: uma janela única ou uma repetição diária ou semanal dentro da
+// vigência informada
+func manutencaoDoFormulario(valores url.Values) (zabbix.Manutencao, error) {
+	horas, err := strconv.ParseFloat(strings.Replace(valores.Get("duracao"), ",", ".", 1), 64)
+	if err != nil || horas <= 0 {
+		return zabbix.Manutencao{}, errors.New("duração inválida; informe as horas, ex: 2 ou 1.5")
+	}
+	duracao := time.Duration(horas * float64(time.Hour)).Round(time.Minute)
+	comColeta := valores.Get("com_coleta") == "1"
+
+	if valores.Get("recorrencia") == "unica" {
+		inicio, err := time.ParseInLocation("2006-01-02T15:04", valores.Get("inicio"), time.Local)
+		if err != nil {
+			return zabbix.Manutencao{}, errors.New("data de início inválida")
+		}
+		m := zabbix.ManutencaoUnica(valores.Get("nome"), inicio, duracao, comColeta, valores["host"], valores["grupo"])
+		m.Descricao = valores.Get("descricao")
+		return m, nil
+	}
+
+	hora, err := time.Parse("15:04", valores.Get("hora"))
+	if err != nil {
+		return zabbix.Manutencao{}, errors.New("horário de início inválido")
+	}
+	desde, err := time.ParseInLocation("2006-01-02", valores.Get("vigencia_inicio"), time.Local)
+	if err != nil {
+		return zabbix.Manutencao{}, errors.New("início da vigência inválido")
+	}
+	ate, err := time.ParseInLocation("2006-01-02", valores.Get("vigencia_fim"), time.Local)
+	if err != nil {
+		return zabbix.Manutencao{}, errors.New("fim da vigência inválido")
+	}
+	aCada, err := strconv.Atoi(valores.Get("a_cada"))
+	if err != nil || aCada < 1 {
+		aCada = 1
+	}
+
+	periodo := zabbix.PeriodoManutencao{
+		Tipo:       zabbix.PeriodoDiario,
+		ACada:      strconv.Itoa(aCada),
+		HoraInicio: strconv.Itoa(hora.Hour()*3600 + hora.Minute()*60),
+		Duracao:    strconv.FormatInt(int64(duracao/time.Second), 10),
+	}
+	if valores.Get("recorrencia") == "semanal" {
+		dias := 0
+		for _, dia := range valores["dia"] {
+			bit, _ := strconv.Atoi(dia)
+			dias |= bit
+		}
+		if dias == 0 {
+			return zabbix.Manutencao{}, errors.New("escolha ao menos um dia da semana")
+		}
+		periodo.Tipo = zabbix.PeriodoSemanal
+		periodo.DiasSemana = strconv.Itoa(dias)
+	}
+
+	m := zabbix.Manutencao{
+		Nome:       valores.Get("nome"),
+		Tipo:       zabbix.ManutencaoSemColeta,
+		Descricao:  valores.Get("descricao"),
+		AtivaDesde: zabbix.NovoInstante(desde.Unix()),
+		// O último dia da vigência é incluído por inteiro
+		AtivaAte: zabbix.NovoInstante(ate.AddDate(0, 0, 1).Unix()),
+		Periodos: []zabbix.PeriodoManutencao{periodo},
+	}
+	if comColeta {
+		m.Tipo = zabbix.ManutencaoComColeta
+	}
+	for _, id := range valores["host"] {
+		m.Hosts = append(m.Hosts, zabbix.Host{ID: id})
+	}
+	for _, id := range valores["grupo"] {
+		m.Grupos = append(m.Grupos, zabbix.GrupoHost{ID: id})
+	}
+	return m, nil
+}
+
+// detalhesManutencao resume a manutenção para a trilha de auditoria
+func detalhesManutencao(m zabbix.Manutencao) map[string]interface{} {
+	hosts := make([]string, len(m.Hosts))
+	for i, h := range m.Hosts {
+		hosts[i] = h.ID
+	}
+	grupos := make([]string, len(m.Grupos))
+	for i, g := range m.Grupos {
+		grupos[i] = g.ID
+	}
+	periodos := make([]string, len(m.Periodos))
+	for i, p := range m.Periodos {
+		periodos[i] = p.Descricao()
+	}
+	return map[string]interface{}{
+		"nome":      m.Nome,
+		"semColeta": m.Tipo == zabbix.ManutencaoSemColeta,
+		"desde":     m.AtivaDesde.Time,
+		"ate":       m.AtivaAte.Time,
+		"periodos":  periodos,
+		"hosts":     hosts,
+		"grupos":    grupos,
+	}
+}
+
+// criarManutencao envia a manutenção ao servidor, registra o resultado na
+// auditoria e retorna a mensagem a exibir e se houve sucesso
+func criarManutencao(r *http.Request, m zabbix.Manutencao) (string, bool) {
+	id, err := clienteAPI.CriarManutencaoCtx(r.Context(), m)
+	var alvos []string
+	if id != "" {
+		alvos = []string{id}
+	}
+	registrarAuditoria(r, "maintenance.create", alvos, detalhesManutencao(m), err)
+	if err != nil {
+		log.Printf("Error creating maintenance %q: %v", m.Nome, err)
+		return "Erro ao criar manutenção: " + descreverErro(err), false
+	}
+	return fmt.Sprintf("Manutenção %q criada, de %s a %s.", m.Nome,
+		m.AtivaDesde.Format("02/01/2006 15:04"), m.AtivaAte.Format("02/01/2006 15:04")), true
+}
+
+// manipuladorCriarManutencao cria a manutenção descrita no formulário
+func manipuladorCriarManutencao(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/manutencoes", http.StatusFound)
+		return
+	}
+	if clienteAPI == nil {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Erro ao processar formulário", http.StatusBadRequest)
+		return
+	}
+
+	m, err := manutencaoDoFormulario(r.PostForm)
+	if err != nil {
+		http.Redirect(w, r, comMensagem("/manutencoes", "erro", "Formulário inválido: "+err.Error()), http.StatusFound)
+		return
+	}
+
+	mensagem, ok := criarManutencao(r, m)
+	tipo := "sucesso"
+	if !ok {
+		tipo = "erro"
+	}
+	http.Redirect(w, r, comMensagem("/manutencoes", tipo, mensagem), http.StatusFound)
+}
+
+// manipuladorManutencaoRapida coloca os hosts marcados na listagem em
+// manutenção a partir de agora pelo número de horas escolhido
+func manipuladorManutencaoRapida(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/hosts", http.StatusFound)
+		return
+	}
+	if clienteAPI == nil {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Erro ao processar formulário", http.StatusBadRequest)
+		return
+	}
+
+	voltar := caminhoLocal(r.PostForm.Get("voltar"), "/hosts")
+	hosts := r.PostForm["host"]
+	horas, err := strconv.Atoi(r.PostForm.Get("horas"))
+	if err != nil || horas < 1 || horas > 720 {
+		http.Redirect(w, r, comMensagem(voltar, "erro", "Informe a duração da manutenção em horas, de 1 a 720."), http.StatusFound)
+		return
+	}
+	if len(hosts) == 0 {
+		http.Redirect(w, r, comMensagem(voltar, "erro", "Nenhum host selecionado."), http.StatusFound)
+		return
+	}
+
+	agora := time.Now()
+	// O nome identifica a manutenção e precisa ser único no servidor
+	nome := strings.TrimSpace(r.PostForm.Get("nome"))
+	if nome == "" {
+		nome = "Manutenção rápida " + agora.Format("02/01/2006 15:04:05")
+	}
+	m := zabbix.ManutencaoUnica(nome, agora, time.Duration(horas)*time.Hour,
+		r.PostForm.Get("com_coleta") == "1", hosts, nil)
+	m.Descricao = fmt.Sprintf("Criada pelo Zabbix Manager para %d host(s).", len(hosts))
+
+	mensagem, ok := criarManutencao(r, m)
+	tipo := "sucesso"
+	if !ok {
+		tipo = "erro"
+	}
+	http.Redirect(w, r, comMensagem(voltar, tipo, mensagem), http.StatusFound)
+}
+
+// manipuladorEncerrarManutencao antecipa para agora o fim de uma manutenção em vigor
+func manipuladorEncerrarManutencao(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/manutencoes", http.StatusFound)
+		return
+	}
+	if clienteAPI == nil {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+
+	id := r.FormValue("id")
+	agora := time.Now()
+	err := clienteAPI.EncerrarManutencaoCtx(r.Context(), id, agora)
+	registrarAuditoria(r, "maintenance.update", []string{id}, map[string]interface{}{
+		"nome": r.FormValue("nome"),
+		"ate":  agora,
+	}, err)
+	if err != nil {
+		log.Printf("Error ending maintenance %s: %v", id, err)
+		http.Redirect(w, r, comMensagem("/manutencoes", "erro", "Erro ao encerrar manutenção: "+descreverErro(err)), http.StatusFound)
+		return
+	}
+	http.Redirect(w, r, comMensagem("/manutencoes", "sucesso", fmt.Sprintf("Manutenção %q encerrada.", r.FormValue("nome"))), http.StatusFound)
+}
+
+// manipuladorRemoverManutencao exclui uma manutenção
+func manipuladorRemoverManutencao(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/manutencoes", http.StatusFound)
+		return
+	}
+	if clienteAPI == nil {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+
+	id := r.FormValue("id")
+	err := clienteAPI.RemoverManutencoesCtx(r.Context(), []string{id})
+	registrarAuditoria(r, "maintenance.delete", []string{id}, map[string]interface{}{
+		"nome": r.FormValue("nome"),
+	}, err)
+	if err != nil {
+		log.Printf("Error deleting maintenance %s: %v", id, err)
+		http.Redirect(w, r, comMensagem("/manutencoes", "erro", "Erro ao excluir manutenção: "+descreverErro(err)), http.StatusFound)
+		return
+	}
+	http.Redirect(w, r, comMensagem("/manutencoes", "sucesso", fmt.Sprintf("Manutenção %q excluída.", r.FormValue("nome"))), http.StatusFound)
+}
+
+// camposAlteracao são os campos do formulário de alteração em massa da lista de hosts
+var camposAlteracao = []string{"host", "status", "vincular_template", "desvincular_template",
+	"adicionar_grupo", "remover_grupo", "definir_tags", "remover_tags"}
+
+// alteracaoDoFormulario lê a alteração em massa pedida na lista de hosts
+func alteracaoDoFormulario(valores url.Values) zabbix.AlteracaoHosts {
+	preenchidos := func(nome string) []string {
+		var lista []string
+		for _, valor := range valores[nome] {
+			if valor != "" {
+				lista = append(lista, valor)
+			}
+		}
+		return lista
+	}
+	return zabbix.AlteracaoHosts{
+		Status:               valores.Get("status"),
+		VincularTemplates:    preenchidos("vincular_template"),
+		DesvincularTemplates: preenchidos("desvincular_template"),
+		AdicionarGrupos:      preenchidos("adicionar_grupo"),
+		RemoverGrupos:        preenchidos("remover_grupo"),
+		DefinirTags:          zabbix.ParseTags(valores.Get("definir_tags")),
+		RemoverTags:          zabbix.ParseTags(valores.Get("remover_tags")),
+	}
+}
+
+// manipuladorAlterarHosts mostra o que a alteração em massa mudaria em cada
+// host marcado e, quando confirmada, aplica a alteração e registra cada
+// chamada feita na auditoria
+func manipuladorAlterarHosts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/hosts", http.StatusFound)
+		return
+	}
+	if clienteAPI == nil {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+	perfilAtivo, err := cfg.PerfilAtivo()
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Erro ao processar formulário", http.StatusBadRequest)
+		return
+	}
+
+	voltar := caminhoLocal(r.PostForm.Get("voltar"), "/hosts")
+	hosts := r.PostForm["host"]
+	alteracao := alteracaoDoFormulario(r.PostForm)
+
+	var etapas []zabbix.EtapaAlteracao
+	if r.PostForm.Get("aplicar") == "1" {
+		etapas, err = clienteAPI.AplicarAlteracaoHostsCtx(r.Context(), hosts, alteracao, r.PostForm.Get("assinatura"))
+	}
+	// Sem confirmação, ou se os hosts mudaram desde a prévia, mostra a prévia atual
+	if r.PostForm.Get("aplicar") != "1" || errors.Is(err, zabbix.ErrPrevisaoDesatualizada) {
+		desatualizada := err != nil
+		previsoes, err := clienteAPI.PreverAlteracaoHostsCtx(r.Context(), hosts, alteracao)
+		if err != nil {
+			http.Redirect(w, r, comMensagem(voltar, "erro", "Erro ao preparar alteração: "+descreverErro(err)), http.StatusFound)
+			return
+		}
+
+		pagina := PaginaAlteracaoHosts{
+			NomeServidor: perfilAtivo.Nome,
+			IndicePerfil: cfg.PerfilAtual,
+			URLAtual:     voltar,
+			Previsoes:    previsoes,
+			Formulario:   url.Values{},
+			Assinatura:   zabbix.AssinaturaPrevisao(previsoes),
+			Voltar:       voltar,
+		}
+		if desatualizada {
+			pagina.MensagemErro = "Os hosts mudaram depois da prévia e nada foi alterado. Revise a prévia atualizada abaixo."
+		}
+		for _, p := range previsoes {
+			if len(p.Mudancas) > 0 {
+				pagina.ComMudancas++
+			}
+		}
+		for _, campo := range camposAlteracao {
+			if valores, ok := r.PostForm[campo]; ok {
+				pagina.Formulario[campo] = valores
+			}
+		}
+		renderizarTemplate(w, "alteracao", pagina)
+		return
+	}
+
+	for _, etapa := range etapas {
+		detalhes := map[string]interface{}{"descricao": etapa.Descricao}
+		for chave, valor := range etapa.Detalhes {
+			detalhes[chave] = valor
+		}
+		registrarAuditoria(r, etapa.Metodo, etapa.Hosts, detalhes, etapa.Err)
+	}
+	switch {
+	case err != nil:
+		log.Printf("Error updating hosts %v: %v", hosts, err)
+		mensagem := "Erro ao alterar hosts: " + descreverErro(err)
+		if len(etapas) > 1 {
+			mensagem += fmt.Sprintf(" As %d etapas anteriores foram aplicadas.", len(etapas)-1)
+		}
+		http.Redirect(w, r, comMensagem(voltar, "erro", mensagem), http.StatusFound)
+	case len(etapas) == 0:
+		http.Redirect(w, r, comMensagem(voltar, "sucesso", "Os hosts já estavam como pedido; nada foi alterado."), http.StatusFound)
+	default:
+		descricoes := make([]string, len(etapas))
+		for i, etapa := range etapas {
+			descricoes[i] = fmt.Sprintf("%s (%d host(s))", etapa.Descricao, len(etapa.Hosts))
+		}
+		http.Redirect(w, r, comMensagem(voltar, "sucesso", "Hosts alterados: "+strings.Join(descricoes, ", ")+"."), http.StatusFound)
+	}
+}
+
+// tamanhoMaximoImportacao limita o arquivo enviado para importação
+const tamanhoMaximoImportacao = 32 << 20
+
+// clienteDoPerfil retorna o cliente do perfil pelo índice: o cliente ativo
+// para o perfil atual ou um cliente novo para os demais. A função retornada
+// encerra a sessão do cliente novo e deve ser chamada ao fim do uso.
+func clienteDoPerfil(indice int) (*zabbix.ClienteAPI, *config.ConfiguracaoPerfil, func(), error) {
+	if indice < 0 || indice >= len(cfg.Perfis) {
+		return nil, nil, nil, fmt.Errorf("perfil %d não existe", indice)
+	}
+	perfil := &cfg.Perfis[indice]
+	if indice == cfg.PerfilAtual && clienteAPI != nil {
+		return clienteAPI, perfil, func() {}, nil
+	}
+
+	cliente := zabbix.NovoClienteAPI(configAPIPerfil(perfil))
+	encerrar := func() {
+		if err := cliente.EncerrarSessao(); err != nil {
+			log.Printf("Error closing Zabbix session for profile %q: %v", perfil.Nome, err)
+		}
+	}
+	return cliente, perfil, encerrar, nil
+}
+
+// regrasDoFormulario lê as regras de importação marcadas
+func regrasDoFormulario(valores url.Values) zabbix.RegrasImportacao {
+	return zabbix.RegrasImportacao{
+		CriarAusentes:       valores.Get("criar_ausentes") == "1",
+		AtualizarExistentes: valores.Get("atualizar_existentes") == "1",
+		RemoverAusentes:     valores.Get("remover_ausentes") == "1",
+	}
+}
+
+// detalhesRegras resume as regras de importação para a auditoria
+func detalhesRegras(regras zabbix.RegrasImportacao) map[string]interface{} {
+	return map[string]interface{}{
+		"createMissing":  regras.CriarAusentes,
+		"updateExisting": regras.AtualizarExistentes,
+		"deleteMissing":  regras.RemoverAusentes,
+	}
+}
+
+// manipuladorExportacao mostra os formulários de exportação, importação e
+// cópia de templates entre perfis
+func manipuladorExportacao(w http.ResponseWriter, r *http.Request) {
+	if clienteAPI == nil {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+
+	perfilAtivo, err := cfg.PerfilAtivo()
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+
+	pagina := PaginaExportacao{
+		NomeServidor: perfilAtivo.Nome,
+		IndicePerfil: cfg.PerfilAtual,
+		URLAtual:     r.URL.RequestURI(),
+		Regras:       zabbix.RegrasImportacaoPadrao,
+		Perfis:       cfg.Perfis,
+		Origem:       cfg.PerfilAtual,
+	}
+
+	pagina.Auditoria, err = auditoriaLocal.Ultimos(10)
+	if err != nil {
+		log.Printf("Error reading audit trail: %v", err)
+	}
+
+	pagina.Versao, err = clienteAPI.ObterVersaoCtx(r.Context())
+	if err == nil {
+		pagina.Grupos, pagina.Templates, err = clienteAPI.ObterGruposETemplatesCtx(r.Context())
+	}
+	if err == nil {
+		pagina.Hosts, err = hostsParaSelecao(r.Context(), clienteAPI)
+	}
+	if err != nil {
+		pagina.definirErro("Erro ao obter objetos do servidor", err)
+		renderizarTemplate(w, "exportacao", pagina)
+		return
+	}
+
+	// Os templates da origem da cópia vêm do perfil escolhido, que pode ser outro
+	if origem, err := strconv.Atoi(r.URL.Query().Get("origem")); err == nil {
+		pagina.Origem = origem
+	}
+	if pagina.Origem == cfg.PerfilAtual {
+		pagina.TemplatesOrigem = pagina.Templates
+	} else if cliente, perfil, encerrar, err := clienteDoPerfil(pagina.Origem); err != nil {
+		pagina.ErroOrigem = err.Error()
+	} else {
+		_, pagina.TemplatesOrigem, err = cliente.ObterGruposETemplatesCtx(r.Context())
+		encerrar()
+		if err != nil {
+			pagina.ErroOrigem = fmt.Sprintf("Erro ao obter templates do perfil %q: %s", perfil.Nome, descreverErro(err))
+		}
+	}
+
+	pagina.MensagemSucesso = r.URL.Query().Get("sucesso")
+	pagina.MensagemErro = r.URL.Query().Get("erro")
+	renderizarTemplate(w, "exportacao", pagina)
+}
+
+// manipuladorBaixarExportacao envia como arquivo a configuração dos objetos
+// escolhidos, no formato pedido
+func manipuladorBaixarExportacao(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/exportacao", http.StatusFound)
+		return
+	}
+	if clienteAPI == nil {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+	perfilAtivo, err := cfg.PerfilAtivo()
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Erro ao processar formulário", http.StatusBadRequest)
+		return
+	}
+
+	objetos := zabbix.ObjetosConfiguracao{
+		Templates: r.PostForm["template"],
+		Grupos:    r.PostForm["grupo"],
+		Hosts:     r.PostForm["host"],
+	}
+	formato := r.PostForm.Get("formato")
+	conteudo, err := clienteAPI.ExportarConfiguracaoCtx(r.Context(), objetos, formato)
+	if err != nil {
+		log.Printf("Error exporting configuration: %v", err)
+		http.Redirect(w, r, comMensagem("/exportacao", "erro", "Erro ao exportar: "+descreverErro(err)), http.StatusFound)
+		return
+	}
+
+	tiposConteudo := map[string]string{
+		zabbix.FormatoYAML: "application/yaml",
+		zabbix.FormatoXML:  "application/xml",
+		zabbix.FormatoJSON: "application/json",
+	}
+	nomeArquivo := fmt.Sprintf("zbx_export_%s_%s.%s", perfilAtivo.Nome, time.Now().Format("20060102_150405"), formato)
+	w.Header().Set("Content-Type", tiposConteudo[formato]+"; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", nomeArquivo))
+	if _, err := io.WriteString(w, conteudo); err != nil {
+		log.Printf("Error writing configuration export: %v", err)
+	}
+}
+
+// formatoDoArquivo deduz o formato de importação pela extensão do arquivo
+func formatoDoArquivo(nome string) string {
+	switch strings.ToLower(filepath.Ext(nome)) {
+	case ".yaml", ".yml":
+		return zabbix.FormatoYAML
+	case ".xml":
+		return zabbix.FormatoXML
+	case ".json":
+		return zabbix.FormatoJSON
+	}
+	return ""
+}
+
+// manipuladorImportarConfiguracao importa no perfil ativo o arquivo enviado,
+// com as regras marcadas, e registra o resultado na auditoria
+func manipuladorImportarConfiguracao(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/exportacao", http.StatusFound)
+		return
+	}
+	if clienteAPI == nil {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, tamanhoMaximoImportacao)
+	if err := r.ParseMultipartForm(tamanhoMaximoImportacao); err != nil {
+		http.Redirect(w, r, comMensagem("/exportacao", "erro", "Arquivo inválido ou maior que 32 MiB."), http.StatusFound)
+		return
+	}
+	arquivo, cabecalho, err := r.FormFile("arquivo")
+	if err != nil {
+		http.Redirect(w, r, comMensagem("/exportacao", "erro", "Escolha o arquivo a importar."), http.StatusFound)
+		return
+	}
+	conteudo, err := io.ReadAll(arquivo)
+	arquivo.Close()
+	if err != nil {
+		http.Redirect(w, r, comMensagem("/exportacao", "erro", "Erro ao ler o arquivo enviado."), http.StatusFound)
+		return
+	}
+
+	formato := r.FormValue("formato")
+	if formato == "" {
+		formato = formatoDoArquivo(cabecalho.Filename)
+	}
+	if formato == "" {
+		http.Redirect(w, r, comMensagem("/exportacao", "erro", "Não foi possível deduzir o formato pela extensão; escolha o formato."), http.StatusFound)
+		return
+	}
+
+	regras := regrasDoFormulario(r.MultipartForm.Value)
+	err = clienteAPI.ImportarConfiguracaoCtx(r.Context(), formato, string(conteudo), regras)
+	detalhes := detalhesRegras(regras)
+	detalhes["nome"] = cabecalho.Filename
+	detalhes["formato"] = formato
+	detalhes["bytes"] = len(conteudo)
+	registrarAuditoria(r, "configuration.import", nil, detalhes, err)
+	if err != nil {
+		log.Printf("Error importing %s: %v", cabecalho.Filename, err)
+		http.Redirect(w, r, comMensagem("/exportacao", "erro", "Erro ao importar: "+descreverErro(err)), http.StatusFound)
+		return
+	}
+	http.Redirect(w, r, comMensagem("/exportacao", "sucesso", fmt.Sprintf("Arquivo %q importado.", cabecalho.Filename)), http.StatusFound)
+}
+
+// manipuladorCopiarTemplates exporta templates de um perfil e os importa em
+// outro; a auditoria registra a importação no perfil de destino
+func manipuladorCopiarTemplates(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/exportacao", http.StatusFound)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Erro ao processar formulário", http.StatusBadRequest)
+		return
+	}
+
+	indiceOrigem, errOrigem := strconv.Atoi(r.PostForm.Get("origem"))
+	indiceDestino, errDestino := strconv.Atoi(r.PostForm.Get("destino"))
+	voltar := "/exportacao?origem=" + r.PostForm.Get("origem")
+	if errOrigem != nil || errDestino != nil || indiceOrigem == indiceDestino {
+		http.Redirect(w, r, comMensagem(voltar, "erro", "Escolha perfis de origem e destino diferentes."), http.StatusFound)
+		return
+	}
+	templates := r.PostForm["template"]
+	if len(templates) == 0 {
+		http.Redirect(w, r, comMensagem(voltar, "erro", "Escolha ao menos um template."), http.StatusFound)
+		return
+	}
+
+	origem, perfilOrigem, encerrarOrigem, err := clienteDoPerfil(indiceOrigem)
+	if err != nil {
+		http.Redirect(w, r, comMensagem(voltar, "erro", err.Error()), http.StatusFound)
+		return
+	}
+	defer encerrarOrigem()
+	destino, perfilDestino, encerrarDestino, err := clienteDoPerfil(indiceDestino)
+	if err != nil {
+		http.Redirect(w, r, comMensagem(voltar, "erro", err.Error()), http.StatusFound)
+		return
+	}
+	defer encerrarDestino()
+
+	regras := regrasDoFormulario(r.PostForm)
+	err = zabbix.CopiarConfiguracaoCtx(r.Context(), origem, destino, zabbix.ObjetosConfiguracao{Templates: templates}, regras)
+	detalhes := detalhesRegras(regras)
+	detalhes["descricao"] = fmt.Sprintf("Cópia de templates do perfil %q", perfilOrigem.Nome)
+	detalhes["templatesOrigem"] = templates
+	registrarAuditoriaPerfil(r, perfilDestino, destino, "configuration.import", nil, detalhes, err)
+	if err != nil {
+		log.Printf("Error copying templates %v from %q to %q: %v", templates, perfilOrigem.Nome, perfilDestino.Nome, err)
+		http.Redirect(w, r, comMensagem(voltar, "erro", "Erro ao copiar templates: "+descreverErro(err)), http.StatusFound)
+		return
+	}
+	http.Redirect(w, r, comMensagem(voltar, "sucesso", fmt.Sprintf("%d template(s) copiado(s) de %q para %q.",
+		len(templates), perfilOrigem.Nome, perfilDestino.Nome)), http.StatusFound)
+}
+
+// alvosBackup lista os perfis atuais para o agendador de cópias. O cliente é
+// procurado pelo nome no momento da cópia, pois os perfis podem mudar.
+func alvosBackup() []backup.Alvo {
+	alvos := make([]backup.Alvo, len(cfg.Perfis))
+	for i, perfil := range cfg.Perfis {
+		nome := perfil.Nome
+		alvos[i] = backup.Alvo{
+			Perfil: nome,
+			Cliente: func() (*zabbix.ClienteAPI, func(), error) {
+				for indice := range cfg.Perfis {
+					if cfg.Perfis[indice].Nome == nome {
+						cliente, _, encerrar, err := clienteDoPerfil(indice)
+						return cliente, encerrar, err
+					}
+				}
+				return nil, nil, fmt.Errorf("perfil %q não existe mais", nome)
+			},
+		}
+	}
+	return alvos
+}
+
+// manipuladorConfigurarBackup salva a agenda, o diretório e a retenção das cópias
+func manipuladorConfigurarBackup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/config", http.StatusFound)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Erro ao processar formulário", http.StatusBadRequest)
+		return
+	}
+
+	configuracao := config.ConfiguracaoBackup{
+		Agenda:    strings.TrimSpace(r.PostForm.Get("agenda")),
+		Diretorio: strings.TrimSpace(r.PostForm.Get("diretorio")),
+	}
+	for campo, destino := range map[string]*int{
+		"manter_quantidade": &configuracao.ManterQuantidade,
+		"manter_dias":       &configuracao.ManterDias,
+	} {
+		texto := strings.TrimSpace(r.PostForm.Get(campo))
+		if texto == "" {
+			continue
+		}
+		n, err := strconv.Atoi(texto)
+		if err != nil {
+			http.Redirect(w, r, comMensagem("/config", "erro", "Retenção inválida: "+texto), http.StatusFound)
+			return
+		}
+		*destino = n
+	}
+	if configuracao.Diretorio != "" && !filepath.IsAbs(configuracao.Diretorio) {
+		http.Redirect(w, r, comMensagem("/config", "erro", "Informe o caminho completo do diretório de cópias"), http.StatusFound)
+		return
+	}
+
+	if err := agendadorBackup.Configurar(configuracao); err != nil {
+		http.Redirect(w, r, comMensagem("/config", "erro", err.Error()), http.StatusFound)
+		return
+	}
+	cfg.Backup = configuracao
+	if err := cfg.Salvar(arquivoConfig); err != nil {
+		http.Redirect(w, r, comMensagem("/config", "erro", fmt.Sprintf("Erro ao salvar configuração: %v", err)), http.StatusFound)
+		return
+	}
+
+	mensagem := "Cópias agendadas desativadas"
+	if configuracao.Ativo() {
+		mensagem = "Agenda de cópias salva"
+	}
+	http.Redirect(w, r, comMensagem("/config", "sucesso", mensagem), http.StatusFound)
+}
+
+// manipuladorExecutarBackup pede uma cópia imediata de todos os perfis
+func manipuladorExecutarBackup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/config", http.StatusFound)
+		return
+	}
+	if len(cfg.Perfis) == 0 {
+		http.Redirect(w, r, comMensagem("/config", "erro", "Não há perfis para copiar"), http.StatusFound)
+		return
+	}
+
+	agendadorBackup.Solicitar()
+	http.Redirect(w, r, comMensagem("/config", "sucesso", "Cópia de segurança iniciada; atualize a página para ver o resultado"), http.StatusFound)
+}
+
+// Modos e escopos da página de comparação
+const (
+	modoComparacaoCopias      = "copias"
+	modoComparacaoPerfis      = "perfis"
+	escopoComparacaoTemplates = "templates"
+	escopoComparacaoTudo      = "tudo"
+)
+
+// manipuladorComparacao compara a configuração entre duas cópias de segurança
+// de um perfil ou entre dois perfis. Com formato=json responde apenas com as
+// mudanças, em JSON.
+func manipuladorComparacao(w http.ResponseWriter, r *http.Request) {
+	consulta := r.URL.Query()
+	pagina := PaginaComparacao{
+		IndicePerfil: cfg.PerfilAtual,
+		URLAtual:     r.URL.RequestURI(),
+		Modo:         consulta.Get("modo"),
+		PerfilCopias: consulta.Get("perfil"),
+		Antes:        consulta.Get("antes"),
+		Depois:       consulta.Get("depois"),
+		Perfis:       cfg.Perfis,
+		Escopo:       consulta.Get("escopo"),
+		Filtro:       strings.TrimSpace(consulta.Get("filtro")),
+	}
+	if perfilAtivo, err := cfg.PerfilAtivo(); err == nil {
+		pagina.NomeServidor = perfilAtivo.Nome
+	}
+	if pagina.Modo != modoComparacaoPerfis {
+		pagina.Modo = modoComparacaoCopias
+	}
+	if pagina.Escopo != escopoComparacaoTudo {
+		pagina.Escopo = escopoComparacaoTemplates
+	}
+
+	// Por padrão, o primeiro perfil contra o segundo
+	pagina.Origem, pagina.Destino = 0, 1
+	if origem, err := strconv.Atoi(consulta.Get("origem")); err == nil {
+		pagina.Origem = origem
+	}
+	if destino, err := strconv.Atoi(consulta.Get("destino")); err == nil {
+		pagina.Destino = destino
+	}
+
+	// Por padrão, as duas cópias mais recentes do perfil ativo ou do primeiro com cópias
+	for _, estado := range agendadorBackup.Situacao().Perfis {
+		if len(estado.Arquivos) > 0 {
+			pagina.Copias = append(pagina.Copias, estado)
+		}
+	}
+	copias := copiasDoPerfil(pagina.Copias, pagina.PerfilCopias)
+	if copias == nil {
+		copias = copiasDoPerfil(pagina.Copias, pagina.NomeServidor)
+	}
+	if copias == nil && len(pagina.Copias) > 0 {
+		copias = &pagina.Copias[0]
+	}
+	if copias != nil {
+		pagina.PerfilCopias = copias.Perfil
+		if pagina.Depois == "" {
+			pagina.Depois = copias.Arquivos[0].Nome()
+		}
+		if pagina.Antes == "" {
+			pagina.Antes = copias.Arquivos[len(copias.Arquivos)-1].Nome()
+			if len(copias.Arquivos) > 1 {
+				pagina.Antes = copias.Arquivos[1].Nome()
+			}
+		}
+	}
+
+	if consulta.Get("comparar") != "1" {
+		renderizarTemplate(w, "comparacao", pagina)
+		return
+	}
+
+	var antes, depois []byte
+	var err error
+	status := http.StatusBadRequest
+	if pagina.Modo == modoComparacaoCopias {
+		if copias == nil {
+			err = errors.New("não há cópias de segurança gravadas")
+		} else {
+			antes, pagina.DescricaoAntes, err = lerCopiaParaComparacao(copias, pagina.Antes)
+			if err == nil {
+				depois, pagina.DescricaoDepois, err = lerCopiaParaComparacao(copias, pagina.Depois)
+			}
+		}
+	} else if pagina.Origem == pagina.Destino {
+		err = errors.New("escolha dois perfis diferentes")
+	} else {
+		status = http.StatusBadGateway
+		antes, pagina.DescricaoAntes, err = exportarParaComparacao(r.Context(), pagina.Origem, pagina.Escopo)
+		if err == nil {
+			depois, pagina.DescricaoDepois, err = exportarParaComparacao(r.Context(), pagina.Destino, pagina.Escopo)
+		}
+	}
+	if err == nil {
+		pagina.Resultado, err = comparacao.Comparar(antes, depois)
+		pagina.Resultado = pagina.Resultado.Filtrar(pagina.Filtro)
+	}
+
+	if consulta.Get("formato") == "json" {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		codificador := json.NewEncoder(w)
+		codificador.SetEscapeHTML(false)
+		codificador.SetIndent("", "  ")
+		if err != nil {
+			w.WriteHeader(status)
+			codificador.Encode(map[string]string{"erro": err.Error()})
+			return
+		}
+		resposta := struct {
+			Antes  string `json:"antes"`
+			Depois string `json:"depois"`
+			comparacao.Resultado
+		}{pagina.DescricaoAntes, pagina.DescricaoDepois, pagina.Resultado}
+		if err := codificador.Encode(resposta); err != nil {
+			log.Printf("Error writing configuration diff: %v", err)
+		}
+		return
+	}
+
+	if err != nil {
+		log.Printf("Error comparing configuration: %v", err)
+		pagina.definirErro("Erro ao comparar", err)
+		renderizarTemplate(w, "comparacao", pagina)
+		return
+	}
+
+	consultaJSON := r.URL.Query()
+	consultaJSON.Set("formato", "json")
+	pagina.URLJSON = "/comparacao?" + consultaJSON.Encode()
+	pagina.Comparado = true
+	pagina.Grupos = pagina.Resultado.Grupos()
+	renderizarTemplate(w, "comparacao", pagina)
+}
+
+// copiasDoPerfil retorna as cópias do perfil pelo nome ou nil
+func copiasDoPerfil(copias []backup.Estado, perfil string) *backup.Estado {
+	for i := range copias {
+		if copias[i].Perfil == perfil {
+			return &copias[i]
+		}
+	}
+	return nil
+}
+
+// lerCopiaParaComparacao lê uma cópia do perfil pelo nome do arquivo. Só são
+// aceitos arquivos listados no diretório do perfil.
+func lerCopiaParaComparacao(copias *backup.Estado, nome string) ([]byte, string, error) {
+	for _, arquivo := range copias.Arquivos {
+		if arquivo.Nome() == nome {
+			conteudo, err := backup.Ler(arquivo.Caminho)
+			descricao := fmt.Sprintf("%s em %s", copias.Perfil, arquivo.Momento.Format("02/01/2006 15:04:05"))
+			return conteudo, descricao, err
+		}
+	}
+	return nil, "", fmt.Errorf("cópia %q não encontrada para o perfil %q", nome, copias.Perfil)
+}
+
+// exportarParaComparacao exporta em JSON a configuração atual de um perfil:
+// apenas os templates ou todos os objetos, como nas cópias de segurança
+func exportarParaComparacao(ctx context.Context, indice int, escopo string) ([]byte, string, error) {
+	cliente, perfil, encerrar, err := clienteDoPerfil(indice)
+	if err != nil {
+		return nil, "", err
+	}
+	defer encerrar()
+	descricao := perfil.Nome + " (atual)"
+
+	var objetos zabbix.ObjetosConfiguracao
+	if escopo == escopoComparacaoTemplates {
+		objetos.Templates, err = cliente.ObterIDsTemplatesCtx(ctx)
+	} else {
+		objetos, err = cliente.ObterTodosObjetosConfiguracaoCtx(ctx)
+	}
+	if err != nil {
+		return nil, descricao, fmt.Errorf("%s: %w", perfil.Nome, err)
+	}
+	// Um servidor sem objetos equivale a um documento vazio
+	if objetos.Vazio() {
+		return []byte(`{"zabbix_export":{}}`), descricao, nil
+	}
+
+	conteudo, err := cliente.ExportarConfiguracaoCtx(ctx, objetos, zabbix.FormatoJSON)
+	if err != nil {
+		return nil, descricao, fmt.Errorf("%s: %w", perfil.Nome, err)
+	}
+	return []byte(conteudo), descricao, nil
+}
+
+// carregarPlano lê os arquivos YAML do caminho e planeja as operações que
+// deixam o servidor como eles
+func carregarPlano(ctx context.Context, cliente *zabbix.ClienteAPI, caminhos ...string) (*zabbix.Plano, []string, error) {
+	declaracao, arquivos, err := declarativo.Carregar(caminhos...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("erro nos arquivos: %w", err)
+	}
+	plano, err := cliente.PlanejarDeclaracaoCtx(ctx, declaracao)
+	if err != nil {
+		return nil, arquivos, err
+	}
+	return plano, arquivos, nil
+}
+
+// registrarPlano grava na auditoria cada operação enviada ao servidor pelo
+// cliente; as puladas ficam de fora
+func registrarPlano(origem string, perfil *config.ConfiguracaoPerfil, cliente *zabbix.ClienteAPI, plano *zabbix.Plano) {
+	for _, op := range plano.Operacoes {
+		if op.Situacao != zabbix.SituacaoAplicada && op.Situacao != zabbix.SituacaoFalhou {
+			continue
+		}
+		detalhes := map[string]interface{}{
+			"acao": op.Acao,
+			"tipo": op.Tipo,
+		}
+		if len(op.Mudancas) > 0 {
+			detalhes["mudancas"] = op.Mudancas
+		}
+		registrarAuditoriaOrigem(origem, perfil, cliente, op.Metodo, []string{op.Nome}, detalhes, op.Err)
+	}
+}
+
+// manipuladorDeclarativo mostra, sem alterar nada, o plano que deixa o perfil
+// ativo como os arquivos YAML do caminho informado
+func manipuladorDeclarativo(w http.ResponseWriter, r *http.Request) {
+	if clienteAPI == nil {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+	perfilAtivo, err := cfg.PerfilAtivo()
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+
+	consulta := r.URL.Query()
+	pagina := PaginaDeclarativa{
+		NomeServidor: perfilAtivo.Nome,
+		IndicePerfil: cfg.PerfilAtual,
+		URLAtual:     r.URL.RequestURI(),
+		Base:         cfg.CaminhoDeclarativo,
+		Caminho:      strings.TrimSpace(consulta.Get("caminho")),
+	}
+	pagina.MensagemSucesso = consulta.Get("sucesso")
+	pagina.MensagemErro = consulta.Get("erro")
+
+	if consulta.Get("planejar") == "1" {
+		caminho, err := caminhoDeclarativo(pagina.Caminho)
+		if err != nil {
+			pagina.MensagemErro = "Caminho inválido: " + err.Error()
+		} else {
+			pagina.Plano, pagina.Arquivos, err = carregarPlano(r.Context(), clienteAPI, caminho)
+			if err != nil {
+				pagina.definirErro("Erro ao planejar", err)
+			}
+		}
+	}
+	renderizarTemplate(w, "declarativo", pagina)
+}
+
+// caminhoDeclarativo resolve o caminho informado na página dentro do diretório
+// configurado em caminhoDeclarativo. Caminhos absolutos ou que saem dele são
+// recusados, para que a página não leia arquivos arbitrários do servidor.
+func caminhoDeclarativo(relativo string) (string, error) {
+	base := strings.TrimSpace(cfg.CaminhoDeclarativo)
+	if base == "" {
+		return "", errors.New("defina caminhoDeclarativo no arquivo de configuração com o diretório dos arquivos YAML")
+	}
+	if relativo == "" || relativo == "." {
+		return base, nil
+	}
+	if !filepath.IsLocal(relativo) {
+		return "", errors.New("informe um arquivo ou subdiretório relativo ao diretório configurado, sem \"..\"")
+	}
+	return filepath.Join(base, relativo), nil
+}
+
+// manipuladorAplicarDeclarativo planeja de novo e aplica o plano se ele for o
+// mesmo que foi revisado, registrando cada operação na auditoria
+func manipuladorAplicarDeclarativo(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/declarativo", http.StatusFound)
+		return
+	}
+	if clienteAPI == nil {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+	perfilAtivo, err := cfg.PerfilAtivo()
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Erro ao processar formulário", http.StatusBadRequest)
+		return
+	}
+
+	relativo := strings.TrimSpace(r.PostForm.Get("caminho"))
+	voltar := "/declarativo?planejar=1&caminho=" + url.QueryEscape(relativo)
+	caminho, err := caminhoDeclarativo(relativo)
+	if err != nil {
+		http.Redirect(w, r, comMensagem("/declarativo", "erro", "Caminho inválido: "+err.Error()), http.StatusFound)
+		return
+	}
+
+	plano, arquivos, err := carregarPlano(r.Context(), clienteAPI, caminho)
+	if err != nil {
+		http.Redirect(w, r, comMensagem("/declarativo", "erro", "Erro ao planejar: "+descreverErro(err)), http.StatusFound)
+		return
+	}
+	if plano.Assinatura() != r.PostForm.Get("assinatura") {
+		http.Redirect(w, r, comMensagem(voltar, "erro", "O servidor ou os arquivos mudaram desde a prévia. Revise o plano abaixo antes de aplicar."), http.StatusFound)
+		return
+	}
+
+	pagina := PaginaDeclarativa{
+		NomeServidor: perfilAtivo.Nome,
+		IndicePerfil: cfg.PerfilAtual,
+		URLAtual:     "/declarativo",
+		Base:         cfg.CaminhoDeclarativo,
+		Caminho:      relativo,
+		Arquivos:     arquivos,
+		Plano:        plano,
+		Aplicado:     true,
+	}
+	err = clienteAPI.AplicarPlanoCtx(r.Context(), plano)
+	registrarPlano(r.RemoteAddr, perfilAtivo, clienteAPI, plano)
+	if err != nil {
+		log.Printf("Error applying declarative plan from %s: %v", caminho, err)
+		pagina.MensagemErro = "Plano aplicado em parte: " + err.Error() + "."
+	} else {
+		pagina.MensagemSucesso = fmt.Sprintf("Plano aplicado: %d operação(ões).", len(plano.Operacoes))
+	}
+	renderizarTemplate(w, "declarativo", pagina)
+}
+
+// consultaMacros lê a macro e o grupo consultados na página de macros
+func consultaMacros(valores url.Values) (string, zabbix.FiltroHosts, string) {
+	macro := strings.TrimSpace(valores.Get("macro"))
+	grupo := valores.Get("grupo")
+	var filtro zabbix.FiltroHosts
+	if grupo != "" {
+		filtro.GrupoIDs = []string{grupo}
+	}
+	return macro, filtro, grupo
+}
+
+// manipuladorMacros mostra o valor efetivo da macro consultada em cada host,
+// de onde ele vem e as macros globais
+func manipuladorMacros(w http.ResponseWriter, r *http.Request) {
+	if clienteAPI == nil {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+	perfilAtivo, err := cfg.PerfilAtivo()
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+
+	consulta := r.URL.Query()
+	macro, filtro, grupo := consultaMacros(consulta)
+	pagina := PaginaMacros{
+		NomeServidor: perfilAtivo.Nome,
+		IndicePerfil: cfg.PerfilAtual,
+		URLAtual:     r.URL.RequestURI(),
+		Macro:        macro,
+		GrupoID:      grupo,
+	}
+
+	pagina.Grupos, pagina.Templates, err = clienteAPI.ObterGruposETemplatesCtx(r.Context())
+	if err != nil {
+		log.Printf("Error loading macro filter options: %v", err)
+		pagina.definirErro("Erro ao obter grupos e templates", err)
+	}
+	if pagina.MensagemErro == "" && pagina.TentarEm == 0 {
+		pagina.Globais, err = clienteAPI.ObterMacrosGlobaisCtx(r.Context())
+		if err != nil {
+			log.Printf("Error loading global macros: %v", err)
+			pagina.definirErro("Erro ao obter macros globais", err)
+		}
+	}
+
+	if macro != "" && pagina.MensagemErro == "" && pagina.TentarEm == 0 {
+		pagina.Efetivas, err = clienteAPI.ResolverMacroCtx(r.Context(), macro, filtro, cfg.TamanhoPaginaHosts)
+		if err != nil {
+			log.Printf("Error resolving macro %s: %v", macro, err)
+			pagina.definirErro("Erro ao consultar a macro", err)
+		}
+		pagina.Consultada = err == nil
+		for _, e := range pagina.Efetivas {
+			switch {
+			case e.NoHost():
+				pagina.NoHost++
+			case e.Definida():
+				pagina.Herdada++
+			default:
+				pagina.NaoDefinida++
+			}
+		}
+	}
+
+	if mensagem := consulta.Get("sucesso"); mensagem != "" {
+		pagina.MensagemSucesso = mensagem
+	}
+	if mensagem := consulta.Get("erro"); mensagem != "" {
+		pagina.MensagemErro = mensagem
+	}
+	renderizarTemplate(w, "macros", pagina)
+}
+
+// manipuladorExportarMacros exporta em CSV o valor efetivo da macro em cada
+// host, com os valores secretos mascarados
+func manipuladorExportarMacros(w http.ResponseWriter, r *http.Request) {
+	if clienteAPI == nil {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+	perfilAtivo, err := cfg.PerfilAtivo()
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+
+	macro, filtro, _ := consultaMacros(r.URL.Query())
+	if macro == "" {
+		http.Error(w, "Informe a macro", http.StatusBadRequest)
+		return
+	}
+	efetivas, err := clienteAPI.ResolverMacroCtx(r.Context(), macro, filtro, cfg.TamanhoPaginaHosts)
+	if err != nil {
+		log.Printf("Error exporting macro %s: %v", macro, err)
+		http.Redirect(w, r, urlFalha("/macros", err), http.StatusFound)
+		return
+	}
+
+	nome := strings.Trim(strings.NewReplacer("{$", "", "}", "", ":", "_", "\"", "", "/", "_").Replace(macro), "_")
+	nomeArquivo := fmt.Sprintf("macro_%s_%s.csv", perfilAtivo.Nome, nome)
+	saida := &respostaCSV{w: w, nomeArquivo: nomeArquivo}
+	if err := zabbix.EscreverMacrosEfetivasCSV(saida, macro, efetivas); err != nil {
+		log.Printf("Error writing macro CSV: %v", err)
+	}
+}
+
+// manipuladorAlterarMacros define, sobrescreve ou remove a macro no próprio
+// nível dos hosts marcados ou de um template, registrando cada chamada na
+// auditoria com o valor mascarado nas macros secretas
+func manipuladorAlterarMacros(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/macros", http.StatusFound)
+		return
+	}
+	if clienteAPI == nil {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Erro ao processar formulário", http.StatusBadRequest)
+		return
+	}
+
+	voltar := caminhoLocal(r.PostForm.Get("voltar"), "/macros")
+	alteracao := zabbix.AlteracaoMacro{
+		Acao:      r.PostForm.Get("acao"),
+		Macro:     strings.TrimSpace(r.PostForm.Get("macro")),
+		Valor:     r.PostForm.Get("valor"),
+		Descricao: strings.TrimSpace(r.PostForm.Get("descricao")),
+		Tipo:      r.PostForm.Get("tipo"),
+	}
+	ids := r.PostForm["id"]
+	objetos := "host(s)"
+	if r.PostForm.Get("nivel") == zabbix.NivelMacroTemplate {
+		objetos = "template(s)"
+	}
+
+	etapas, err := clienteAPI.AplicarAlteracaoMacroCtx(r.Context(), ids, alteracao)
+	for _, etapa := range etapas {
+		detalhes := map[string]interface{}{"descricao": etapa.Descricao}
+		for chave, valor := range etapa.Detalhes {
+			detalhes[chave] = valor
+		}
+		registrarAuditoria(r, etapa.Metodo, etapa.Hosts, detalhes, etapa.Err)
+	}
+	switch {
+	case err != nil:
+		log.Printf("Error changing macro %s on %v: %v", alteracao.Macro, ids, err)
+		mensagem := "Erro ao alterar a macro: " + descreverErro(err)
+		if len(etapas) > 1 {
+			mensagem += fmt.Sprintf(" As %d etapas anteriores foram aplicadas.", len(etapas)-1)
+		}
+		http.Redirect(w, r, comMensagem(voltar, "erro", mensagem), http.StatusFound)
+	case len(etapas) == 0:
+		http.Redirect(w, r, comMensagem(voltar, "sucesso", "Os "+objetos+" já estavam como pedido; nada foi alterado."), http.StatusFound)
+	default:
+		descricoes := make([]string, len(etapas))
+		for i, etapa := range etapas {
+			descricoes[i] = fmt.Sprintf("%s (%d %s)", etapa.Descricao, len(etapa.Hosts), objetos)
+		}
+		http.Redirect(w, r, comMensagem(voltar, "sucesso", "Macro alterada: "+strings.Join(descricoes, ", ")+"."), http.StatusFound)
+	}
+}
+
+// manipuladorSalvarMacroGlobal cria ou altera uma macro global
+func manipuladorSalvarMacroGlobal(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/macros", http.StatusFound)
+		return
+	}
+	if clienteAPI == nil {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Erro ao processar formulário", http.StatusBadRequest)
+		return
+	}
+
+	voltar := caminhoLocal(r.PostForm.Get("voltar"), "/macros")
+	macro := zabbix.MacroUsuario{
+		IDGlobal:  r.PostForm.Get("id"),
+		Macro:     strings.TrimSpace(r.PostForm.Get("macro")),
+		Valor:     r.PostForm.Get("valor"),
+		Descricao: strings.TrimSpace(r.PostForm.Get("descricao")),
+		Tipo:      r.PostForm.Get("tipo"),
+	}
+	acao := "usermacro.createglobal"
+	if macro.IDGlobal != "" {
+		acao = "usermacro.updateglobal"
+	}
+
+	id, err := clienteAPI.SalvarMacroGlobalCtx(r.Context(), macro)
+	detalhes := map[string]interface{}{
+		"macro": macro.Macro,
+		"valor": macro.ValorExibido(),
+		"tipo":  macro.NomeTipo(),
+	}
+	if macro.Descricao != "" {
+		detalhes["descricao_macro"] = macro.Descricao
+	}
+	registrarAuditoria(r, acao, []string{id}, detalhes, err)
+	if err != nil {
+		log.Printf("Error saving global macro %s: %v", macro.Macro, err)
+		http.Redirect(w, r, comMensagem(voltar, "erro", "Erro ao salvar a macro global: "+descreverErro(err)), http.StatusFound)
+		return
+	}
+	http.Redirect(w, r, comMensagem(voltar, "sucesso", fmt.Sprintf("Macro global %s salva.", macro.Macro)), http.StatusFound)
+}
+
+// manipuladorRemoverMacroGlobal remove uma macro global
+func manipuladorRemoverMacroGlobal(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/macros", http.StatusFound)
+		return
+	}
+	if clienteAPI == nil {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Erro ao processar formulário", http.StatusBadRequest)
+		return
+	}
+
+	voltar := caminhoLocal(r.PostForm.Get("voltar"), "/macros")
+	id := r.PostForm.Get("id")
+	macro := r.PostForm.Get("macro")
+
+	err := clienteAPI.RemoverMacrosGlobaisCtx(r.Context(), []string{id})
+	registrarAuditoria(r, "usermacro.deleteglobal", []string{id}, map[string]interface{}{"macro": macro}, err)
+	if err != nil {
+		log.Printf("Error removing global macro %s: %v", id, err)
+		http.Redirect(w, r, comMensagem(voltar, "erro", "Erro ao remover a macro global: "+descreverErro(err)), http.StatusFound)
+		return
+	}
+	http.Redirect(w, r, comMensagem(voltar, "sucesso", fmt.Sprintf("Macro global %s removida.", macro)), http.StatusFound)
+}
+
+// usoComandos descreve os subcomandos de linha de comando
+const usoComandos = `Uso:
+  zabbix-manager                            inicia a interface web na porta 5000
+  zabbix-manager plan [opções] CAMINHO...   mostra o que mudaria para o servidor ficar como os arquivos YAML
+  zabbix-manager apply [opções] CAMINHO...  aplica essas mudanças
+
+CAMINHO é um arquivo YAML ou um diretório com arquivos .yaml e .yml.
+
+Opções:
+  -perfil NOME  usa o perfil informado no lugar do perfil ativo
+  -sim          (apply) aplica sem pedir confirmação
+`
+
+// executarComando executa um subcomando e retorna o código de saída: 0 em
+// sucesso, 1 em falha e 2 em uso incorreto
+func executarComando(nome string, args []string) int {
+	switch nome {
+	case "plan", "apply":
+		return comandoPlano(nome, args)
+	case "help", "-h", "-help", "--help":
+		fmt.Print(usoComandos)
+		return 0
+	}
+	fmt.Fprintf(os.Stderr, "Comando desconhecido: %s\n\n%s", nome, usoComandos)
+	return 2
+}
+
+// comandoPlano mostra o plano dos arquivos e, no apply, aplica-o depois da
+// confirmação
+func comandoPlano(nome string, args []string) int {
+	opcoes := flag.NewFlagSet(nome, flag.ContinueOnError)
+	opcoes.Usage = func() { fmt.Fprint(os.Stderr, usoComandos) }
+	nomePerfil := opcoes.String("perfil", "", "perfil usado no lugar do perfil ativo")
+	confirmado := opcoes.Bool("sim", false, "aplica sem pedir confirmação")
+	if err := opcoes.Parse(args); err != nil {
+		return 2
+	}
+	if opcoes.NArg() == 0 {
+		opcoes.Usage()
+		return 2
+	}
+
+	perfil, err := perfilDoComando(*nomePerfil)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	cliente := zabbix.NovoClienteAPI(configAPIPerfil(perfil))
+	defer func() {
+		if err := cliente.EncerrarSessao(); err != nil {
+			log.Printf("Error closing Zabbix session for profile %q: %v", perfil.Nome, err)
+		}
+	}()
+
+	ctx, cancelar := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancelar()
+
+	plano, arquivos, err := carregarPlano(ctx, cliente, opcoes.Args()...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro ao planejar: %s\n", descreverErro(err))
+		return 1
+	}
+
+	fmt.Printf("Perfil %q (Zabbix %s), %d arquivo(s)\n\n", perfil.Nome, plano.Versao, len(arquivos))
+	imprimirPlano(os.Stdout, plano)
+	if nome != "apply" || plano.Vazio() {
+		return 0
+	}
+
+	if !*confirmado {
+		fmt.Printf("\nAplicar estas %d operação(ões) no perfil %q? Digite \"sim\" para confirmar: ", len(plano.Operacoes), perfil.Nome)
+		resposta, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if strings.TrimSpace(resposta) != "sim" {
+			fmt.Println("Nada foi alterado.")
+			return 1
+		}
+	}
+
+	err = cliente.AplicarPlanoCtx(ctx, plano)
+	registrarPlano("linha de comando", perfil, cliente, plano)
+	fmt.Println()
+	imprimirResultado(os.Stdout, plano)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "\nPlano aplicado em parte: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// perfilDoComando retorna o perfil pelo nome ou, sem nome, o perfil ativo
+func perfilDoComando(nome string) (*config.ConfiguracaoPerfil, error) {
+	if nome == "" {
+		return cfg.PerfilAtivo()
+	}
+	for i := range cfg.Perfis {
+		if cfg.Perfis[i].Nome == nome {
+			return &cfg.Perfis[i], nil
+		}
+	}
+	return nil, fmt.Errorf("perfil %q não encontrado", nome)
+}
+
+// simbolosAcao marcam cada operação na saída do plano
+var simbolosAcao = map[string]string{
+	zabbix.AcaoCriar:     "+",
+	zabbix.AcaoAtualizar: "~",
+	zabbix.AcaoRemover:   "-",
+}
+
+// imprimirPlano escreve as operações do plano e as mudanças de cada uma
+func imprimirPlano(w io.Writer, plano *zabbix.Plano) {
+	if plano.Vazio() {
+		fmt.Fprintln(w, "Nenhuma mudança: o servidor já está como os arquivos.")
+		return
+	}
+	for _, op := range plano.Operacoes {
+		fmt.Fprintf(w, "%s %s %s %q\n", simbolosAcao[op.Acao], op.Acao, op.Tipo, op.Nome)
+		for _, mudanca := range op.Mudancas {
+			fmt.Fprintf(w, "      %s\n", mudanca)
+		}
+	}
+	fmt.Fprintf(w, "\nPlano: %d a criar, %d a atualizar, %d a remover.\n",
+		plano.Contar(zabbix.AcaoCriar), plano.Contar(zabbix.AcaoAtualizar), plano.Contar(zabbix.AcaoRemover))
+}
+
+// imprimirResultado escreve a situação de cada operação depois de aplicar
+func imprimirResultado(w io.Writer, plano *zabbix.Plano) {
+	for _, op := range plano.Operacoes {
+		linha := fmt.Sprintf("%-8s %s %s %q", op.Situacao, op.Acao, op.Tipo, op.Nome)
+		if op.Err != nil {
+			linha += ": " + descreverErro(op.Err)
+		}
+		fmt.Fprintln(w, linha)
+	}
+	fmt.Fprintf(w, "\nAplicadas: %d, com falha: %d, puladas: %d.\n",
+		plano.ContarSituacao(zabbix.SituacaoAplicada), plano.ContarSituacao(zabbix.SituacaoFalhou), plano.ContarSituacao(zabbix.SituacaoPulada))
 }
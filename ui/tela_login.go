@@ -1,6 +1,8 @@
 package ui
 
 import (
+	"time"
+
 	"zabbix-manager/config"
 	"zabbix-manager/zabbix"
 )
@@ -9,19 +11,22 @@ import (
 type TelaLogin interface {
 	// Exibir exibe a tela de login
 	Exibir()
-	
+
 	// ProcessarLogin processa o login com os dados fornecidos
 	ProcessarLogin(url, token string) error
-	
+
+	// ProcessarLoginUsuario processa o login com usuário e senha (user.login)
+	ProcessarLoginUsuario(url, usuario, senha string) error
+
 	// AdicionarPerfil adiciona um novo perfil de servidor
 	AdicionarPerfil(nome, url, token string) error
-	
+
 	// EditarPerfil edita um perfil existente
 	EditarPerfil(indice int, nome, url, token string) error
-	
+
 	// RemoverPerfil remove um perfil existente
 	RemoverPerfil(indice int) error
-	
+
 	// ObterPerfis retorna a lista de perfis configurados
 	ObterPerfis() []config.ConfiguracaoPerfil
 }
@@ -32,10 +37,30 @@ func TestarConexao(url, token string) error {
 	configAPI := zabbix.ConfigAPI{
 		URL:         url,
 		Token:       token,
-		TempoLimite: 30, // 30 segundos
+		TempoLimite: 30 * time.Second,
 	}
 	clienteAPI := zabbix.NovoClienteAPI(configAPI)
-	
+
 	// Testar conexão
 	return clienteAPI.TestarConexao()
-}
\ No newline at end of file
+}
+
+// TestarConexaoUsuario testa a conexão e o login com usuário e senha,
+// encerrando a sessão criada ao final do teste
+func TestarConexaoUsuario(url, usuario, senha string) error {
+	configAPI := zabbix.ConfigAPI{
+		URL:         url,
+		Usuario:     usuario,
+		Senha:       senha,
+		TempoLimite: 30 * time.Second,
+	}
+	clienteAPI := zabbix.NovoClienteAPI(configAPI)
+
+	if err := clienteAPI.TestarConexao(); err != nil {
+		return err
+	}
+	if err := clienteAPI.IniciarSessao(); err != nil {
+		return err
+	}
+	return clienteAPI.EncerrarSessao()
+}
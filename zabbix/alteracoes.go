@@ -0,0 +1,438 @@
+package zabbix
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AlteracaoHosts descreve uma alteração em massa de hosts. Campos vazios não
+// alteram nada.
+type AlteracaoHosts struct {
+	Status               string // "0" ativa e "1" desativa o monitoramento; vazio mantém
+	VincularTemplates    []string
+	DesvincularTemplates []string // Desvincula sem apagar itens e triggers herdados
+	AdicionarGrupos      []string
+	RemoverGrupos        []string
+	DefinirTags          []Tag // Substitui os valores atuais de cada tag
+	RemoverTags          []Tag // Sem valor remove a tag com qualquer valor
+}
+
+// Vazia informa se nenhuma alteração foi pedida
+func (a AlteracaoHosts) Vazia() bool {
+	return a.Status == "" && len(a.VincularTemplates) == 0 && len(a.DesvincularTemplates) == 0 &&
+		len(a.AdicionarGrupos) == 0 && len(a.RemoverGrupos) == 0 &&
+		len(a.DefinirTags) == 0 && len(a.RemoverTags) == 0
+}
+
+// Validar confere se as alterações podem ser aplicadas juntas
+func (a AlteracaoHosts) Validar() error {
+	if a.Vazia() {
+		return errors.New("nenhuma alteração escolhida")
+	}
+	if _, ok := StatusHost[a.Status]; a.Status != "" && !ok {
+		return fmt.Errorf("status inválido: %q", a.Status)
+	}
+	if id, ok := emComum(a.VincularTemplates, a.DesvincularTemplates); ok {
+		return fmt.Errorf("o template %s não pode ser vinculado e desvinculado ao mesmo tempo", id)
+	}
+	if id, ok := emComum(a.AdicionarGrupos, a.RemoverGrupos); ok {
+		return fmt.Errorf("o grupo %s não pode ser adicionado e removido ao mesmo tempo", id)
+	}
+	for _, definir := range a.DefinirTags {
+		for _, remover := range a.RemoverTags {
+			if definir.Nome == remover.Nome {
+				return fmt.Errorf("a tag %q não pode ser definida e removida ao mesmo tempo", definir.Nome)
+			}
+		}
+	}
+	return nil
+}
+
+// emComum retorna o primeiro ID presente nas duas listas
+func emComum(a, b []string) (string, bool) {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return x, true
+			}
+		}
+	}
+	return "", false
+}
+
+// ParseTags interpreta tags separadas por vírgula no formato "nome=valor" ou "nome"
+func ParseTags(texto string) []Tag {
+	var tags []Tag
+	for _, parte := range strings.Split(texto, ",") {
+		parte = strings.TrimSpace(parte)
+		if parte == "" {
+			continue
+		}
+		nome, valor, _ := strings.Cut(parte, "=")
+		tags = append(tags, Tag{Nome: strings.TrimSpace(nome), Valor: strings.TrimSpace(valor)})
+	}
+	return tags
+}
+
+// PrevisaoHost lista o que uma alteração em massa muda em um host
+type PrevisaoHost struct {
+	ID       string
+	Nome     string
+	Mudancas []string // Vazio quando o host já está como pedido
+	Avisos   []string // Mudanças que o Zabbix deve recusar
+}
+
+// ErrPrevisaoDesatualizada indica que o estado dos hosts mudou entre a prévia
+// e a aplicação, e a alteração aplicada não seria a que foi revisada
+var ErrPrevisaoDesatualizada = errors.New("os hosts mudaram depois da prévia; revise a alteração novamente")
+
+// AssinaturaPrevisao resume as mudanças previstas em cada host. Duas prévias
+// com a mesma assinatura fazem as mesmas alterações, o que permite confirmar
+// que o que é aplicado é o que foi revisado.
+func AssinaturaPrevisao(previsoes []PrevisaoHost) string {
+	resumo := sha256.New()
+	for _, p := range previsoes {
+		fmt.Fprintf(resumo, "%s\x00%s\x00%s\n", p.ID, strings.Join(p.Mudancas, "\x00"), strings.Join(p.Avisos, "\x00"))
+	}
+	return hex.EncodeToString(resumo.Sum(nil))[:16]
+}
+
+// EtapaAlteracao é uma chamada da API feita para aplicar a alteração, com os
+// hosts que ela muda
+type EtapaAlteracao struct {
+	Metodo    string
+	Descricao string
+	Hosts     []string
+	Detalhes  map[string]interface{} // Valores enviados, para registro
+	Err       error
+
+	params []interface{} // Mais de um parâmetro vira um lote de chamadas
+}
+
+// PreverAlteracaoHosts mostra, sem alterar nada, o que a alteração mudaria em
+// cada host, a partir do estado atual do servidor e não do cache
+func (c *ClienteAPI) PreverAlteracaoHosts(hostIDs []string, alteracao AlteracaoHosts) ([]PrevisaoHost, error) {
+	return c.PreverAlteracaoHostsCtx(context.Background(), hostIDs, alteracao)
+}
+
+// PreverAlteracaoHostsCtx é a variante de PreverAlteracaoHosts que aceita um contexto
+func (c *ClienteAPI) PreverAlteracaoHostsCtx(ctx context.Context, hostIDs []string, alteracao AlteracaoHosts) ([]PrevisaoHost, error) {
+	previsoes, _, err := c.planejarAlteracao(ctx, hostIDs, alteracao)
+	return previsoes, err
+}
+
+// AplicarAlteracaoHosts aplica a alteração com host.massupdate, host.massadd,
+// host.massremove e, para as tags, host.update de cada host. A alteração é
+// planejada de novo e, se a assinatura da prévia não for a informada, nada é
+// enviado e o erro é ErrPrevisaoDesatualizada. As etapas são feitas em ordem e
+// a primeira que falhar interrompe as seguintes; as etapas retornadas são as
+// que chegaram a ser enviadas.
+func (c *ClienteAPI) AplicarAlteracaoHosts(hostIDs []string, alteracao AlteracaoHosts, assinatura string) ([]EtapaAlteracao, error) {
+	return c.AplicarAlteracaoHostsCtx(context.Background(), hostIDs, alteracao, assinatura)
+}
+
+// AplicarAlteracaoHostsCtx é a variante de AplicarAlteracaoHosts que aceita um contexto
+func (c *ClienteAPI) AplicarAlteracaoHostsCtx(ctx context.Context, hostIDs []string, alteracao AlteracaoHosts, assinatura string) ([]EtapaAlteracao, error) {
+	previsoes, etapas, err := c.planejarAlteracao(ctx, hostIDs, alteracao)
+	if err != nil {
+		return nil, err
+	}
+	if AssinaturaPrevisao(previsoes) != assinatura {
+		return nil, ErrPrevisaoDesatualizada
+	}
+
+	for i := range etapas {
+		etapa := &etapas[i]
+		etapa.Err = c.executarEtapa(ctx, *etapa)
+		if etapa.Err != nil {
+			return etapas[:i+1], fmt.Errorf("%s: %w", etapa.Descricao, etapa.Err)
+		}
+	}
+	return etapas, nil
+}
+
+// executarEtapa envia a chamada da etapa ou, com vários parâmetros, um lote
+func (c *ClienteAPI) executarEtapa(ctx context.Context, etapa EtapaAlteracao) error {
+	if len(etapa.params) == 1 {
+		_, err := ChamarCtx[json.RawMessage](ctx, c, etapa.Metodo, etapa.params[0])
+		return err
+	}
+
+	lote := c.NovoLote()
+	resultados := make([]*ResultadoLote[json.RawMessage], len(etapa.params))
+	for i, params := range etapa.params {
+		resultados[i] = AdicionarAoLote[json.RawMessage](lote, etapa.Metodo, params)
+	}
+	if err := lote.ExecutarCtx(ctx); err != nil {
+		return err
+	}
+
+	falhas := 0
+	var primeira error
+	for _, resultado := range resultados {
+		if _, err := resultado.Obter(); err != nil {
+			falhas++
+			if primeira == nil {
+				primeira = err
+			}
+		}
+	}
+	if falhas > 0 {
+		return fmt.Errorf("%d de %d hosts não foram alterados: %w", falhas, len(resultados), primeira)
+	}
+	return nil
+}
+
+// planejarAlteracao consulta o estado atual dos hosts e calcula as mudanças de
+// cada um e as chamadas necessárias. Hosts já como pedido ficam fora das chamadas.
+// As tags são regravadas por inteiro, por isso o estado é lido sem o cache.
+func (c *ClienteAPI) planejarAlteracao(ctx context.Context, hostIDs []string, alteracao AlteracaoHosts) ([]PrevisaoHost, []EtapaAlteracao, error) {
+	if len(hostIDs) == 0 {
+		return nil, nil, errors.New("nenhum host selecionado")
+	}
+	if err := alteracao.Validar(); err != nil {
+		return nil, nil, err
+	}
+
+	ctx = semCache(ctx)
+	var hosts []Host
+	err := c.PercorrerHostsCtx(ctx, ParamsHostGet{
+		Output:                []string{"hostid", "host", "name", "status"},
+		HostIDs:               hostIDs,
+		SelectHostGroups:      []string{"groupid", "name"},
+		SelectParentTemplates: []string{"templateid", "name"},
+		SelectTags:            []string{"tag", "value"},
+	}, 0, func(h Host) error {
+		hosts = append(hosts, h)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(hosts) == 0 {
+		return nil, nil, errors.New("nenhum dos hosts selecionados foi encontrado")
+	}
+
+	// Nomes para descrever grupos e templates ainda não ligados aos hosts
+	grupos, templates, err := c.ObterGruposETemplatesCtx(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	nomesGrupos := make(map[string]string, len(grupos))
+	for _, g := range grupos {
+		nomesGrupos[g.ID] = g.Nome
+	}
+	nomesTemplates := make(map[string]string, len(templates))
+	for _, t := range templates {
+		nomesTemplates[t.ID] = t.Nome
+	}
+	nome := func(nomes map[string]string, id string) string {
+		if n, ok := nomes[id]; ok {
+			return n
+		}
+		return id
+	}
+
+	var (
+		previsoes                               []PrevisaoHost
+		mudarStatus, vincular, desvincular      []string
+		adicionarGrupos, removerGrupos, comTags []string
+		paramsTags                              []interface{}
+		tagsPorHost                             = make(map[string]string)
+	)
+	for _, h := range hosts {
+		previsao := PrevisaoHost{ID: h.ID, Nome: h.NomeExibicao()}
+
+		if alteracao.Status != "" && h.Status != alteracao.Status {
+			previsao.Mudancas = append(previsao.Mudancas,
+				fmt.Sprintf("Status: %s → %s", StatusHost[h.Status], StatusHost[alteracao.Status]))
+			mudarStatus = append(mudarStatus, h.ID)
+		}
+
+		atuais := make(map[string]bool, len(h.Templates))
+		for _, t := range h.Templates {
+			atuais[t.ID] = true
+		}
+		mudou := false
+		for _, id := range alteracao.VincularTemplates {
+			if !atuais[id] {
+				previsao.Mudancas = append(previsao.Mudancas, "Vincular template "+nome(nomesTemplates, id))
+				mudou = true
+			}
+		}
+		if mudou {
+			vincular = append(vincular, h.ID)
+		}
+		mudou = false
+		for _, id := range alteracao.DesvincularTemplates {
+			if atuais[id] {
+				previsao.Mudancas = append(previsao.Mudancas, "Desvincular template "+nome(nomesTemplates, id))
+				mudou = true
+			}
+		}
+		if mudou {
+			desvincular = append(desvincular, h.ID)
+		}
+
+		noGrupo := make(map[string]bool, len(h.Grupos))
+		for _, g := range h.Grupos {
+			noGrupo[g.ID] = true
+		}
+		restantes := len(h.Grupos)
+		mudou = false
+		for _, id := range alteracao.AdicionarGrupos {
+			if !noGrupo[id] {
+				previsao.Mudancas = append(previsao.Mudancas, "Adicionar ao grupo "+nome(nomesGrupos, id))
+				restantes++
+				mudou = true
+			}
+		}
+		if mudou {
+			adicionarGrupos = append(adicionarGrupos, h.ID)
+		}
+		mudou = false
+		for _, id := range alteracao.RemoverGrupos {
+			if noGrupo[id] {
+				previsao.Mudancas = append(previsao.Mudancas, "Remover do grupo "+nome(nomesGrupos, id))
+				restantes--
+				mudou = true
+			}
+		}
+		if mudou {
+			removerGrupos = append(removerGrupos, h.ID)
+			if restantes == 0 {
+				previsao.Avisos = append(previsao.Avisos, "O host ficaria sem grupos e o Zabbix recusará a remoção")
+			}
+		}
+
+		if tags, mudou := alteracao.aplicarTags(h.Tags); mudou {
+			previsao.Mudancas = append(previsao.Mudancas,
+				fmt.Sprintf("Tags: %s → %s", descreverTags(h.Tags), descreverTags(tags)))
+			comTags = append(comTags, h.ID)
+			tagsPorHost[h.ID] = descreverTags(tags)
+			paramsTags = append(paramsTags, ParamsHostUpdate{HostID: h.ID, Tags: tags})
+		}
+
+		previsoes = append(previsoes, previsao)
+	}
+
+	var etapas []EtapaAlteracao
+	if len(mudarStatus) > 0 {
+		status, _ := strconv.Atoi(alteracao.Status)
+		etapas = append(etapas, EtapaAlteracao{
+			Metodo:    "host.massupdate",
+			Descricao: "Alterar status para " + StatusHost[alteracao.Status],
+			Hosts:     mudarStatus,
+			Detalhes:  map[string]interface{}{"status": alteracao.Status},
+			params:    []interface{}{ParamsHostMassa{Hosts: objetosID("hostid", mudarStatus), Status: &status}},
+		})
+	}
+	if len(adicionarGrupos) > 0 {
+		etapas = append(etapas, EtapaAlteracao{
+			Metodo:    "host.massadd",
+			Descricao: "Adicionar a grupos",
+			Hosts:     adicionarGrupos,
+			Detalhes:  map[string]interface{}{"grupos": alteracao.AdicionarGrupos},
+			params:    []interface{}{ParamsHostMassa{Hosts: objetosID("hostid", adicionarGrupos), Groups: objetosID("groupid", alteracao.AdicionarGrupos)}},
+		})
+	}
+	if len(removerGrupos) > 0 {
+		etapas = append(etapas, EtapaAlteracao{
+			Metodo:    "host.massremove",
+			Descricao: "Remover de grupos",
+			Hosts:     removerGrupos,
+			Detalhes:  map[string]interface{}{"grupos": alteracao.RemoverGrupos},
+			params:    []interface{}{ParamsHostMassRemove{HostIDs: removerGrupos, GroupIDs: alteracao.RemoverGrupos}},
+		})
+	}
+	if len(vincular) > 0 {
+		etapas = append(etapas, EtapaAlteracao{
+			Metodo:    "host.massadd",
+			Descricao: "Vincular templates",
+			Hosts:     vincular,
+			Detalhes:  map[string]interface{}{"templates": alteracao.VincularTemplates},
+			params:    []interface{}{ParamsHostMassa{Hosts: objetosID("hostid", vincular), Templates: objetosID("templateid", alteracao.VincularTemplates)}},
+		})
+	}
+	if len(desvincular) > 0 {
+		etapas = append(etapas, EtapaAlteracao{
+			Metodo:    "host.massremove",
+			Descricao: "Desvincular templates",
+			Hosts:     desvincular,
+			Detalhes:  map[string]interface{}{"templates": alteracao.DesvincularTemplates},
+			params:    []interface{}{ParamsHostMassRemove{HostIDs: desvincular, TemplateIDs: alteracao.DesvincularTemplates}},
+		})
+	}
+	if len(comTags) > 0 {
+		etapas = append(etapas, EtapaAlteracao{
+			Metodo:    "host.update",
+			Descricao: "Alterar tags",
+			Hosts:     comTags,
+			Detalhes:  map[string]interface{}{"tags": tagsPorHost},
+			params:    paramsTags,
+		})
+	}
+
+	return previsoes, etapas, nil
+}
+
+// aplicarTags retorna as tags do host depois da alteração, em ordem, e se
+// elas mudaram. host.update substitui todas as tags, por isso a lista completa
+// é calculada para cada host.
+func (a AlteracaoHosts) aplicarTags(atuais []Tag) ([]Tag, bool) {
+	if len(a.DefinirTags) == 0 && len(a.RemoverTags) == 0 {
+		return atuais, false
+	}
+
+	definidas := make(map[string]bool, len(a.DefinirTags))
+	for _, t := range a.DefinirTags {
+		definidas[t.Nome] = true
+	}
+	remover := func(t Tag) bool {
+		if definidas[t.Nome] {
+			return true
+		}
+		for _, r := range a.RemoverTags {
+			if r.Nome == t.Nome && (r.Valor == "" || r.Valor == t.Valor) {
+				return true
+			}
+		}
+		return false
+	}
+
+	tags := []Tag{}
+	for _, t := range atuais {
+		if !remover(t) {
+			tags = append(tags, t)
+		}
+	}
+	tags = append(tags, a.DefinirTags...)
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].String() < tags[j].String()
+	})
+
+	antes := append([]Tag(nil), atuais...)
+	sort.Slice(antes, func(i, j int) bool {
+		return antes[i].String() < antes[j].String()
+	})
+	return tags, descreverTags(antes) != descreverTags(tags)
+}
+
+// descreverTags lista as tags para exibição
+func descreverTags(tags []Tag) string {
+	if len(tags) == 0 {
+		return "(nenhuma)"
+	}
+	textos := make([]string, len(tags))
+	for i, t := range tags {
+		textos[i] = t.String()
+	}
+	return strings.Join(textos, ", ")
+}
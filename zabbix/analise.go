@@ -1,17 +1,373 @@
 package zabbix
 
-import "time"
-
-type AnaliseProblema struct {
-	HostNome        string
-	TotalProblemas  int
-	LimitesExcedidos int
-	PicoTrigger     struct {
-		Nome      string
-		DataPico  time.Time
-		Contagem  int
-		Gravidade string
-	}
-	DuracaoMedia string
-	TempoTotal   string
-}
\ No newline at end of file
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+// JanelaAnteriorAnalise é quanto antes do início do período são buscados os
+// problemas que ainda podem estar abertos dentro dele. Problemas abertos há
+// mais tempo não entram no tempo de indisponibilidade.
+const JanelaAnteriorAnalise = 30 * 24 * time.Hour
+
+// tamanhoLoteRecuperacoes é a quantidade de eventos de recuperação pedidos em cada event.get
+const tamanhoLoteRecuperacoes = 1000
+
+// OcorrenciaProblema é um problema com o início e, se já resolvido, a recuperação
+type OcorrenciaProblema struct {
+	EventoID   string
+	TriggerID  string
+	Nome       string
+	Severidade string
+	HostID     string
+	HostNome   string
+	Inicio     time.Time
+	Fim        time.Time // Zero enquanto o problema está aberto
+}
+
+// ResolvidoAte informa se o problema foi resolvido antes do instante
+func (o OcorrenciaProblema) ResolvidoAte(instante time.Time) bool {
+	return !o.Fim.IsZero() && o.Fim.Before(instante)
+}
+
+// intervaloNoPeriodo recorta a duração do problema em [inicio, fim). Problemas
+// abertos contam até fim ou até agora, o que vier antes.
+func (o OcorrenciaProblema) intervaloNoPeriodo(inicio, fim, agora time.Time) (time.Time, time.Time, bool) {
+	termino := o.Fim
+	if termino.IsZero() || termino.After(agora) {
+		termino = agora
+	}
+	if termino.After(fim) {
+		termino = fim
+	}
+	comeco := o.Inicio
+	if comeco.Before(inicio) {
+		comeco = inicio
+	}
+	return comeco, termino, termino.After(comeco)
+}
+
+// AnalisarProblemasMensais analisa problemas de um mês específico
+func (c *ClienteAPI) AnalisarProblemasMensais(ano int, mes int) ([]AnaliseMensal, error) {
+	return c.AnalisarProblemasMensaisCtx(context.Background(), ano, mes)
+}
+
+// AnalisarProblemasMensaisCtx é a variante de AnalisarProblemasMensais que aceita um contexto
+func (c *ClienteAPI) AnalisarProblemasMensaisCtx(ctx context.Context, ano int, mes int) ([]AnaliseMensal, error) {
+	inicio := time.Date(ano, time.Month(mes), 1, 0, 0, 0, 0, time.Local)
+	return c.AnalisarProblemasPeriodoCtx(ctx, inicio, inicio.AddDate(0, 1, 0))
+}
+
+// AnalisarProblemasPeriodo analisa os problemas de [inicio, fim), ordenando os
+// hosts pelo tempo indisponível. Os picos diários são contados nos dias do fuso de inicio.
+func (c *ClienteAPI) AnalisarProblemasPeriodo(inicio, fim time.Time) ([]AnaliseMensal, error) {
+	return c.AnalisarProblemasPeriodoCtx(context.Background(), inicio, fim)
+}
+
+// AnalisarProblemasPeriodoCtx é a variante de AnalisarProblemasPeriodo que aceita um contexto
+func (c *ClienteAPI) AnalisarProblemasPeriodoCtx(ctx context.Context, inicio, fim time.Time) ([]AnaliseMensal, error) {
+	if !fim.After(inicio) {
+		return nil, fmt.Errorf("período de análise vazio: %s a %s", inicio.Format("02/01/2006 15:04"), fim.Format("02/01/2006 15:04"))
+	}
+
+	log.Printf("Analisando problemas de %s até %s", inicio.Format("02/01/2006 15:04"), fim.Format("02/01/2006 15:04"))
+
+	ocorrencias, err := c.ObterOcorrenciasCtx(ctx, inicio.Add(-JanelaAnteriorAnalise), fim)
+	if err != nil {
+		return nil, err
+	}
+
+	return AnalisarOcorrencias(ocorrencias, inicio, fim, time.Now()), nil
+}
+
+// ObterOcorrencias busca os problemas de triggers iniciados em [inicio, fim) e
+// o horário da recuperação de cada um
+func (c *ClienteAPI) ObterOcorrencias(inicio, fim time.Time) ([]OcorrenciaProblema, error) {
+	return c.ObterOcorrenciasCtx(context.Background(), inicio, fim)
+}
+
+// ObterOcorrenciasCtx é a variante de ObterOcorrencias que aceita um contexto
+func (c *ClienteAPI) ObterOcorrenciasCtx(ctx context.Context, inicio, fim time.Time) ([]OcorrenciaProblema, error) {
+	return c.obterOcorrencias(ctx, inicio, fim, nil)
+}
+
+// obterOcorrencias busca as ocorrências de [inicio, fim), apenas dos eventos
+// com as tags informadas quando houver alguma
+func (c *ClienteAPI) obterOcorrencias(ctx context.Context, inicio, fim time.Time, tags []FiltroTag) ([]OcorrenciaProblema, error) {
+	// event.get informa apenas o ID da recuperação; o horário vem de uma segunda consulta
+	var ocorrencias []OcorrenciaProblema
+	indicePorRecuperacao := make(map[string]int)
+	err := PercorrerCtx(ctx, c, "event.get", ParamsEventGet{
+		Output:      []string{"eventid", "objectid", "clock", "r_eventid", "name", "severity"},
+		Value:       []string{"1"},
+		Tags:        tags,
+		TimeFrom:    inicio.Unix(),
+		TimeTill:    fim.Unix() - 1,
+		SelectHosts: []string{"hostid", "host", "name"},
+		SortField:   []string{"eventid"},
+	}, func(e Evento) error {
+		o := OcorrenciaProblema{
+			EventoID:   e.ID,
+			TriggerID:  e.ObjetoID,
+			Nome:       e.Nome,
+			Severidade: e.Severidade,
+			Inicio:     e.Clock.Time,
+		}
+		if len(e.Hosts) > 0 {
+			o.HostID = e.Hosts[0].ID
+			o.HostNome = e.Hosts[0].NomeExibicao()
+		}
+		if e.RecuperacaoID != "" && e.RecuperacaoID != "0" {
+			indicePorRecuperacao[e.RecuperacaoID] = len(ocorrencias)
+		}
+		ocorrencias = append(ocorrencias, o)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	recuperacoes := make([]string, 0, len(indicePorRecuperacao))
+	for id := range indicePorRecuperacao {
+		recuperacoes = append(recuperacoes, id)
+	}
+	sort.Strings(recuperacoes)
+
+	for inicioLote := 0; inicioLote < len(recuperacoes); inicioLote += tamanhoLoteRecuperacoes {
+		fimLote := inicioLote + tamanhoLoteRecuperacoes
+		if fimLote > len(recuperacoes) {
+			fimLote = len(recuperacoes)
+		}
+		err := PercorrerCtx(ctx, c, "event.get", ParamsEventGet{
+			Output:   []string{"eventid", "clock"},
+			EventIDs: recuperacoes[inicioLote:fimLote],
+		}, func(e Evento) error {
+			if i, ok := indicePorRecuperacao[e.ID]; ok {
+				ocorrencias[i].Fim = e.Clock.Time
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return ocorrencias, nil
+}
+
+// AnalisarOcorrencias calcula as métricas de cada host em [inicio, fim).
+// Contagens, picos e tempos até a recuperação consideram os problemas iniciados
+// no período; um problema resolvido só depois de fim conta como aberto. Os
+// tempos de indisponibilidade incluem problemas anteriores que ainda estavam
+// abertos e são recortados ao período.
+func AnalisarOcorrencias(ocorrencias []OcorrenciaProblema, inicio, fim, agora time.Time) []AnaliseMensal {
+	type acumulador struct {
+		duracoes   []time.Duration
+		intervalos [][2]time.Time
+	}
+
+	analises := make(map[string]*AnaliseMensal)
+	porHost := make(map[string]*acumulador)
+	porTrigger := make(map[string]map[string]*MetricasTrigger)
+	acumuladoresTrigger := make(map[string]map[string]*acumulador)
+	problemasPorDia := make(map[string]map[string]map[time.Time]int)
+
+	for _, o := range ocorrencias {
+		if o.HostID == "" {
+			continue
+		}
+
+		comeco, termino, dentro := o.intervaloNoPeriodo(inicio, fim, agora)
+		iniciouNoPeriodo := !o.Inicio.Before(inicio) && o.Inicio.Before(fim)
+		if !dentro && !iniciouNoPeriodo {
+			continue
+		}
+
+		analise, ok := analises[o.HostID]
+		if !ok {
+			analise = &AnaliseMensal{
+				HostID:              o.HostID,
+				HostNome:            o.HostNome,
+				ProblemasPorTrigger: make(map[string]int),
+			}
+			analises[o.HostID] = analise
+			porHost[o.HostID] = &acumulador{}
+			porTrigger[o.HostID] = make(map[string]*MetricasTrigger)
+			acumuladoresTrigger[o.HostID] = make(map[string]*acumulador)
+			problemasPorDia[o.HostID] = make(map[string]map[time.Time]int)
+		}
+
+		trigger, ok := porTrigger[o.HostID][o.TriggerID]
+		if !ok {
+			trigger = &MetricasTrigger{TriggerID: o.TriggerID, Nome: o.Nome, Severidade: o.Severidade}
+			porTrigger[o.HostID][o.TriggerID] = trigger
+			acumuladoresTrigger[o.HostID][o.TriggerID] = &acumulador{}
+		}
+		acumuladorHost := porHost[o.HostID]
+		acumuladorTrigger := acumuladoresTrigger[o.HostID][o.TriggerID]
+
+		if dentro {
+			intervalo := [2]time.Time{comeco, termino}
+			acumuladorHost.intervalos = append(acumuladorHost.intervalos, intervalo)
+			acumuladorTrigger.intervalos = append(acumuladorTrigger.intervalos, intervalo)
+			analise.TempoTotal += termino.Sub(comeco)
+		}
+
+		if !iniciouNoPeriodo {
+			continue
+		}
+
+		analise.TotalProblemas++
+		analise.LimitesExcedidos++
+		analise.ProblemasPorTrigger[o.TriggerID]++
+		trigger.TotalProblemas++
+
+		if o.ResolvidoAte(fim) {
+			duracao := o.Fim.Sub(o.Inicio)
+			acumuladorHost.duracoes = append(acumuladorHost.duracoes, duracao)
+			acumuladorTrigger.duracoes = append(acumuladorTrigger.duracoes, duracao)
+		} else {
+			analise.ProblemasAbertos++
+			trigger.ProblemasAbertos++
+		}
+
+		// Pico diário de cada trigger
+		dataInicio := o.Inicio.In(inicio.Location())
+		dia := time.Date(dataInicio.Year(), dataInicio.Month(), dataInicio.Day(), 0, 0, 0, 0, inicio.Location())
+		if _, existe := problemasPorDia[o.HostID][o.TriggerID]; !existe {
+			problemasPorDia[o.HostID][o.TriggerID] = make(map[time.Time]int)
+		}
+		problemasPorDia[o.HostID][o.TriggerID][dia]++
+		contagem := problemasPorDia[o.HostID][o.TriggerID][dia]
+		if contagem > analise.PicoTrigger.Contagem {
+			analise.PicoTrigger.Nome = o.Nome
+			analise.PicoTrigger.DataPico = dia
+			analise.PicoTrigger.Contagem = contagem
+			analise.PicoTrigger.Gravidade = o.Severidade
+		}
+	}
+
+	resultado := make([]AnaliseMensal, 0, len(analises))
+	for hostID, analise := range analises {
+		acumuladorHost := porHost[hostID]
+		analise.DuracaoMedia, analise.DuracaoMediana = mediaEMediana(acumuladorHost.duracoes)
+		analise.TempoIndisponivel = duracaoUniao(acumuladorHost.intervalos)
+
+		for triggerID, trigger := range porTrigger[hostID] {
+			acumuladorTrigger := acumuladoresTrigger[hostID][triggerID]
+			trigger.DuracaoMedia, trigger.DuracaoMediana = mediaEMediana(acumuladorTrigger.duracoes)
+			trigger.TempoIndisponivel = duracaoUniao(acumuladorTrigger.intervalos)
+			analise.Triggers = append(analise.Triggers, *trigger)
+		}
+		sort.Slice(analise.Triggers, func(i, j int) bool {
+			a, b := analise.Triggers[i], analise.Triggers[j]
+			if a.TempoIndisponivel != b.TempoIndisponivel {
+				return a.TempoIndisponivel > b.TempoIndisponivel
+			}
+			return a.Nome < b.Nome
+		})
+
+		resultado = append(resultado, *analise)
+	}
+
+	sort.Slice(resultado, func(i, j int) bool {
+		a, b := resultado[i], resultado[j]
+		if a.TempoIndisponivel != b.TempoIndisponivel {
+			return a.TempoIndisponivel > b.TempoIndisponivel
+		}
+		if a.TotalProblemas != b.TotalProblemas {
+			return a.TotalProblemas > b.TotalProblemas
+		}
+		return a.HostNome < b.HostNome
+	})
+
+	return resultado
+}
+
+// mediaEMediana calcula a média e a mediana das durações; zero sem durações
+func mediaEMediana(duracoes []time.Duration) (time.Duration, time.Duration) {
+	if len(duracoes) == 0 {
+		return 0, 0
+	}
+
+	ordenadas := append([]time.Duration(nil), duracoes...)
+	sort.Slice(ordenadas, func(i, j int) bool { return ordenadas[i] < ordenadas[j] })
+
+	var soma time.Duration
+	for _, d := range ordenadas {
+		soma += d
+	}
+	media := soma / time.Duration(len(ordenadas))
+
+	meio := len(ordenadas) / 2
+	mediana := ordenadas[meio]
+	if len(ordenadas)%2 == 0 {
+		mediana = (ordenadas[meio-1] + ordenadas[meio]) / 2
+	}
+	return media, mediana
+}
+
+// duracaoUniao soma o tempo coberto por ao menos um dos intervalos, sem
+// contar duas vezes os trechos sobrepostos
+func duracaoUniao(intervalos [][2]time.Time) time.Duration {
+	if len(intervalos) == 0 {
+		return 0
+	}
+
+	ordenados := append([][2]time.Time(nil), intervalos...)
+	sort.Slice(ordenados, func(i, j int) bool { return ordenados[i][0].Before(ordenados[j][0]) })
+
+	var total time.Duration
+	atual := ordenados[0]
+	for _, intervalo := range ordenados[1:] {
+		if intervalo[0].After(atual[1]) {
+			total += atual[1].Sub(atual[0])
+			atual = intervalo
+			continue
+		}
+		if intervalo[1].After(atual[1]) {
+			atual[1] = intervalo[1]
+		}
+	}
+	return total + atual[1].Sub(atual[0])
+}
+
+// FormatarDuracao escreve uma duração com até três unidades a partir da maior
+// diferente de zero, omitindo as zeradas (ex.: "2d 3h 5m", "30d", "4m 10s")
+func FormatarDuracao(d time.Duration) string {
+	d = d.Round(time.Second)
+	if d <= 0 {
+		return "0s"
+	}
+
+	unidades := []struct {
+		tamanho time.Duration
+		sufixo  string
+	}{
+		{24 * time.Hour, "d"},
+		{time.Hour, "h"},
+		{time.Minute, "m"},
+		{time.Second, "s"},
+	}
+
+	var partes []string
+	usadas := 0
+	for _, u := range unidades {
+		quantidade := d / u.tamanho
+		d -= quantidade * u.tamanho
+		if usadas > 0 || quantidade > 0 {
+			usadas++
+		}
+		if quantidade > 0 {
+			partes = append(partes, fmt.Sprintf("%d%s", quantidade, u.sufixo))
+		}
+		if usadas == 3 {
+			break
+		}
+	}
+	return strings.Join(partes, " ")
+}
@@ -0,0 +1,200 @@
+package zabbix
+
+import (
+	"testing"
+	"time"
+)
+
+// instanteAnalise é a origem dos horários dos testes de análise
+var instanteAnalise = time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+// horas retorna o instante h horas depois de instanteAnalise
+func horas(h float64) time.Time {
+	return instanteAnalise.Add(time.Duration(h * float64(time.Hour)))
+}
+
+func TestDuracaoUniao(t *testing.T) {
+	casos := []struct {
+		nome       string
+		intervalos [][2]time.Time
+		esperado   time.Duration
+	}{
+		{"sem intervalos", nil, 0},
+		{"um intervalo", [][2]time.Time{{horas(1), horas(3)}}, 2 * time.Hour},
+		{"separados", [][2]time.Time{{horas(0), horas(1)}, {horas(2), horas(4)}}, 3 * time.Hour},
+		{"sobrepostos", [][2]time.Time{{horas(0), horas(2)}, {horas(1), horas(3)}}, 3 * time.Hour},
+		{"contido no outro", [][2]time.Time{{horas(0), horas(5)}, {horas(1), horas(2)}}, 5 * time.Hour},
+		{"encostados", [][2]time.Time{{horas(0), horas(1)}, {horas(1), horas(2)}}, 2 * time.Hour},
+		{"fora de ordem", [][2]time.Time{{horas(4), horas(6)}, {horas(0), horas(1)}, {horas(5), horas(7)}}, 4 * time.Hour},
+	}
+	for _, caso := range casos {
+		t.Run(caso.nome, func(t *testing.T) {
+			if obtido := duracaoUniao(caso.intervalos); obtido != caso.esperado {
+				t.Errorf("duracaoUniao = %s, esperava %s", obtido, caso.esperado)
+			}
+		})
+	}
+}
+
+func TestMediaEMediana(t *testing.T) {
+	casos := []struct {
+		nome     string
+		duracoes []time.Duration
+		media    time.Duration
+		mediana  time.Duration
+	}{
+		{"sem durações", nil, 0, 0},
+		{"uma duração", []time.Duration{time.Hour}, time.Hour, time.Hour},
+		{"quantidade ímpar", []time.Duration{6 * time.Hour, time.Hour, 2 * time.Hour}, 3 * time.Hour, 2 * time.Hour},
+		{"quantidade par", []time.Duration{4 * time.Hour, time.Hour, 2 * time.Hour, 9 * time.Hour}, 4 * time.Hour, 3 * time.Hour},
+	}
+	for _, caso := range casos {
+		t.Run(caso.nome, func(t *testing.T) {
+			media, mediana := mediaEMediana(caso.duracoes)
+			if media != caso.media || mediana != caso.mediana {
+				t.Errorf("mediaEMediana = %s, %s; esperava %s, %s", media, mediana, caso.media, caso.mediana)
+			}
+		})
+	}
+}
+
+func TestAnalisarOcorrencias(t *testing.T) {
+	// Período de 24 horas; agora é depois do fim, salvo quando o caso informa
+	inicio, fim := horas(0), horas(24)
+	aberto := func(trigger string, de float64) OcorrenciaProblema {
+		return OcorrenciaProblema{TriggerID: trigger, Nome: "Trigger " + trigger, HostID: "10001", HostNome: "srv-01", Inicio: horas(de)}
+	}
+	problema := func(trigger string, de, ate float64) OcorrenciaProblema {
+		o := aberto(trigger, de)
+		o.Fim = horas(ate)
+		return o
+	}
+
+	casos := []struct {
+		nome        string
+		ocorrencias []OcorrenciaProblema
+		agora       time.Time
+
+		total, abertos int
+		media, mediana time.Duration
+		tempoTotal     time.Duration
+		indisponivel   time.Duration
+		semAnaliseHost bool
+	}{
+		{
+			nome:         "resolvido dentro do período",
+			ocorrencias:  []OcorrenciaProblema{problema("1", 2, 5)},
+			total:        1,
+			media:        3 * time.Hour,
+			mediana:      3 * time.Hour,
+			tempoTotal:   3 * time.Hour,
+			indisponivel: 3 * time.Hour,
+		},
+		{
+			// Iniciado antes do período: só entra no tempo indisponível
+			nome:         "recortado no início",
+			ocorrencias:  []OcorrenciaProblema{problema("1", -3, 2)},
+			tempoTotal:   2 * time.Hour,
+			indisponivel: 2 * time.Hour,
+		},
+		{
+			nome:         "resolvido depois do fim conta como aberto",
+			ocorrencias:  []OcorrenciaProblema{problema("1", 22, 30)},
+			total:        1,
+			abertos:      1,
+			tempoTotal:   2 * time.Hour,
+			indisponivel: 2 * time.Hour,
+		},
+		{
+			nome:         "aberto no fim do período",
+			ocorrencias:  []OcorrenciaProblema{aberto("1", 20)},
+			total:        1,
+			abertos:      1,
+			tempoTotal:   4 * time.Hour,
+			indisponivel: 4 * time.Hour,
+		},
+		{
+			nome:         "aberto conta até agora dentro do período",
+			ocorrencias:  []OcorrenciaProblema{aberto("1", 10)},
+			agora:        horas(12),
+			total:        1,
+			abertos:      1,
+			tempoTotal:   2 * time.Hour,
+			indisponivel: 2 * time.Hour,
+		},
+		{
+			// Problemas simultâneos somam no tempo total, mas não no indisponível
+			nome:         "união de sobrepostos",
+			ocorrencias:  []OcorrenciaProblema{problema("1", 10, 12), problema("2", 11, 13)},
+			total:        2,
+			media:        2 * time.Hour,
+			mediana:      2 * time.Hour,
+			tempoTotal:   4 * time.Hour,
+			indisponivel: 3 * time.Hour,
+		},
+		{
+			nome:         "mediana de durações diferentes",
+			ocorrencias:  []OcorrenciaProblema{problema("1", 0, 1), problema("1", 2, 4), problema("1", 10, 16)},
+			total:        3,
+			media:        3 * time.Hour,
+			mediana:      2 * time.Hour,
+			tempoTotal:   9 * time.Hour,
+			indisponivel: 9 * time.Hour,
+		},
+		{
+			nome:           "resolvido antes do período é ignorado",
+			ocorrencias:    []OcorrenciaProblema{problema("1", -5, -1)},
+			semAnaliseHost: true,
+		},
+	}
+
+	for _, caso := range casos {
+		t.Run(caso.nome, func(t *testing.T) {
+			agora := caso.agora
+			if agora.IsZero() {
+				agora = horas(48)
+			}
+			analises := AnalisarOcorrencias(caso.ocorrencias, inicio, fim, agora)
+			if caso.semAnaliseHost {
+				if len(analises) != 0 {
+					t.Fatalf("esperava nenhuma análise, obteve %d", len(analises))
+				}
+				return
+			}
+			if len(analises) != 1 {
+				t.Fatalf("esperava a análise de um host, obteve %d", len(analises))
+			}
+
+			a := analises[0]
+			if a.TotalProblemas != caso.total || a.ProblemasAbertos != caso.abertos {
+				t.Errorf("problemas = %d (%d abertos), esperava %d (%d abertos)", a.TotalProblemas, a.ProblemasAbertos, caso.total, caso.abertos)
+			}
+			if a.DuracaoMedia != caso.media || a.DuracaoMediana != caso.mediana {
+				t.Errorf("média e mediana = %s, %s; esperava %s, %s", a.DuracaoMedia, a.DuracaoMediana, caso.media, caso.mediana)
+			}
+			if a.TempoTotal != caso.tempoTotal {
+				t.Errorf("tempo total = %s, esperava %s", a.TempoTotal, caso.tempoTotal)
+			}
+			if a.TempoIndisponivel != caso.indisponivel {
+				t.Errorf("tempo indisponível = %s, esperava %s", a.TempoIndisponivel, caso.indisponivel)
+			}
+		})
+	}
+}
+
+// TestAnalisarOcorrenciasOrdem confere que os hosts vêm do mais indisponível
+// para o menos e que ocorrências sem host são ignoradas
+func TestAnalisarOcorrenciasOrdem(t *testing.T) {
+	ocorrencias := []OcorrenciaProblema{
+		{TriggerID: "1", HostID: "10001", HostNome: "srv-01", Inicio: horas(1), Fim: horas(2)},
+		{TriggerID: "2", HostID: "10002", HostNome: "srv-02", Inicio: horas(1), Fim: horas(5)},
+		{TriggerID: "3", Inicio: horas(1), Fim: horas(9)},
+	}
+	analises := AnalisarOcorrencias(ocorrencias, horas(0), horas(24), horas(48))
+	if len(analises) != 2 {
+		t.Fatalf("esperava dois hosts, obteve %d", len(analises))
+	}
+	if analises[0].HostID != "10002" || analises[1].HostID != "10001" {
+		t.Errorf("ordem = %s, %s; esperava 10002, 10001", analises[0].HostID, analises[1].HostID)
+	}
+}
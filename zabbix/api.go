@@ -1,12 +1,11 @@
 package zabbix
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
-	"fmt"
-	"log"
 	"net/http"
-	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -14,25 +13,79 @@ import (
 type ConfigAPI struct {
 	URL         string        // URL do servidor (Ex: http://zabbix.example.com)
 	Token       string        // Token de autenticação da API
-	TempoLimite time.Duration // Tempo limite para requisições (em segundos)
+	Usuario     string        // Usuário para user.login (alternativa ao token)
+	Senha       string        // Senha para user.login
+	TempoLimite time.Duration // Tempo limite de cada requisição sem prazo definido no contexto
+
+	// TLS: por padrão são usadas as CAs do sistema e a versão mínima do Go
+	ArquivoCA             string // Arquivo PEM com CAs adicionais para verificar o servidor
+	CertificadoCliente    string // Certificado PEM apresentado ao servidor (TLS mútuo)
+	ChaveCliente          string // Chave privada PEM do certificado do cliente
+	VersaoTLSMinima       string // "1.0", "1.1", "1.2" ou "1.3"
+	IgnorarVerificacaoTLS bool   // Aceita qualquer certificado do servidor (inseguro)
+
+	// Proxy e cabeçalhos: sem Proxy, as variáveis HTTP_PROXY/HTTPS_PROXY do ambiente são usadas
+	Proxy            string            // URL do proxy (http, https ou socks5), com credenciais opcionais
+	SemProxy         []string          // Hosts, domínios (.exemplo.com) e redes (10.0.0.0/8) acessados diretamente
+	CabecalhosExtras map[string]string // Cabeçalhos enviados em todas as requisições (ex: gateway de SSO)
+
+	// Cache de resultados de leitura, compartilhável entre clientes de perfis diferentes
+	Cache    *Cache                   // nil desativa o cache
+	Perfil   string                   // Identifica o servidor e a credencial nas chaves do cache
+	TTLCache map[string]time.Duration // Tempo de cache por método; ausentes usam TTLCachePadrao, zero desativa
+
+	// Novas tentativas para métodos de leitura (*.get, apiinfo.version)
+	Tentativas    int           // Total de tentativas por chamada (padrão 3; 1 desativa)
+	EsperaInicial time.Duration // Espera antes da segunda tentativa, dobrada a cada nova tentativa
+	EsperaMaxima  time.Duration // Limite para a espera entre tentativas
+
+	// Disjuntor: após LimiteFalhas falhas de transporte seguidas, as chamadas
+	// falham imediatamente durante TempoDisjuntor
+	LimiteFalhas   int           // Padrão 5; negativo desativa o disjuntor
+	TempoDisjuntor time.Duration // Tempo que o disjuntor fica aberto
 }
 
 // ClienteAPI encapsula funcionalidades para interagir com a API do Zabbix
 type ClienteAPI struct {
-	config ConfigAPI
-	client *http.Client
+	config    ConfigAPI
+	client    *http.Client
+	disjuntor *Disjuntor
+
+	// Erro na configuração de TLS ou proxy, devolvido em todas as requisições
+	erroTransporte error
+
+	// Versão do servidor, negociada uma única vez via apiinfo.version
+	muVersao sync.Mutex
+	versao   *Versao
+
+	// Sessão obtida via user.login quando o perfil usa usuário e senha
+	muSessao sync.Mutex
+	sessao   string
+
+	// Usuário dono do token ou da sessão, consultado uma única vez para a auditoria
+	muUsuario sync.Mutex
+	usuario   string
+
+	// Último ID usado nos pedidos JSON-RPC
+	proximoID atomic.Uint64
+
+	// Chamadas idênticas em andamento, compartilhadas entre requisições simultâneas
+	chamadas grupoChamadas
 }
 
 // RespostaAPI encapsula a resposta da API do Zabbix
 type RespostaAPI struct {
 	Jsonrpc string          `json:"jsonrpc"`
 	Result  json.RawMessage `json:"result"`
-	Error   *struct {
-		Code    int    `json:"code"`
-		Message string `json:"message"`
-		Data    string `json:"data"`
-	} `json:"error"`
-	ID int `json:"id"`
+	Error   *ErroRPC        `json:"error"`
+	ID      uint64          `json:"id"`
+}
+
+// ErroRPC é o objeto error de uma resposta JSON-RPC
+type ErroRPC struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    string `json:"data"`
 }
 
 // NovoClienteAPI cria uma nova instância de ClienteAPI
@@ -42,228 +95,167 @@ func NovoClienteAPI(config ConfigAPI) *ClienteAPI {
 		config.TempoLimite = 30 * time.Second
 	}
 
-	// Criar cliente HTTP com timeout configurado
-	client := &http.Client{
-		Timeout: config.TempoLimite,
+	// Definir padrões para novas tentativas e disjuntor
+	if config.Tentativas <= 0 {
+		config.Tentativas = TentativasPadrao
 	}
-
-	return &ClienteAPI{
-		config: config,
-		client: client,
+	if config.EsperaInicial <= 0 {
+		config.EsperaInicial = EsperaInicialPadrao
 	}
-}
-
-// TestarConexao verifica se a conexão com a API do Zabbix está funcionando
-func (c *ClienteAPI) TestarConexao() error {
-	// Requisição para obter a versão da API (método simples para testar conexão)
-	pedido := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"method":  "apiinfo.version",
-		"params":  map[string]interface{}{},
-		"id":      1,
+	if config.EsperaMaxima <= 0 {
+		config.EsperaMaxima = EsperaMaximaPadrao
 	}
-
-	var resposta RespostaAPI
-	err := c.realizarRequisicao(pedido, &resposta)
-	if err != nil {
-		return err
+	if config.LimiteFalhas == 0 {
+		config.LimiteFalhas = LimiteFalhasPadrao
 	}
-
-	// Verificar se houve erro na resposta
-	if resposta.Error != nil {
-		return fmt.Errorf("erro na API: %s - %s", resposta.Error.Message, resposta.Error.Data)
+	if config.TempoDisjuntor <= 0 {
+		config.TempoDisjuntor = TempoDisjuntorPadrao
 	}
 
-	return nil
-}
+	// O tempo limite é aplicado por requisição através do contexto, permitindo
+	// que cada chamada defina o próprio prazo
+	client := &http.Client{}
 
-// ObterHosts retorna a lista de hosts do Zabbix com seus itens e triggers
-// ObterHistoricoEventos obtém o histórico detalhado de eventos
-func (c *ClienteAPI) ObterHistoricoEventos(hostID string, inicio, fim time.Time) ([]Evento, error) {
-	pedido := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"method":  "event.get",
-		"params": map[string]interface{}{
-			"output":              "extend",
-			"hostids":             hostID,
-			"time_from":           inicio.Unix(),
-			"time_till":           fim.Unix(),
-			"sortfield":           "clock",
-			"sortorder":           "DESC",
-			"selectRelatedObject": "extend",
-		},
-		"auth": c.config.Token,
-		"id":   1,
+	transporte, erroTransporte := novoTransporte(config)
+	if erroTransporte == nil {
+		client.Transport = transporte
 	}
 
-	var resposta RespostaAPI
-	err := c.realizarRequisicao(pedido, &resposta)
-	if err != nil {
-		return nil, err
+	return &ClienteAPI{
+		config:         config,
+		client:         client,
+		disjuntor:      NovoDisjuntor(config.LimiteFalhas, config.TempoDisjuntor),
+		erroTransporte: erroTransporte,
 	}
+}
 
-	var eventos []Evento
-	err = json.Unmarshal(resposta.Result, &eventos)
-	return eventos, err
+// EstadoDisjuntor retorna o estado do disjuntor deste cliente
+func (c *ClienteAPI) EstadoDisjuntor() EstadoDisjuntor {
+	return c.disjuntor.Estado()
 }
 
-// ObterProblemasPeriodo obtém problemas de um período específico
-func (c *ClienteAPI) ObterProblemasPeriodo(inicio, fim time.Time) ([]Problema, error) {
-	pedido := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"method":  "problem.get",
-		"params": map[string]interface{}{
-			"output":      "extend",
-			"time_from":   inicio.Unix(),
-			"time_till":   fim.Unix(),
-			"sortfield":   []string{"eventid"},
-			"selectHosts": []string{"hostid", "host"},
-		},
-		"auth": c.config.Token,
-		"id":   1,
-	}
+// TestarConexao verifica se a conexão com a API do Zabbix está funcionando
+func (c *ClienteAPI) TestarConexao() error {
+	return c.TestarConexaoCtx(context.Background())
+}
 
-	var resposta RespostaAPI
-	err := c.realizarRequisicao(pedido, &resposta)
-	if err != nil {
-		return nil, err
-	}
+// TestarConexaoCtx é a variante de TestarConexao que aceita um contexto
+func (c *ClienteAPI) TestarConexaoCtx(ctx context.Context) error {
+	// Consultar a versão da API (método simples para testar conexão)
+	c.muVersao.Lock()
+	c.versao = nil
+	c.muVersao.Unlock()
 
-	var problemas []Problema
-	err = json.Unmarshal(resposta.Result, &problemas)
-	return problemas, err
+	_, err := c.ObterVersaoCtx(ctx)
+	return err
 }
 
-// AnalisarProblemasMensais analisa problemas de um mês específico
-func (c *ClienteAPI) AnalisarProblemasMensais(ano int, mes int) ([]AnaliseMensal, error) {
-	inicio := time.Date(ano, time.Month(mes), 1, 0, 0, 0, 0, time.UTC)
-	fim := inicio.AddDate(0, 1, 0).Add(-time.Second)
+// ObterVersao retorna a versão do servidor, consultando apiinfo.version apenas
+// na primeira chamada. O resultado define como o token é enviado.
+func (c *ClienteAPI) ObterVersao() (Versao, error) {
+	return c.ObterVersaoCtx(context.Background())
+}
 
-	log.Printf("Analisando problemas de %s até %s", inicio.Format("02/01/2006"), fim.Format("02/01/2006"))
+// ObterVersaoCtx é a variante de ObterVersao que aceita um contexto
+func (c *ClienteAPI) ObterVersaoCtx(ctx context.Context) (Versao, error) {
+	c.muVersao.Lock()
+	defer c.muVersao.Unlock()
 
-	problemas, err := c.ObterProblemasPeriodo(inicio, fim)
-	if err != nil {
-		return nil, err
+	if c.versao != nil {
+		return *c.versao, nil
 	}
 
-	analises := make(map[string]*AnaliseMensal)
-	problemasporDia := make(map[string]map[string]map[time.Time]int)
-
-	for _, p := range problemas {
-		// Inicializar estruturas
-		if _, existe := analises[p.HostID]; !existe {
-			analises[p.HostID] = &AnaliseMensal{
-				HostID:              p.HostID,
-				HostNome:            p.Hosts[0].Nome,
-				ProblemasPorTrigger: make(map[string]int),
-			}
-			problemasporDia[p.HostID] = make(map[string]map[time.Time]int)
-		}
-
-		// Análise por dia
-		if _, existe := problemasporDia[p.HostID][p.TriggerID]; !existe {
-			problemasporDia[p.HostID][p.TriggerID] = make(map[time.Time]int)
-		}
-
-		dia := time.Date(p.DataInicio.Year(), p.DataInicio.Month(), p.DataInicio.Day(), 0, 0, 0, 0, time.UTC)
-		problemasporDia[p.HostID][p.TriggerID][dia]++
-
-		analise := analises[p.HostID]
-		analise.TotalProblemas++
-		analise.ProblemasPorTrigger[p.TriggerID]++
-
-		// Verificar se é um novo pico
-		contagem := problemasporDia[p.HostID][p.TriggerID][dia]
-		if contagem > analise.PicoTrigger.Contagem {
-			analise.PicoTrigger.Nome = p.Nome
-			analise.PicoTrigger.DataPico = dia
-			analise.PicoTrigger.Contagem = contagem
-			analise.PicoTrigger.Gravidade = p.Severidade
-		}
-
-		if p.Valor == "1" { // Problema ativo
-			analise.LimitesExcedidos++
-		}
+	// apiinfo.version não aceita autenticação, nem no corpo nem no cabeçalho
+	respostas, err := c.enviarPedidos(ctx, []*pedidoRPC{c.novoPedido("apiinfo.version", nil)}, "")
+	if err != nil {
+		return Versao{}, err
 	}
 
-	resultado := make([]AnaliseMensal, 0, len(analises))
-	for _, a := range analises {
-		resultado = append(resultado, *a)
+	var texto string
+	if err := decodificarResultado("apiinfo.version", &respostas[0], &texto); err != nil {
+		return Versao{}, err
 	}
 
-	return resultado, nil
-}
-
-func (c *ClienteAPI) ObterHosts() ([]Host, error) {
-	// Preparar requisição para obter hosts com itens e triggers
-	pedido := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"method":  "host.get",
-		"params": map[string]interface{}{
-			"output":         []string{"hostid", "host", "status"},
-			"selectItems":    []string{"itemid", "name"},
-			"selectTriggers": []string{"triggerid", "description"},
-		},
-		"auth": c.config.Token,
-		"id":   1,
-	}
-
-	var resposta RespostaAPI
-	err := c.realizarRequisicao(pedido, &resposta)
+	versao, err := ParseVersao(texto)
 	if err != nil {
-		return nil, err
+		return Versao{}, err
 	}
 
-	// Verificar se houve erro na resposta
-	if resposta.Error != nil {
-		return nil, fmt.Errorf("erro na API: %s - %s", resposta.Error.Message, resposta.Error.Data)
-	}
+	c.versao = &versao
+	return versao, nil
+}
 
-	// Decodificar resultado para slice de hosts
-	var hosts []Host
-	err = json.Unmarshal(resposta.Result, &hosts)
-	if err != nil {
-		return nil, fmt.Errorf("erro ao decodificar resposta: %w", err)
-	}
+// ObterHistoricoEventos obtém o histórico detalhado de eventos
+func (c *ClienteAPI) ObterHistoricoEventos(hostID string, inicio, fim time.Time) ([]Evento, error) {
+	return c.ObterHistoricoEventosCtx(context.Background(), hostID, inicio, fim)
+}
 
-	return hosts, nil
+// ObterHistoricoEventosCtx é a variante de ObterHistoricoEventos que aceita um contexto
+func (c *ClienteAPI) ObterHistoricoEventosCtx(ctx context.Context, hostID string, inicio, fim time.Time) ([]Evento, error) {
+	return ChamarCtx[[]Evento](ctx, c, "event.get", paramsHistoricoEventos(hostID, inicio, fim))
 }
 
-// realizarRequisicao envia uma requisição para a API do Zabbix
-func (c *ClienteAPI) realizarRequisicao(pedido map[string]interface{}, resposta *RespostaAPI) error {
-	// Converter pedido para JSON
-	pedidoBytes, err := json.Marshal(pedido)
-	if err != nil {
-		return fmt.Errorf("erro ao criar pedido JSON: %w", err)
+// paramsHistoricoEventos monta a consulta dos eventos do host no período, do mais recente ao mais antigo
+func paramsHistoricoEventos(hostID string, inicio, fim time.Time) ParamsEventGet {
+	return ParamsEventGet{
+		Output:              SaidaCompleta,
+		HostIDs:             []string{hostID},
+		TimeFrom:            inicio.Unix(),
+		TimeTill:            fim.Unix(),
+		SortField:           []string{"clock"},
+		SortOrder:           "DESC",
+		SelectRelatedObject: SaidaCompleta,
 	}
+}
 
-	// Criar requisição HTTP
-	apiURL := strings.TrimRight(c.config.URL, "/") + "/api_jsonrpc.php"
-	req, err := http.NewRequest("POST", apiURL, bytes.NewBuffer(pedidoBytes))
-	if err != nil {
-		return fmt.Errorf("erro ao criar requisição: %w", err)
-	}
+// ObterProblemasPeriodo obtém problemas de um período específico
+func (c *ClienteAPI) ObterProblemasPeriodo(inicio, fim time.Time) ([]Problema, error) {
+	return c.ObterProblemasPeriodoCtx(context.Background(), inicio, fim)
+}
 
-	// Definir cabeçalhos
-	req.Header.Set("Content-Type", "application/json-rpc")
+// ObterProblemasPeriodoCtx é a variante de ObterProblemasPeriodo que aceita um contexto
+func (c *ClienteAPI) ObterProblemasPeriodoCtx(ctx context.Context, inicio, fim time.Time) ([]Problema, error) {
+	return ChamarCtx[[]Problema](ctx, c, "problem.get", paramsProblemasPeriodo(inicio, fim))
+}
 
-	// Enviar requisição
-	resp, err := c.client.Do(req)
-	if err != nil {
-		return fmt.Errorf("erro na requisição: %w", err)
+// paramsProblemasPeriodo monta a consulta de problemas iniciados em [inicio, fim),
+// incluindo os já resolvidos. time_till do Zabbix é inclusivo, daí o segundo a menos.
+func paramsProblemasPeriodo(inicio, fim time.Time) ParamsProblemGet {
+	return ParamsProblemGet{
+		Output:    SaidaCompleta,
+		TimeFrom:  inicio.Unix(),
+		TimeTill:  fim.Unix() - 1,
+		Recent:    true,
+		SortField: []string{"eventid"},
 	}
-	defer resp.Body.Close()
+}
 
-	// Verificar código de status
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("erro na API, código de status: %d", resp.StatusCode)
-	}
+// ObterHosts retorna a lista de hosts do Zabbix com seus itens e triggers
+func (c *ClienteAPI) ObterHosts() ([]Host, error) {
+	return c.ObterHostsCtx(context.Background())
+}
 
-	// Decodificar resposta
-	err = json.NewDecoder(resp.Body).Decode(resposta)
-	if err != nil {
-		return fmt.Errorf("erro ao decodificar resposta: %w", err)
-	}
+// ObterHostsCtx é a variante de ObterHosts que aceita um contexto. Os hosts são
+// buscados em páginas; para não manter todos na memória use PercorrerHostsCtx.
+func (c *ClienteAPI) ObterHostsCtx(ctx context.Context) ([]Host, error) {
+	var hosts []Host
+	err := c.PercorrerHostsCtx(ctx, ParamsHostRelatorio(), 0, func(h Host) error {
+		hosts = append(hosts, h)
+		return nil
+	})
+	return hosts, err
+}
 
-	return nil
+// ParamsHostRelatorio retorna a consulta de host.get com as propriedades de
+// hosts, itens, triggers, interfaces, grupos, templates e tags usadas no relatório CSV
+func ParamsHostRelatorio() ParamsHostGet {
+	return ParamsHostGet{
+		Output:                []string{"hostid", "host", "name", "status"},
+		SelectItems:           []string{"itemid", "name", "status", "state", "lastvalue", "lastclock"},
+		SelectTriggers:        []string{"triggerid", "description", "status", "value", "lastchange"},
+		SelectInterfaces:      []string{"interfaceid", "type", "main", "ip", "dns", "port"},
+		SelectHostGroups:      []string{"groupid", "name"},
+		SelectParentTemplates: []string{"templateid", "name"},
+		SelectTags:            []string{"tag", "value"},
+	}
 }
@@ -1,7 +1,7 @@
-
 package zabbix
 
 import (
+	"strings"
 	"sync"
 	"time"
 )
@@ -10,6 +10,9 @@ type Cache struct {
 	dados map[string]interface{}
 	exp   map[string]time.Time
 	mu    sync.RWMutex
+
+	// Momento da última remoção das entradas expiradas
+	ultimaLimpeza time.Time
 }
 
 func NovoCache() *Cache {
@@ -24,19 +27,55 @@ func (c *Cache) Set(chave string, valor interface{}, duracao time.Duration) {
 	defer c.mu.Unlock()
 	c.dados[chave] = valor
 	c.exp[chave] = time.Now().Add(duracao)
+
+	// Entradas que nunca voltam a ser lidas também precisam sair do mapa
+	if time.Since(c.ultimaLimpeza) > time.Minute {
+		c.removerExpiradas()
+	}
 }
 
 func (c *Cache) Get(chave string) (interface{}, bool) {
 	c.mu.RLock()
-	defer c.mu.RUnlock()
-	
-	if exp, ok := c.exp[chave]; ok {
-		if time.Now().After(exp) {
+	exp, ok := c.exp[chave]
+	valor := c.dados[chave]
+	c.mu.RUnlock()
+
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(exp) {
+		// A remoção exige a trava de escrita; a entrada pode ter sido renovada nesse meio tempo
+		c.mu.Lock()
+		if exp, ok := c.exp[chave]; ok && time.Now().After(exp) {
+			delete(c.dados, chave)
+			delete(c.exp, chave)
+		}
+		c.mu.Unlock()
+		return nil, false
+	}
+	return valor, true
+}
+
+// RemoverPrefixo apaga todas as entradas cuja chave começa com o prefixo
+func (c *Cache) RemoverPrefixo(prefixo string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for chave := range c.dados {
+		if strings.HasPrefix(chave, prefixo) {
+			delete(c.dados, chave)
+			delete(c.exp, chave)
+		}
+	}
+}
+
+// removerExpiradas apaga as entradas vencidas. Deve ser chamada com mu travado.
+func (c *Cache) removerExpiradas() {
+	agora := time.Now()
+	for chave, exp := range c.exp {
+		if agora.After(exp) {
 			delete(c.dados, chave)
 			delete(c.exp, chave)
-			return nil, false
 		}
-		return c.dados[chave], true
 	}
-	return nil, false
+	c.ultimaLimpeza = agora
 }
@@ -0,0 +1,189 @@
+package zabbix
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+)
+
+// TTLCachePadrao define por quanto tempo o resultado de cada método de leitura
+// fica em cache quando ConfigAPI.TTLCache não informa o método
+var TTLCachePadrao = map[string]time.Duration{
+	"host.get":    time.Minute,
+	"trigger.get": time.Minute,
+	"problem.get": 30 * time.Second,
+	"event.get":   30 * time.Second,
+}
+
+// chaveSemCache marca no contexto as leituras que devem ir ao servidor
+type chaveSemCache struct{}
+
+// semCache retorna um contexto cujas leituras ignoram o cache, sem consultá-lo
+// nem gravá-lo. Serve para quem precisa do estado atual, como um plano de
+// alterações, sem descartar o cache usado pelas demais páginas do perfil.
+func semCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, chaveSemCache{}, true)
+}
+
+// ttlCache retorna o tempo de cache do método; zero desativa o cache
+func (c *ClienteAPI) ttlCache(ctx context.Context, metodo string) time.Duration {
+	if c.config.Cache == nil || !metodoIdempotente(metodo) || ctx.Value(chaveSemCache{}) != nil {
+		return 0
+	}
+	if ttl, ok := c.config.TTLCache[metodo]; ok {
+		return ttl
+	}
+	return TTLCachePadrao[metodo]
+}
+
+// chaveCache identifica uma chamada pelo perfil, pelo método e pelos parâmetros.
+// O tipo separa resultados decodificados de respostas gravadas para leitura em fluxo.
+func (c *ClienteAPI) chaveCache(tipo, metodo string, params interface{}) (string, bool) {
+	dados, err := json.Marshal(params)
+	if err != nil {
+		return "", false
+	}
+	return prefixoCache(c.config.Perfil) + tipo + "|" + metodo + "|" + string(dados), true
+}
+
+// prefixoCache é o início comum das chaves de um perfil
+func prefixoCache(perfil string) string {
+	return perfil + "|"
+}
+
+// LimparCache descarta os resultados guardados para o perfil deste cliente
+func (c *ClienteAPI) LimparCache() {
+	if c.config.Cache != nil {
+		c.config.Cache.RemoverPrefixo(prefixoCache(c.config.Perfil))
+	}
+}
+
+// LimparCachePerfil descarta os resultados guardados para um perfil, por exemplo
+// ao selecioná-lo de novo. perfil é o mesmo valor informado em ConfigAPI.Perfil.
+func LimparCachePerfil(cache *Cache, perfil string) {
+	if cache != nil {
+		cache.RemoverPrefixo(prefixoCache(perfil))
+	}
+}
+
+// executarComCache envia um único pedido, reaproveitando resultados recentes e
+// juntando pedidos idênticos feitos ao mesmo tempo
+func (c *ClienteAPI) executarComCache(ctx context.Context, pedido *pedidoRPC) (RespostaAPI, error) {
+	ttl := c.ttlCache(ctx, pedido.Metodo)
+	chave, ok := c.chaveCache("chamada", pedido.Metodo, pedido.Params)
+	if ttl <= 0 || !ok {
+		return c.executarUm(ctx, pedido)
+	}
+
+	if resultado, ok := c.config.Cache.Get(chave); ok {
+		return RespostaAPI{Jsonrpc: "2.0", Result: resultado.(json.RawMessage), ID: pedido.ID}, nil
+	}
+
+	valor, err, compartilhado := c.chamadas.fazer(ctx, chave, func() (interface{}, error) {
+		resposta, err := c.executarUm(ctx, pedido)
+		if err == nil && resposta.Error == nil {
+			c.config.Cache.Set(chave, resposta.Result, ttl)
+		}
+		return resposta, err
+	})
+	if compartilhado && falhaDoLider(ctx, err) {
+		return c.executarUm(ctx, pedido)
+	}
+	if err != nil {
+		return RespostaAPI{}, err
+	}
+
+	return valor.(RespostaAPI), nil
+}
+
+// executarUm envia um único pedido autenticado
+func (c *ClienteAPI) executarUm(ctx context.Context, pedido *pedidoRPC) (RespostaAPI, error) {
+	respostas, err := c.executar(ctx, []*pedidoRPC{pedido})
+	if err != nil {
+		return RespostaAPI{}, err
+	}
+	return respostas[0], nil
+}
+
+// respostaGravada guarda o corpo de uma resposta lida em fluxo para ser lida
+// novamente. O ID é o do pedido original, conferido na releitura.
+type respostaGravada struct {
+	corpo []byte
+	id    uint64
+}
+
+// reproduzir entrega a fn os elementos de uma resposta gravada
+func reproduzir[R any](gravada respostaGravada, fn func(R) error) error {
+	_, err := lerRespostaFluxo(bytes.NewReader(gravada.corpo), &pedidoRPC{ID: gravada.id}, fn)
+	return err
+}
+
+// erroConsumidor marca um erro devolvido pela função de quem percorre o
+// resultado, e não pela API. Ele não deve ser repassado às chamadas agrupadas.
+type erroConsumidor struct {
+	err error
+}
+
+func (e *erroConsumidor) Error() string {
+	return e.err.Error()
+}
+
+// falhaDoLider informa se a chamada agrupada falhou por um motivo próprio de
+// quem a executou (cancelamento ou erro da sua função), caso em que as demais
+// chamadas devem ser feitas de novo
+func falhaDoLider(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+	var consumidor *erroConsumidor
+	return errors.As(err, &consumidor) ||
+		errors.Is(err, context.Canceled) ||
+		errors.Is(err, context.DeadlineExceeded)
+}
+
+// grupoChamadas junta chamadas idênticas simultâneas em uma única requisição
+type grupoChamadas struct {
+	mu       sync.Mutex
+	chamadas map[string]*chamadaEmAndamento
+}
+
+type chamadaEmAndamento struct {
+	pronta chan struct{}
+	valor  interface{}
+	err    error
+}
+
+// fazer executa fn uma única vez por chave entre as chamadas simultâneas. As
+// demais aguardam o resultado, a menos que o próprio contexto termine antes.
+// O terceiro retorno indica que o resultado veio da chamada de outra requisição.
+func (g *grupoChamadas) fazer(ctx context.Context, chave string, fn func() (interface{}, error)) (interface{}, error, bool) {
+	g.mu.Lock()
+	if g.chamadas == nil {
+		g.chamadas = make(map[string]*chamadaEmAndamento)
+	}
+	if chamada, ok := g.chamadas[chave]; ok {
+		g.mu.Unlock()
+		select {
+		case <-chamada.pronta:
+			return chamada.valor, chamada.err, true
+		case <-ctx.Done():
+			return nil, ctx.Err(), true
+		}
+	}
+	chamada := &chamadaEmAndamento{pronta: make(chan struct{})}
+	g.chamadas[chave] = chamada
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.chamadas, chave)
+		g.mu.Unlock()
+		close(chamada.pronta)
+	}()
+
+	chamada.valor, chamada.err = fn()
+	return chamada.valor, chamada.err, false
+}
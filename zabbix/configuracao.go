@@ -0,0 +1,303 @@
+package zabbix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Formatos aceitos por configuration.export e configuration.import
+const (
+	FormatoYAML = "yaml" // Zabbix 5.2+
+	FormatoXML  = "xml"
+	FormatoJSON = "json"
+)
+
+// FormatosConfiguracao descreve cada formato de exportação
+var FormatosConfiguracao = map[string]string{
+	FormatoYAML: "YAML",
+	FormatoXML:  "XML",
+	FormatoJSON: "JSON",
+}
+
+// ObjetosConfiguracao são os IDs dos objetos a exportar
+type ObjetosConfiguracao struct {
+	Templates  []string
+	Grupos     []string // Grupos de hosts
+	Hosts      []string
+	Mapas      []string
+	TiposMidia []string // Zabbix 5.0+
+}
+
+// Vazio informa se nenhum objeto foi escolhido
+func (o ObjetosConfiguracao) Vazio() bool {
+	return len(o.Templates) == 0 && len(o.Grupos) == 0 && len(o.Hosts) == 0 &&
+		len(o.Mapas) == 0 && len(o.TiposMidia) == 0
+}
+
+// Total retorna a quantidade de objetos escolhidos
+func (o ObjetosConfiguracao) Total() int {
+	return len(o.Templates) + len(o.Grupos) + len(o.Hosts) + len(o.Mapas) + len(o.TiposMidia)
+}
+
+// RegrasImportacao são as regras de configuration.import, aplicadas a todos os
+// tipos de objeto que as aceitam
+type RegrasImportacao struct {
+	CriarAusentes       bool // createMissing: cria o que está no arquivo e não existe no servidor
+	AtualizarExistentes bool // updateExisting: sobrescreve o que já existe com o conteúdo do arquivo
+	RemoverAusentes     bool // deleteMissing: apaga itens, triggers etc. que não estão no arquivo
+}
+
+// RegrasImportacaoPadrao cria e atualiza sem apagar nada, como o frontend sugere
+var RegrasImportacaoPadrao = RegrasImportacao{CriarAusentes: true, AtualizarExistentes: true}
+
+// paraVersao monta o parâmetro rules com os tipos de objeto e as opções que a
+// versão do servidor aceita. Opções desconhecidas fazem a importação falhar.
+func (r RegrasImportacao) paraVersao(versao Versao) map[string]map[string]bool {
+	criarAtualizar := func() map[string]bool {
+		return map[string]bool{"createMissing": r.CriarAusentes, "updateExisting": r.AtualizarExistentes}
+	}
+	completo := func() map[string]bool {
+		return map[string]bool{"createMissing": r.CriarAusentes, "updateExisting": r.AtualizarExistentes, "deleteMissing": r.RemoverAusentes}
+	}
+
+	regras := map[string]map[string]bool{
+		"hosts":           criarAtualizar(),
+		"templates":       criarAtualizar(),
+		"items":           completo(),
+		"triggers":        completo(),
+		"graphs":          completo(),
+		"discoveryRules":  completo(),
+		"httptests":       completo(),
+		"templateLinkage": {"createMissing": r.CriarAusentes, "deleteMissing": r.RemoverAusentes},
+		"maps":            criarAtualizar(),
+		"images":          criarAtualizar(), // Ícones e fundos usados pelos mapas
+	}
+	// Tipos de mídia entram na exportação a partir do Zabbix 5.0, como em ExportarConfiguracao
+	if versao.AoMenos(5, 0) {
+		regras["mediaTypes"] = criarAtualizar()
+	}
+	// Grupos de templates foram separados dos grupos de hosts no Zabbix 6.2
+	if versao.AoMenos(6, 2) {
+		regras["host_groups"] = criarAtualizar()
+		regras["template_groups"] = criarAtualizar()
+	} else {
+		regras["groups"] = map[string]bool{"createMissing": r.CriarAusentes}
+	}
+	// Telas de template viraram dashboards no Zabbix 5.2
+	if versao.AoMenos(5, 2) {
+		regras["templateDashboards"] = completo()
+	} else {
+		regras["templateScreens"] = completo()
+	}
+	// Mapas de valores passaram a pertencer aos templates e hosts no Zabbix 5.4
+	if versao.AoMenos(5, 4) {
+		regras["valueMaps"] = completo()
+	} else {
+		regras["valueMaps"] = criarAtualizar()
+	}
+	return regras
+}
+
+// validarFormato confere se o servidor aceita o formato
+func validarFormato(versao Versao, formato string) error {
+	if _, ok := FormatosConfiguracao[formato]; !ok {
+		return fmt.Errorf("formato inválido: %q", formato)
+	}
+	if formato == FormatoYAML && !versao.AoMenos(5, 2) {
+		return errors.New("o formato YAML requer Zabbix 5.2 ou posterior")
+	}
+	return nil
+}
+
+// ExportarConfiguracao retorna a configuração dos objetos no formato pedido,
+// como o arquivo baixado pelo frontend
+func (c *ClienteAPI) ExportarConfiguracao(objetos ObjetosConfiguracao, formato string) (string, error) {
+	return c.ExportarConfiguracaoCtx(context.Background(), objetos, formato)
+}
+
+// ExportarConfiguracaoCtx é a variante de ExportarConfiguracao que aceita um contexto
+func (c *ClienteAPI) ExportarConfiguracaoCtx(ctx context.Context, objetos ObjetosConfiguracao, formato string) (string, error) {
+	if objetos.Vazio() {
+		return "", errors.New("nenhum objeto escolhido para exportar")
+	}
+	versao, err := c.ObterVersaoCtx(ctx)
+	if err != nil {
+		return "", fmt.Errorf("erro ao negociar versão da API: %w", err)
+	}
+	if err := validarFormato(versao, formato); err != nil {
+		return "", err
+	}
+	if len(objetos.TiposMidia) > 0 && !versao.AoMenos(5, 0) {
+		return "", errors.New("a exportação de tipos de mídia requer Zabbix 5.0 ou posterior")
+	}
+
+	opcoes := map[string][]string{}
+	if len(objetos.Templates) > 0 {
+		opcoes["templates"] = objetos.Templates
+	}
+	if len(objetos.Hosts) > 0 {
+		opcoes["hosts"] = objetos.Hosts
+	}
+	if len(objetos.Grupos) > 0 {
+		// "groups" virou "host_groups" no Zabbix 6.2
+		if versao.AoMenos(6, 2) {
+			opcoes["host_groups"] = objetos.Grupos
+		} else {
+			opcoes["groups"] = objetos.Grupos
+		}
+	}
+	if len(objetos.Mapas) > 0 {
+		opcoes["maps"] = objetos.Mapas
+	}
+	if len(objetos.TiposMidia) > 0 {
+		opcoes["mediaTypes"] = objetos.TiposMidia
+	}
+
+	return ChamarCtx[string](ctx, c, "configuration.export", ParamsConfigurationExport{
+		Format:  formato,
+		Options: opcoes,
+	})
+}
+
+// ObterTodosObjetosConfiguracao retorna os IDs de todos os templates, grupos de
+// hosts, hosts, mapas e tipos de mídia visíveis ao usuário, para uma exportação
+// completa do servidor. Tipos de mídia só são incluídos a partir do Zabbix 5.0.
+func (c *ClienteAPI) ObterTodosObjetosConfiguracao() (ObjetosConfiguracao, error) {
+	return c.ObterTodosObjetosConfiguracaoCtx(context.Background())
+}
+
+// ObterTodosObjetosConfiguracaoCtx é a variante de ObterTodosObjetosConfiguracao que aceita um contexto
+func (c *ClienteAPI) ObterTodosObjetosConfiguracaoCtx(ctx context.Context) (ObjetosConfiguracao, error) {
+	versao, err := c.ObterVersaoCtx(ctx)
+	if err != nil {
+		return ObjetosConfiguracao{}, fmt.Errorf("erro ao negociar versão da API: %w", err)
+	}
+
+	type objeto struct {
+		GrupoID    string `json:"groupid"`
+		TemplateID string `json:"templateid"`
+		MapaID     string `json:"sysmapid"`
+		TipoID     string `json:"mediatypeid"`
+	}
+	lote := c.NovoLote()
+	resultadoGrupos := AdicionarAoLote[[]objeto](lote, "hostgroup.get", ParamsHostGroupGet{
+		Output: []string{"groupid"},
+	})
+	resultadoTemplates := AdicionarAoLote[[]objeto](lote, "template.get", ParamsTemplateGet{
+		Output: []string{"templateid"},
+	})
+	resultadoMapas := AdicionarAoLote[[]objeto](lote, "map.get", ParamsMapGet{
+		Output: []string{"sysmapid"},
+	})
+	var resultadoTipos *ResultadoLote[[]objeto]
+	if versao.AoMenos(5, 0) {
+		resultadoTipos = AdicionarAoLote[[]objeto](lote, "mediatype.get", ParamsMediaTypeGet{
+			Output: []string{"mediatypeid"},
+		})
+	}
+	if err := lote.ExecutarCtx(ctx); err != nil {
+		return ObjetosConfiguracao{}, err
+	}
+
+	var objetos ObjetosConfiguracao
+	ids := func(resultado *ResultadoLote[[]objeto], campo func(objeto) string) ([]string, error) {
+		lista, err := resultado.Obter()
+		if err != nil {
+			return nil, err
+		}
+		ids := make([]string, len(lista))
+		for i, o := range lista {
+			ids[i] = campo(o)
+		}
+		return ids, nil
+	}
+	if objetos.Grupos, err = ids(resultadoGrupos, func(o objeto) string { return o.GrupoID }); err != nil {
+		return ObjetosConfiguracao{}, err
+	}
+	if objetos.Templates, err = ids(resultadoTemplates, func(o objeto) string { return o.TemplateID }); err != nil {
+		return ObjetosConfiguracao{}, err
+	}
+	if objetos.Mapas, err = ids(resultadoMapas, func(o objeto) string { return o.MapaID }); err != nil {
+		return ObjetosConfiguracao{}, err
+	}
+	if resultadoTipos != nil {
+		if objetos.TiposMidia, err = ids(resultadoTipos, func(o objeto) string { return o.TipoID }); err != nil {
+			return ObjetosConfiguracao{}, err
+		}
+	}
+
+	// Os hosts são listados em fluxo, pois podem ser muitos
+	if objetos.Hosts, err = c.listarIDsHosts(ctx, ParamsHostGet{}); err != nil {
+		return ObjetosConfiguracao{}, err
+	}
+	return objetos, nil
+}
+
+// ObterIDsTemplates retorna apenas os IDs de todos os templates visíveis ao
+// usuário, para exportar só os templates do servidor
+func (c *ClienteAPI) ObterIDsTemplates() ([]string, error) {
+	return c.ObterIDsTemplatesCtx(context.Background())
+}
+
+// ObterIDsTemplatesCtx é a variante de ObterIDsTemplates que aceita um contexto
+func (c *ClienteAPI) ObterIDsTemplatesCtx(ctx context.Context) ([]string, error) {
+	var ids []string
+	err := PercorrerCtx(ctx, c, "template.get", ParamsTemplateGet{
+		Output: []string{"templateid"},
+	}, func(t struct {
+		ID string `json:"templateid"`
+	}) error {
+		ids = append(ids, t.ID)
+		return nil
+	})
+	return ids, err
+}
+
+// ImportarConfiguracao importa um arquivo exportado por um servidor Zabbix
+func (c *ClienteAPI) ImportarConfiguracao(formato, conteudo string, regras RegrasImportacao) error {
+	return c.ImportarConfiguracaoCtx(context.Background(), formato, conteudo, regras)
+}
+
+// ImportarConfiguracaoCtx é a variante de ImportarConfiguracao que aceita um contexto
+func (c *ClienteAPI) ImportarConfiguracaoCtx(ctx context.Context, formato, conteudo string, regras RegrasImportacao) error {
+	if conteudo == "" {
+		return errors.New("arquivo de importação vazio")
+	}
+	if !regras.CriarAusentes && !regras.AtualizarExistentes && !regras.RemoverAusentes {
+		return errors.New("nenhuma regra de importação escolhida")
+	}
+	versao, err := c.ObterVersaoCtx(ctx)
+	if err != nil {
+		return fmt.Errorf("erro ao negociar versão da API: %w", err)
+	}
+	if err := validarFormato(versao, formato); err != nil {
+		return err
+	}
+
+	_, err = ChamarCtx[bool](ctx, c, "configuration.import", ParamsConfigurationImport{
+		Format: formato,
+		Source: conteudo,
+		Rules:  regras.paraVersao(versao),
+	})
+	return err
+}
+
+// CopiarConfiguracao exporta os objetos do servidor de origem e os importa no
+// de destino, por exemplo para levar um template de um perfil a outro. O
+// formato JSON é usado por ser aceito por todas as versões.
+func CopiarConfiguracao(origem, destino *ClienteAPI, objetos ObjetosConfiguracao, regras RegrasImportacao) error {
+	return CopiarConfiguracaoCtx(context.Background(), origem, destino, objetos, regras)
+}
+
+// CopiarConfiguracaoCtx é a variante de CopiarConfiguracao que aceita um contexto
+func CopiarConfiguracaoCtx(ctx context.Context, origem, destino *ClienteAPI, objetos ObjetosConfiguracao, regras RegrasImportacao) error {
+	conteudo, err := origem.ExportarConfiguracaoCtx(ctx, objetos, FormatoJSON)
+	if err != nil {
+		return fmt.Errorf("erro ao exportar da origem: %w", err)
+	}
+	if err := destino.ImportarConfiguracaoCtx(ctx, FormatoJSON, conteudo, regras); err != nil {
+		return fmt.Errorf("erro ao importar no destino: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package zabbix
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Declaracao é o estado desejado de templates e hosts, mantido em arquivos
+// YAML. Em cada objeto, um campo omitido não é gerenciado e o que estiver no
+// servidor é mantido; uma lista vazia ("groups: []") remove tudo.
+type Declaracao struct {
+	Templates       []TemplateDeclarado `yaml:"templates"`
+	Hosts           []HostDeclarado     `yaml:"hosts"`
+	RemoverAusentes EscopoRemocao       `yaml:"delete_missing"`
+}
+
+// EscopoRemocao limita a remoção de objetos ausentes dos arquivos: só hosts
+// e templates desses grupos são removidos. Sem grupos, nada é removido.
+type EscopoRemocao struct {
+	GruposHosts     []string `yaml:"host_groups"`
+	GruposTemplates []string `yaml:"template_groups"` // Grupos de hosts antes do Zabbix 6.2
+}
+
+// TemplateDeclarado é um template. Itens, triggers e demais entidades não são
+// gerenciados aqui; use a importação de configuração para eles.
+type TemplateDeclarado struct {
+	Template  string           `yaml:"template"` // Nome técnico
+	Nome      string           `yaml:"name"`
+	Grupos    []string         `yaml:"groups"`
+	Templates []string         `yaml:"templates"` // Templates vinculados, pelo nome técnico
+	Macros    []MacroDeclarada `yaml:"macros"`
+	Tags      []Tag            `yaml:"tags"`
+}
+
+// HostDeclarado é um host
+type HostDeclarado struct {
+	Host       string               `yaml:"host"` // Nome técnico
+	Nome       string               `yaml:"name"`
+	Status     string               `yaml:"status"` // enabled ou disabled
+	Grupos     []string             `yaml:"groups"`
+	Templates  []string             `yaml:"templates"`
+	Macros     []MacroDeclarada     `yaml:"macros"`
+	Tags       []Tag                `yaml:"tags"`
+	Interfaces []InterfaceDeclarada `yaml:"interfaces"`
+}
+
+// MacroDeclarada é uma macro de usuário de um host ou template. O valor de
+// macros secretas não é lido pela API, por isso só é gravado quando a macro é
+// criada ou muda de tipo.
+type MacroDeclarada struct {
+	Macro     string `yaml:"macro"`
+	Valor     string `yaml:"value"`
+	Descricao string `yaml:"description"`
+	Tipo      string `yaml:"type"` // text (padrão), secret ou vault
+}
+
+// InterfaceDeclarada é uma interface de host. A primeira de cada tipo é a
+// principal, a menos que outra tenha "main: true".
+type InterfaceDeclarada struct {
+	Tipo      string            `yaml:"type"` // agent, snmp, ipmi ou jmx
+	IP        string            `yaml:"ip"`
+	DNS       string            `yaml:"dns"`
+	Porta     string            `yaml:"port"` // Vazia usa a porta padrão do tipo
+	Principal *bool             `yaml:"main"`
+	Detalhes  map[string]string `yaml:"details"` // SNMP; vazio usa SNMPv2 com {$SNMP_COMMUNITY}
+}
+
+// tiposInterfaceDeclarada traz o código da API e a porta padrão de cada tipo
+var tiposInterfaceDeclarada = map[string]struct{ codigo, porta string }{
+	"agent": {"1", "10050"},
+	"snmp":  {"2", "161"},
+	"ipmi":  {"3", "623"},
+	"jmx":   {"4", "12345"},
+}
+
+// tiposMacroDeclarada converte o tipo da macro no código da API
+var tiposMacroDeclarada = map[string]string{
+	"":       "0",
+	"text":   "0",
+	"secret": "1",
+	"vault":  "2",
+}
+
+// statusDeclarado converte o status do host no código da API
+var statusDeclarado = map[string]string{
+	"enabled":  "0",
+	"disabled": "1",
+}
+
+// Validar confere nomes obrigatórios, repetições e valores aceitos. Todos os
+// problemas encontrados são retornados juntos.
+func (d Declaracao) Validar() error {
+	var problemas []error
+	problema := func(formato string, args ...interface{}) {
+		problemas = append(problemas, fmt.Errorf(formato, args...))
+	}
+
+	nomes := make(map[string]string)
+	for _, t := range d.Templates {
+		if strings.TrimSpace(t.Template) == "" {
+			problema("template sem o campo \"template\"")
+			continue
+		}
+		if tipo, repetido := nomes[t.Template]; repetido {
+			problema("o nome %q aparece em mais de um %s", t.Template, tipo)
+		}
+		nomes[t.Template] = TipoTemplate
+		for _, vinculado := range t.Templates {
+			if vinculado == t.Template {
+				problema("o template %q vincula a si mesmo", t.Template)
+			}
+		}
+		problemas = append(problemas, validarMacros("template "+t.Template, t.Macros)...)
+	}
+
+	for _, h := range d.Hosts {
+		if strings.TrimSpace(h.Host) == "" {
+			problema("host sem o campo \"host\"")
+			continue
+		}
+		if tipo, repetido := nomes[h.Host]; repetido {
+			problema("o nome %q aparece em mais de um %s", h.Host, tipo)
+		}
+		nomes[h.Host] = TipoHost
+		if _, ok := statusDeclarado[h.Status]; h.Status != "" && !ok {
+			problema("host %q: status inválido %q (use enabled ou disabled)", h.Host, h.Status)
+		}
+		for _, i := range h.Interfaces {
+			if _, ok := tiposInterfaceDeclarada[i.Tipo]; !ok {
+				problema("host %q: tipo de interface inválido %q (use agent, snmp, ipmi ou jmx)", h.Host, i.Tipo)
+			}
+			if i.IP == "" && i.DNS == "" {
+				problema("host %q: interface %s sem ip nem dns", h.Host, i.Tipo)
+			}
+		}
+		problemas = append(problemas, validarMacros("host "+h.Host, h.Macros)...)
+	}
+	return errors.Join(problemas...)
+}
+
+// validarMacros confere o nome, o tipo e a repetição das macros de um objeto
+func validarMacros(dono string, macros []MacroDeclarada) []error {
+	var problemas []error
+	vistas := make(map[string]bool, len(macros))
+	for _, m := range macros {
+		if !strings.HasPrefix(m.Macro, "{$") || !strings.HasSuffix(m.Macro, "}") {
+			problemas = append(problemas, fmt.Errorf("%s: nome de macro inválido %q (use o formato {$NOME})", dono, m.Macro))
+		}
+		if vistas[m.Macro] {
+			problemas = append(problemas, fmt.Errorf("%s: macro %s repetida", dono, m.Macro))
+		}
+		vistas[m.Macro] = true
+		if _, ok := tiposMacroDeclarada[m.Tipo]; !ok {
+			problemas = append(problemas, fmt.Errorf("%s: tipo inválido %q na macro %s (use text, secret ou vault)", dono, m.Tipo, m.Macro))
+		}
+	}
+	return problemas
+}
+
+// Juntar acrescenta os objetos e o escopo de remoção de outra declaração,
+// como a de outro arquivo
+func (d *Declaracao) Juntar(outra Declaracao) {
+	d.Templates = append(d.Templates, outra.Templates...)
+	d.Hosts = append(d.Hosts, outra.Hosts...)
+	d.RemoverAusentes.GruposHosts = append(d.RemoverAusentes.GruposHosts, outra.RemoverAusentes.GruposHosts...)
+	d.RemoverAusentes.GruposTemplates = append(d.RemoverAusentes.GruposTemplates, outra.RemoverAusentes.GruposTemplates...)
+}
+
+// Vazia informa se a declaração não tem objetos nem escopo de remoção
+func (d Declaracao) Vazia() bool {
+	return len(d.Templates) == 0 && len(d.Hosts) == 0 &&
+		len(d.RemoverAusentes.GruposHosts) == 0 && len(d.RemoverAusentes.GruposTemplates) == 0
+}
+
+// parametros converte a interface para a API, com a porta padrão do tipo e os
+// detalhes SNMP padrão quando omitidos
+func (i InterfaceDeclarada) parametros(principal bool) ParamsInterface {
+	tipo := tiposInterfaceDeclarada[i.Tipo]
+	params := ParamsInterface{
+		Type:    tipo.codigo,
+		Main:    "0",
+		UseIP:   "0",
+		IP:      i.IP,
+		DNS:     i.DNS,
+		Port:    i.Porta,
+		Details: i.Detalhes,
+	}
+	if principal {
+		params.Main = "1"
+	}
+	if i.IP != "" {
+		params.UseIP = "1"
+	}
+	if params.Port == "" {
+		params.Port = tipo.porta
+	}
+	if i.Tipo == "snmp" && len(params.Details) == 0 {
+		params.Details = map[string]string{"version": "2", "bulk": "1", "community": "{$SNMP_COMMUNITY}"}
+	}
+	return params
+}
+
+// parametrosInterfaces converte as interfaces de um host, marcando a principal
+// de cada tipo
+func parametrosInterfaces(interfaces []InterfaceDeclarada) []ParamsInterface {
+	escolhida := make(map[string]int)
+	for i, iface := range interfaces {
+		if _, ok := escolhida[iface.Tipo]; !ok {
+			escolhida[iface.Tipo] = i
+		}
+	}
+	// A primeira marcada com "main: true" tem preferência sobre a primeira do tipo
+	marcada := make(map[string]bool)
+	for i, iface := range interfaces {
+		if iface.Principal != nil && *iface.Principal && !marcada[iface.Tipo] {
+			escolhida[iface.Tipo] = i
+			marcada[iface.Tipo] = true
+		}
+	}
+
+	params := make([]ParamsInterface, len(interfaces))
+	for i, iface := range interfaces {
+		params[i] = iface.parametros(escolhida[iface.Tipo] == i)
+	}
+	return params
+}
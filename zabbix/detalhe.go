@@ -0,0 +1,135 @@
+package zabbix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrHostNaoEncontrado indica que o host pedido não existe ou não é visível
+// para o usuário do perfil
+var ErrHostNaoEncontrado = errors.New("host não encontrado")
+
+// NomesSeveridade mapeia a severidade de triggers e eventos para o nome exibido
+var NomesSeveridade = map[string]string{
+	"0": "Não classificada",
+	"1": "Informação",
+	"2": "Atenção",
+	"3": "Média",
+	"4": "Alta",
+	"5": "Desastre",
+}
+
+// DetalheHost reúne os dados da página de um host: interfaces, itens,
+// triggers e os eventos do período escolhido
+type DetalheHost struct {
+	Host     Host
+	Itens    []Item
+	Triggers []Trigger
+	Eventos  []Evento
+}
+
+// ObterDetalheHost busca o host, seus itens, triggers e eventos entre inicio
+// e fim em uma única requisição em lote
+func (c *ClienteAPI) ObterDetalheHost(hostID string, inicio, fim time.Time) (*DetalheHost, error) {
+	return c.ObterDetalheHostCtx(context.Background(), hostID, inicio, fim)
+}
+
+// ObterDetalheHostCtx é a variante de ObterDetalheHost que aceita um contexto
+func (c *ClienteAPI) ObterDetalheHostCtx(ctx context.Context, hostID string, inicio, fim time.Time) (*DetalheHost, error) {
+	versao, err := c.ObterVersaoCtx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao negociar versão da API: %w", err)
+	}
+
+	paramsHost := ParamsHostGet{
+		Output:                []string{"hostid", "host", "name", "status"},
+		HostIDs:               []string{hostID},
+		SelectInterfaces:      SaidaCompleta,
+		SelectHostGroups:      []string{"groupid", "name"},
+		SelectParentTemplates: []string{"templateid", "name"},
+		SelectTags:            []string{"tag", "value"},
+	}
+	if !versao.AoMenos(6, 2) {
+		paramsHost.SelectGroups = paramsHost.SelectHostGroups
+		paramsHost.SelectHostGroups = nil
+	}
+
+	lote := c.NovoLote()
+	resultadoHost := AdicionarAoLote[[]Host](lote, "host.get", paramsHost)
+	resultadoItens := AdicionarAoLote[[]Item](lote, "item.get", ParamsItemGet{
+		Output: []string{"itemid", "name", "key_", "status", "state", "error",
+			"lastvalue", "prevvalue", "units", "value_type", "lastclock"},
+		HostIDs:   []string{hostID},
+		SortField: []string{"name"},
+	})
+	resultadoTriggers := AdicionarAoLote[[]Trigger](lote, "trigger.get", ParamsTriggerGet{
+		Output:            []string{"triggerid", "description", "status", "state", "error", "value", "priority", "lastchange"},
+		HostIDs:           []string{hostID},
+		ExpandDescription: true,
+		SortField:         []string{"priority", "description"},
+		SortOrder:         "DESC",
+	})
+	resultadoEventos := AdicionarAoLote[[]Evento](lote, "event.get", paramsHistoricoEventos(hostID, inicio, fim))
+
+	// Antes do Zabbix 5.4 a disponibilidade do agente ficava no próprio host
+	var resultadoDisponibilidade *ResultadoLote[[]disponibilidadeHost]
+	if !versao.AoMenos(5, 4) {
+		resultadoDisponibilidade = AdicionarAoLote[[]disponibilidadeHost](lote, "host.get", ParamsHostGet{
+			Output:  []string{"hostid", "available", "error"},
+			HostIDs: []string{hostID},
+		})
+	}
+
+	if err := lote.ExecutarCtx(ctx); err != nil {
+		return nil, err
+	}
+
+	hosts, err := resultadoHost.Obter()
+	if err != nil {
+		return nil, err
+	}
+	if len(hosts) == 0 {
+		return nil, ErrHostNaoEncontrado
+	}
+
+	detalhe := &DetalheHost{Host: hosts[0]}
+	if detalhe.Itens, err = resultadoItens.Obter(); err != nil {
+		return nil, err
+	}
+	if detalhe.Triggers, err = resultadoTriggers.Obter(); err != nil {
+		return nil, err
+	}
+	if detalhe.Eventos, err = resultadoEventos.Obter(); err != nil {
+		return nil, err
+	}
+
+	if resultadoDisponibilidade != nil {
+		disponibilidade, err := resultadoDisponibilidade.Obter()
+		if err != nil {
+			return nil, err
+		}
+		if len(disponibilidade) > 0 && disponibilidade[0].Disponivel != "" {
+			disponibilidade[0].aplicar(&detalhe.Host)
+		}
+	}
+
+	return detalhe, nil
+}
+
+// disponibilidadeHost é a disponibilidade do agente informada no host antes do Zabbix 5.4
+type disponibilidadeHost struct {
+	Disponivel string `json:"available"`
+	Erro       string `json:"error"`
+}
+
+// aplicar copia a disponibilidade para as interfaces de agente do host
+func (d disponibilidadeHost) aplicar(host *Host) {
+	for i := range host.Interfaces {
+		if host.Interfaces[i].Tipo == "1" {
+			host.Interfaces[i].Disponivel = d.Disponivel
+			host.Interfaces[i].Erro = d.Erro
+		}
+	}
+}
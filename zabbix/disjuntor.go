@@ -0,0 +1,151 @@
+package zabbix
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrServidorIndisponivel indica que o disjuntor do servidor está aberto
+var ErrServidorIndisponivel = errors.New("servidor Zabbix indisponível")
+
+// EstadoDisjuntor representa o estado atual de um Disjuntor
+type EstadoDisjuntor int
+
+// Estados do disjuntor
+const (
+	DisjuntorFechado    EstadoDisjuntor = iota // Requisições normais
+	DisjuntorAberto                            // Requisições recusadas até o fim da espera
+	DisjuntorMeioAberto                        // Uma requisição de teste em andamento
+)
+
+// ErroDisjuntorAberto é retornado sem contatar o servidor enquanto o disjuntor está aberto
+type ErroDisjuntorAberto struct {
+	TentarEm time.Duration // Tempo até a próxima tentativa ser permitida
+}
+
+func (e *ErroDisjuntorAberto) Error() string {
+	return fmt.Sprintf("servidor Zabbix indisponível, nova tentativa em %ds", e.SegundosRestantes())
+}
+
+// SegundosRestantes arredonda TentarEm para cima, em segundos
+func (e *ErroDisjuntorAberto) SegundosRestantes() int {
+	return int((e.TentarEm + time.Second - 1) / time.Second)
+}
+
+// Is permite usar errors.Is com ErrServidorIndisponivel e ErrTransporte
+func (e *ErroDisjuntorAberto) Is(alvo error) bool {
+	return alvo == ErrServidorIndisponivel || alvo == ErrTransporte
+}
+
+// Disjuntor interrompe as chamadas a um servidor após falhas consecutivas de
+// transporte, evitando que cada página espere o tempo limite inteiro
+type Disjuntor struct {
+	mu           sync.Mutex
+	limiteFalhas int
+	tempoAberto  time.Duration
+	falhas       int
+	abertoAte    time.Time
+	emTeste      bool
+}
+
+// NovoDisjuntor cria um disjuntor que abre após limiteFalhas falhas seguidas e
+// permanece aberto por tempoAberto. Um limite menor ou igual a zero o desativa.
+func NovoDisjuntor(limiteFalhas int, tempoAberto time.Duration) *Disjuntor {
+	return &Disjuntor{
+		limiteFalhas: limiteFalhas,
+		tempoAberto:  tempoAberto,
+	}
+}
+
+// Permitir informa se uma requisição pode ser feita. Depois da espera, apenas
+// uma requisição de teste passa; as demais aguardam o resultado dela.
+func (d *Disjuntor) Permitir() error {
+	if d == nil || d.limiteFalhas <= 0 {
+		return nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.falhas < d.limiteFalhas {
+		return nil
+	}
+
+	agora := time.Now()
+	if agora.Before(d.abertoAte) {
+		return &ErroDisjuntorAberto{TentarEm: d.abertoAte.Sub(agora)}
+	}
+
+	if d.emTeste {
+		return &ErroDisjuntorAberto{TentarEm: time.Second}
+	}
+
+	d.emTeste = true
+	return nil
+}
+
+// RegistrarSucesso fecha o disjuntor
+func (d *Disjuntor) RegistrarSucesso() {
+	if d == nil {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.falhas = 0
+	d.emTeste = false
+}
+
+// RegistrarFalha contabiliza uma falha de transporte e abre o disjuntor ao atingir o limite
+func (d *Disjuntor) RegistrarFalha() {
+	if d == nil || d.limiteFalhas <= 0 {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.falhas++
+	d.emTeste = false
+	if d.falhas >= d.limiteFalhas {
+		d.abertoAte = time.Now().Add(d.tempoAberto)
+	}
+}
+
+// LiberarTeste encerra a requisição de teste sem resultado, como quando o
+// chamador a cancela, sem contar sucesso nem falha. A próxima requisição
+// passa a ser o teste; sem isso, o disjuntor ficaria meio aberto para sempre.
+func (d *Disjuntor) LiberarTeste() {
+	if d == nil {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.emTeste = false
+}
+
+// Estado retorna o estado atual do disjuntor
+func (d *Disjuntor) Estado() EstadoDisjuntor {
+	if d == nil || d.limiteFalhas <= 0 {
+		return DisjuntorFechado
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	switch {
+	case d.falhas < d.limiteFalhas:
+		return DisjuntorFechado
+	case d.emTeste:
+		return DisjuntorMeioAberto
+	case time.Now().Before(d.abertoAte):
+		return DisjuntorAberto
+	default:
+		return DisjuntorMeioAberto
+	}
+}
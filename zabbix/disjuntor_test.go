@@ -0,0 +1,136 @@
+package zabbix
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Comportamentos do servidor de teste do disjuntor
+const (
+	servidorFalhando = iota
+	servidorTravado
+	servidorRespondendo
+)
+
+// TestDisjuntorTesteCancelado confere que o cancelamento da requisição de
+// teste, com o disjuntor meio aberto, não deixa o perfil bloqueado
+func TestDisjuntorTesteCancelado(t *testing.T) {
+	var modo atomic.Int32
+	fim := make(chan struct{})
+	servidor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch modo.Load() {
+		case servidorFalhando:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case servidorTravado:
+			select {
+			case <-r.Context().Done():
+			case <-fim:
+			}
+		default:
+			w.Write([]byte(`{"jsonrpc":"2.0","result":"6.0.0","id":1}`))
+		}
+	}))
+	defer servidor.Close()
+	defer close(fim) // Libera o handler travado antes de fechar o servidor
+
+	cliente := NovoClienteAPI(ConfigAPI{
+		URL:            servidor.URL,
+		Tentativas:     1,
+		LimiteFalhas:   1,
+		TempoDisjuntor: 10 * time.Millisecond,
+	})
+	enviar := func(ctx context.Context) error {
+		var destino json.RawMessage
+		return cliente.enviarComRepeticao(ctx, map[string]string{"method": "apiinfo.version"}, "", &destino, true)
+	}
+
+	modo.Store(servidorFalhando)
+	if err := enviar(context.Background()); err == nil {
+		t.Fatal("esperava falha do servidor")
+	}
+	if estado := cliente.disjuntor.Estado(); estado != DisjuntorAberto {
+		t.Fatalf("estado após a falha = %d, esperava aberto", estado)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	// A requisição de teste é cancelada pelo chamador enquanto espera o servidor
+	modo.Store(servidorTravado)
+	ctx, cancelar := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancelar()
+	if err := enviar(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("erro do teste cancelado = %v, esperava o do contexto", err)
+	}
+
+	modo.Store(servidorRespondendo)
+	if err := enviar(context.Background()); err != nil {
+		t.Fatalf("requisição após o teste cancelado: %v", err)
+	}
+	if estado := cliente.disjuntor.Estado(); estado != DisjuntorFechado {
+		t.Fatalf("estado após o sucesso = %d, esperava fechado", estado)
+	}
+}
+
+// TestDisjuntorRespostaInvalida confere que respostas que não são JSON-RPC
+// válido contam como falha, mesmo sem novas tentativas
+func TestDisjuntorRespostaInvalida(t *testing.T) {
+	casos := []struct {
+		nome   string
+		status int
+		corpo  string
+	}{
+		{"erro 500 com página", http.StatusInternalServerError, "<html>PHP Fatal error</html>"},
+		{"JSON inválido", http.StatusOK, "<html>manutenção</html>"},
+	}
+	for _, caso := range casos {
+		t.Run(caso.nome, func(t *testing.T) {
+			servidor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(caso.status)
+				w.Write([]byte(caso.corpo))
+			}))
+			defer servidor.Close()
+
+			cliente := NovoClienteAPI(ConfigAPI{
+				URL:            servidor.URL,
+				Tentativas:     1,
+				LimiteFalhas:   2,
+				TempoDisjuntor: time.Minute,
+			})
+			for i := 0; i < 2; i++ {
+				var destino json.RawMessage
+				err := cliente.enviarComRepeticao(context.Background(), map[string]string{"method": "apiinfo.version"}, "", &destino, true)
+				if err == nil {
+					t.Fatal("esperava erro")
+				}
+			}
+			if estado := cliente.disjuntor.Estado(); estado != DisjuntorAberto {
+				t.Fatalf("estado após duas respostas inválidas = %d, esperava aberto", estado)
+			}
+		})
+	}
+}
+
+func TestMetodoIdempotente(t *testing.T) {
+	casos := map[string]bool{
+		"host.get":                 true,
+		"sla.getsli":               true,
+		"apiinfo.version":          true,
+		"configuration.export":     true,
+		"user.checkAuthentication": true,
+		"host.update":              false,
+		"event.acknowledge":        false,
+		"configuration.import":     false,
+		"usermacro.getglobal":      false,
+		"foo.getter.delete":        false,
+	}
+	for metodo, esperado := range casos {
+		if obtido := metodoIdempotente(metodo); obtido != esperado {
+			t.Errorf("metodoIdempotente(%q) = %t, esperava %t", metodo, obtido, esperado)
+		}
+	}
+}
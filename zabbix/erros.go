@@ -1,20 +1,173 @@
-
 package zabbix
 
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// CategoriaErro classifica os erros retornados pelo cliente da API
+type CategoriaErro int
+
+// Categorias de erro
+const (
+	CategoriaDesconhecida CategoriaErro = iota
+	CategoriaAutenticacao
+	CategoriaPermissao
+	CategoriaParametros
+	CategoriaTransporte
+)
+
+// Erros sentinela para uso com errors.Is, um por categoria
+var (
+	ErrAutenticacao = errors.New("falha de autenticação na API do Zabbix")
+	ErrPermissao    = errors.New("sem permissão para a operação na API do Zabbix")
+	ErrParametros   = errors.New("parâmetros inválidos para a API do Zabbix")
+	ErrTransporte   = errors.New("falha de comunicação com o servidor Zabbix")
+)
+
+// Códigos de erro JSON-RPC usados pelo Zabbix
+const (
+	codigoErroParse           = -32700
+	codigoRequisicaoInvalida  = -32600
+	codigoMetodoInexistente   = -32601
+	codigoParametrosInvalidos = -32602
+)
+
+// String retorna um identificador curto da categoria, adequado para URLs
+func (c CategoriaErro) String() string {
+	switch c {
+	case CategoriaAutenticacao:
+		return "autenticacao"
+	case CategoriaPermissao:
+		return "permissao"
+	case CategoriaParametros:
+		return "parametros"
+	case CategoriaTransporte:
+		return "transporte"
+	default:
+		return "desconhecido"
+	}
+}
+
+// sentinela retorna o erro sentinela correspondente à categoria
+func (c CategoriaErro) sentinela() error {
+	switch c {
+	case CategoriaAutenticacao:
+		return ErrAutenticacao
+	case CategoriaPermissao:
+		return ErrPermissao
+	case CategoriaParametros:
+		return ErrParametros
+	case CategoriaTransporte:
+		return ErrTransporte
+	default:
+		return nil
+	}
+}
+
+// ErroAPI representa um erro JSON-RPC retornado pelo servidor Zabbix
 type ErroAPI struct {
 	Codigo    int
 	Mensagem  string
 	Detalhes  string
+	Metodo    string
+	Categoria CategoriaErro
 }
 
 func (e *ErroAPI) Error() string {
-	return e.Mensagem
+	texto := "erro na API"
+	if e.Metodo != "" {
+		texto += " (" + e.Metodo + ")"
+	}
+	texto += ": " + e.Mensagem
+	if e.Detalhes != "" {
+		texto += " - " + e.Detalhes
+	}
+	return texto
 }
 
+// Is permite comparar o erro com os sentinelas da sua categoria
+func (e *ErroAPI) Is(alvo error) bool {
+	sentinela := e.Categoria.sentinela()
+	return sentinela != nil && alvo == sentinela
+}
+
+// NovoErroAPI cria um ErroAPI já classificado a partir do código e das mensagens
 func NovoErroAPI(codigo int, mensagem, detalhes string) *ErroAPI {
 	return &ErroAPI{
-		Codigo:   codigo,
-		Mensagem: mensagem,
-		Detalhes: detalhes,
+		Codigo:    codigo,
+		Mensagem:  mensagem,
+		Detalhes:  detalhes,
+		Categoria: classificarErroAPI(codigo, mensagem+" "+detalhes),
+	}
+}
+
+// classificarErroAPI deduz a categoria pelo código e pelo texto do erro, já que o
+// Zabbix usa -32602 tanto para parâmetros inválidos quanto para falhas de login
+func classificarErroAPI(codigo int, texto string) CategoriaErro {
+	texto = strings.ToLower(texto)
+
+	switch {
+	case strings.Contains(texto, "not authorised"),
+		strings.Contains(texto, "not authorized"),
+		strings.Contains(texto, "session terminated"),
+		strings.Contains(texto, "api token expired"),
+		strings.Contains(texto, "incorrect user name or password"),
+		strings.Contains(texto, "login name or password is incorrect"),
+		strings.Contains(texto, "temporarily blocked"):
+		return CategoriaAutenticacao
+	case strings.Contains(texto, "no permissions"),
+		strings.Contains(texto, "permission denied"),
+		strings.Contains(texto, "you do not have permission"):
+		return CategoriaPermissao
+	}
+
+	switch codigo {
+	case codigoParametrosInvalidos, codigoRequisicaoInvalida, codigoMetodoInexistente:
+		return CategoriaParametros
+	case codigoErroParse:
+		return CategoriaTransporte
+	}
+
+	return CategoriaDesconhecida
+}
+
+// ErroTransporte envolve falhas de HTTP, rede ou decodificação da resposta
+type ErroTransporte struct {
+	Operacao   string // Etapa que falhou (ex: "requisição", "decodificação")
+	StatusCode int    // Código HTTP, quando houver resposta
+	Err        error
+}
+
+func (e *ErroTransporte) Error() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("erro na API, código de status: %d", e.StatusCode)
 	}
+	return fmt.Sprintf("erro na %s: %v", e.Operacao, e.Err)
+}
+
+func (e *ErroTransporte) Unwrap() error {
+	return e.Err
+}
+
+// Is permite usar errors.Is(err, ErrTransporte)
+func (e *ErroTransporte) Is(alvo error) bool {
+	return alvo == ErrTransporte
+}
+
+// ClassificarErro retorna a categoria de qualquer erro devolvido pelo cliente
+func ClassificarErro(err error) CategoriaErro {
+	var erroAPI *ErroAPI
+	if errors.As(err, &erroAPI) {
+		return erroAPI.Categoria
+	}
+
+	for _, categoria := range []CategoriaErro{CategoriaAutenticacao, CategoriaPermissao, CategoriaParametros, CategoriaTransporte} {
+		if errors.Is(err, categoria.sentinela()) {
+			return categoria
+		}
+	}
+
+	return CategoriaDesconhecida
 }
@@ -0,0 +1,252 @@
+package zabbix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ErrItemNaoEncontrado indica que o item pedido não existe ou não é visível
+// para o usuário do perfil
+var ErrItemNaoEncontrado = errors.New("item não encontrado")
+
+// Tipos de informação de um item (value_type). O mesmo código escolhe a
+// tabela de histórico consultada por history.get.
+const (
+	TipoValorNumerico  = "0" // Ponto flutuante
+	TipoValorCaractere = "1"
+	TipoValorLog       = "2"
+	TipoValorInteiro   = "3" // Inteiro sem sinal
+	TipoValorTexto     = "4"
+	TipoValorBinario   = "5" // Zabbix 7.0
+)
+
+// PeriodoMaximoHistorico é o maior período lido do histórico por ObterSerie;
+// períodos mais longos usam as tendências, como nos gráficos do frontend
+const PeriodoMaximoHistorico = 24 * time.Hour
+
+// Limites de valores lidos por ObterSerie. O gráfico reduz a série a um ponto
+// por pixel, mas antes disso a resposta inteira fica em memória; sem limite,
+// um item coletado a cada segundo ou um período de anos traria tudo de uma vez.
+const (
+	LimiteValoresHistorico  = 20000
+	LimiteValoresTendencias = 2 * 366 * 24 // Dois anos de resumos horários
+)
+
+// RegistroHistorico é um valor coletado de um item
+type RegistroHistorico struct {
+	ItemID string   `json:"itemid"`
+	Clock  Instante `json:"clock"`
+	NS     string   `json:"ns"`
+	Valor  string   `json:"value"`
+}
+
+// RegistroTendencia é o resumo horário de um item numérico
+type RegistroTendencia struct {
+	ItemID     string   `json:"itemid"`
+	Clock      Instante `json:"clock"`
+	Quantidade string   `json:"num"`
+	Minimo     string   `json:"value_min"`
+	Media      string   `json:"value_avg"`
+	Maximo     string   `json:"value_max"`
+}
+
+// PontoSerie é um ponto de uma série numérica. Para pontos do histórico,
+// mínimo, média e máximo são o próprio valor.
+type PontoSerie struct {
+	Momento time.Time
+	Minimo  float64
+	Media   float64
+	Maximo  float64
+}
+
+// Serie são os valores de um item em um período, do mais antigo ao mais recente
+type Serie struct {
+	Pontos     []PontoSerie
+	Tendencias bool // Os pontos vieram de trend.get
+	Truncada   bool // O limite de valores foi atingido e os mais antigos ficaram de fora
+}
+
+// Numerico informa se o item guarda valores numéricos, os únicos com tendências
+func (i Item) Numerico() bool {
+	return i.TipoValor == TipoValorNumerico || i.TipoValor == TipoValorInteiro
+}
+
+// ObterItem busca um item pelo ID, com o host a que pertence
+func (c *ClienteAPI) ObterItem(itemID string) (Item, error) {
+	return c.ObterItemCtx(context.Background(), itemID)
+}
+
+// ObterItemCtx é a variante de ObterItem que aceita um contexto
+func (c *ClienteAPI) ObterItemCtx(ctx context.Context, itemID string) (Item, error) {
+	itens, err := ChamarCtx[[]Item](ctx, c, "item.get", ParamsItemGet{
+		Output: []string{"itemid", "hostid", "name", "key_", "status", "state", "error",
+			"lastvalue", "prevvalue", "units", "value_type", "lastclock"},
+		ItemIDs:     []string{itemID},
+		SelectHosts: []string{"hostid", "host", "name"},
+	})
+	if err != nil {
+		return Item{}, err
+	}
+	if len(itens) == 0 {
+		return Item{}, ErrItemNaoEncontrado
+	}
+	return itens[0], nil
+}
+
+// PercorrerHistorico chama fn para cada valor do item entre inicio e fim, do
+// mais antigo ao mais recente. A tabela consultada segue o value_type do item.
+func (c *ClienteAPI) PercorrerHistorico(item Item, inicio, fim time.Time, fn func(RegistroHistorico) error) error {
+	return c.PercorrerHistoricoCtx(context.Background(), item, inicio, fim, fn)
+}
+
+// PercorrerHistoricoCtx é a variante de PercorrerHistorico que aceita um contexto
+func (c *ClienteAPI) PercorrerHistoricoCtx(ctx context.Context, item Item, inicio, fim time.Time, fn func(RegistroHistorico) error) error {
+	tipo, err := strconv.Atoi(item.TipoValor)
+	if err != nil {
+		return fmt.Errorf("tipo de informação inválido no item %s: %q", item.ID, item.TipoValor)
+	}
+
+	return PercorrerCtx(ctx, c, "history.get", ParamsHistoryGet{
+		Output:    SaidaCompleta,
+		History:   tipo,
+		ItemIDs:   []string{item.ID},
+		TimeFrom:  inicio.Unix(),
+		TimeTill:  fim.Unix(),
+		SortField: []string{"clock"},
+		SortOrder: "ASC",
+	}, fn)
+}
+
+// ObterUltimosValores retorna os limite valores mais recentes do item, do mais
+// recente ao mais antigo. Serve para itens de texto, que não formam séries.
+func (c *ClienteAPI) ObterUltimosValores(item Item, limite int) ([]RegistroHistorico, error) {
+	return c.ObterUltimosValoresCtx(context.Background(), item, limite)
+}
+
+// ObterUltimosValoresCtx é a variante de ObterUltimosValores que aceita um contexto
+func (c *ClienteAPI) ObterUltimosValoresCtx(ctx context.Context, item Item, limite int) ([]RegistroHistorico, error) {
+	tipo, err := strconv.Atoi(item.TipoValor)
+	if err != nil {
+		return nil, fmt.Errorf("tipo de informação inválido no item %s: %q", item.ID, item.TipoValor)
+	}
+
+	return ChamarCtx[[]RegistroHistorico](ctx, c, "history.get", ParamsHistoryGet{
+		Output:    SaidaCompleta,
+		History:   tipo,
+		ItemIDs:   []string{item.ID},
+		SortField: []string{"clock"},
+		SortOrder: "DESC",
+		Limit:     limite,
+	})
+}
+
+// PercorrerTendencias chama fn para cada resumo horário do item entre inicio e fim
+func (c *ClienteAPI) PercorrerTendencias(item Item, inicio, fim time.Time, fn func(RegistroTendencia) error) error {
+	return c.PercorrerTendenciasCtx(context.Background(), item, inicio, fim, fn)
+}
+
+// PercorrerTendenciasCtx é a variante de PercorrerTendencias que aceita um contexto
+func (c *ClienteAPI) PercorrerTendenciasCtx(ctx context.Context, item Item, inicio, fim time.Time, fn func(RegistroTendencia) error) error {
+	if !item.Numerico() {
+		return fmt.Errorf("o item %s não é numérico e não tem tendências", item.ID)
+	}
+
+	return PercorrerCtx(ctx, c, "trend.get", ParamsTrendGet{
+		Output:   []string{"itemid", "clock", "num", "value_min", "value_avg", "value_max"},
+		ItemIDs:  []string{item.ID},
+		TimeFrom: inicio.Unix(),
+		TimeTill: fim.Unix(),
+	}, fn)
+}
+
+// ObterSerie retorna os valores de um item numérico entre inicio e fim. Até
+// PeriodoMaximoHistorico os pontos vêm do histórico; acima disso, das tendências.
+// As leituras param nos limites de valores, descartando o início do período.
+func (c *ClienteAPI) ObterSerie(item Item, inicio, fim time.Time) (Serie, error) {
+	return c.ObterSerieCtx(context.Background(), item, inicio, fim)
+}
+
+// ObterSerieCtx é a variante de ObterSerie que aceita um contexto
+func (c *ClienteAPI) ObterSerieCtx(ctx context.Context, item Item, inicio, fim time.Time) (Serie, error) {
+	if !item.Numerico() {
+		return Serie{}, fmt.Errorf("o item %s não é numérico", item.ID)
+	}
+
+	var serie Serie
+	if fim.Sub(inicio) <= PeriodoMaximoHistorico {
+		tipo, err := strconv.Atoi(item.TipoValor)
+		if err != nil {
+			return Serie{}, fmt.Errorf("tipo de informação inválido no item %s: %q", item.ID, item.TipoValor)
+		}
+
+		// Do mais recente ao mais antigo, para que o limite corte o início do período
+		lidos := 0
+		err = PercorrerCtx(ctx, c, "history.get", ParamsHistoryGet{
+			Output:    SaidaCompleta,
+			History:   tipo,
+			ItemIDs:   []string{item.ID},
+			TimeFrom:  inicio.Unix(),
+			TimeTill:  fim.Unix(),
+			SortField: []string{"clock"},
+			SortOrder: "DESC",
+			Limit:     LimiteValoresHistorico,
+		}, func(r RegistroHistorico) error {
+			lidos++
+			valor, err := strconv.ParseFloat(r.Valor, 64)
+			if err != nil {
+				return nil
+			}
+			serie.Pontos = append(serie.Pontos, PontoSerie{
+				Momento: r.Clock.Time,
+				Minimo:  valor,
+				Media:   valor,
+				Maximo:  valor,
+			})
+			return nil
+		})
+		serie.Truncada = lidos >= LimiteValoresHistorico
+		for i, j := 0, len(serie.Pontos)-1; i < j; i, j = i+1, j-1 {
+			serie.Pontos[i], serie.Pontos[j] = serie.Pontos[j], serie.Pontos[i]
+		}
+		return serie, err
+	}
+
+	// Sem ordenação, o limite não escolheria quais horas ficam de fora; o
+	// período é encurtado antes para caber nele
+	serie.Tendencias = true
+	if horasMaximas := time.Duration(LimiteValoresTendencias) * time.Hour; fim.Sub(inicio) > horasMaximas {
+		inicio = fim.Add(-horasMaximas)
+		serie.Truncada = true
+	}
+	err := PercorrerCtx(ctx, c, "trend.get", ParamsTrendGet{
+		Output:   []string{"itemid", "clock", "num", "value_min", "value_avg", "value_max"},
+		ItemIDs:  []string{item.ID},
+		TimeFrom: inicio.Unix(),
+		TimeTill: fim.Unix(),
+		Limit:    LimiteValoresTendencias + 1, // As duas pontas do período entram
+	}, func(r RegistroTendencia) error {
+		minimo, errMin := strconv.ParseFloat(r.Minimo, 64)
+		media, errMedia := strconv.ParseFloat(r.Media, 64)
+		maximo, errMax := strconv.ParseFloat(r.Maximo, 64)
+		if errMin != nil || errMedia != nil || errMax != nil {
+			return nil
+		}
+		serie.Pontos = append(serie.Pontos, PontoSerie{
+			Momento: r.Clock.Time,
+			Minimo:  minimo,
+			Media:   media,
+			Maximo:  maximo,
+		})
+		return nil
+	})
+
+	// trend.get não aceita ordenação; as horas chegam em ordem de armazenamento
+	sort.Slice(serie.Pontos, func(i, j int) bool {
+		return serie.Pontos[i].Momento.Before(serie.Pontos[j].Momento)
+	})
+	return serie, err
+}
@@ -0,0 +1,280 @@
+package zabbix
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TamanhoPaginaHostsPadrao é a quantidade de hosts pedida em cada host.get
+const TamanhoPaginaHostsPadrao = 500
+
+// ResumoHost é a forma compacta de um host usada nas listagens: itens e
+// triggers são apenas contados pelo servidor (selectItems/selectTriggers "count")
+type ResumoHost struct {
+	ID             string              `json:"hostid"`
+	Nome           string              `json:"host"`
+	NomeVisivel    string              `json:"name"`
+	Status         string              `json:"status"`
+	Manutencao     string              `json:"maintenance_status"` // "1" enquanto houver manutenção em vigor
+	TipoManutencao string              `json:"maintenance_type"`
+	TotalItems     int                 `json:"items,string"`
+	TotalTriggers  int                 `json:"triggers,string"`
+	Interfaces     []Interface         `json:"interfaces"`
+	Grupos         []GrupoHost         `json:"hostgroups"`
+	Templates      []TemplateVinculado `json:"parentTemplates"`
+	Tags           []Tag               `json:"tags"`
+}
+
+// UnmarshalJSON aceita os grupos em "groups", nome usado antes do Zabbix 6.2
+func (h *ResumoHost) UnmarshalJSON(dados []byte) error {
+	type resumoJSON ResumoHost
+	var bruto struct {
+		resumoJSON
+		GruposAntigos []GrupoHost `json:"groups"`
+	}
+	if err := json.Unmarshal(dados, &bruto); err != nil {
+		return err
+	}
+
+	*h = ResumoHost(bruto.resumoJSON)
+	if h.Grupos == nil {
+		h.Grupos = bruto.GruposAntigos
+	}
+	return nil
+}
+
+// EmManutencao informa se o host está em uma manutenção em vigor
+func (h ResumoHost) EmManutencao() bool {
+	return h.Manutencao == "1"
+}
+
+// NomeExibicao retorna o nome visível do host ou, se vazio, o nome técnico
+func (h ResumoHost) NomeExibicao() string {
+	if h.NomeVisivel != "" {
+		return h.NomeVisivel
+	}
+	return h.Nome
+}
+
+// CorrespondeBusca informa se o termo aparece no ID, nos nomes, nos endereços
+// das interfaces, nos grupos, nos templates ou nas tags do host
+func (h ResumoHost) CorrespondeBusca(termo string) bool {
+	termo = strings.ToLower(termo)
+	contem := func(texto string) bool {
+		return strings.Contains(strings.ToLower(texto), termo)
+	}
+
+	if contem(h.ID) || contem(h.Nome) || contem(h.NomeVisivel) {
+		return true
+	}
+	for _, i := range h.Interfaces {
+		if contem(i.IP) || contem(i.DNS) {
+			return true
+		}
+	}
+	for _, g := range h.Grupos {
+		if contem(g.Nome) {
+			return true
+		}
+	}
+	for _, t := range h.Templates {
+		if contem(t.Nome) {
+			return true
+		}
+	}
+	for _, t := range h.Tags {
+		if contem(t.String()) {
+			return true
+		}
+	}
+	return false
+}
+
+// FiltroHosts restringe os hosts de uma listagem. Os filtros são aplicados
+// pelo servidor através de groupids, templateids e tags.
+type FiltroHosts struct {
+	GrupoIDs    []string
+	TemplateIDs []string
+	Tags        []FiltroTag
+}
+
+// Aplicar copia o filtro para os parâmetros de host.get
+func (f FiltroHosts) Aplicar(params *ParamsHostGet) {
+	params.GroupIDs = f.GrupoIDs
+	params.TemplateIDs = f.TemplateIDs
+	params.Tags = f.Tags
+}
+
+// ParseFiltroTags interpreta tags no formato "nome=valor" ou apenas "nome",
+// separadas por vírgula. Com valor, a comparação é exata; sem valor, basta a
+// tag existir.
+func ParseFiltroTags(texto string) []FiltroTag {
+	var filtros []FiltroTag
+	for _, parte := range strings.Split(texto, ",") {
+		parte = strings.TrimSpace(parte)
+		if parte == "" {
+			continue
+		}
+		nome, valor, temValor := strings.Cut(parte, "=")
+		filtro := FiltroTag{Tag: strings.TrimSpace(nome), Operator: OperadorTagContem}
+		if temValor {
+			filtro.Value = strings.TrimSpace(valor)
+			filtro.Operator = OperadorTagIgual
+		}
+		filtros = append(filtros, filtro)
+	}
+	return filtros
+}
+
+// PercorrerHosts chama fn para cada host, buscando-os em páginas de
+// tamanhoPagina hosts. Os parâmetros filtram e definem as propriedades
+// retornadas; HostIDs, SortField e Limit de cada página são definidos aqui.
+func (c *ClienteAPI) PercorrerHosts(params ParamsHostGet, tamanhoPagina int, fn func(Host) error) error {
+	return c.PercorrerHostsCtx(context.Background(), params, tamanhoPagina, fn)
+}
+
+// PercorrerHostsCtx é a variante de PercorrerHosts que aceita um contexto
+func (c *ClienteAPI) PercorrerHostsCtx(ctx context.Context, params ParamsHostGet, tamanhoPagina int, fn func(Host) error) error {
+	return percorrerHosts(ctx, c, params, tamanhoPagina, fn)
+}
+
+// PercorrerResumosHosts chama fn para o resumo de cada host que atende ao
+// filtro, com a contagem de itens e triggers
+func (c *ClienteAPI) PercorrerResumosHosts(filtro FiltroHosts, tamanhoPagina int, fn func(ResumoHost) error) error {
+	return c.PercorrerResumosHostsCtx(context.Background(), filtro, tamanhoPagina, fn)
+}
+
+// PercorrerResumosHostsCtx é a variante de PercorrerResumosHosts que aceita um contexto
+func (c *ClienteAPI) PercorrerResumosHostsCtx(ctx context.Context, filtro FiltroHosts, tamanhoPagina int, fn func(ResumoHost) error) error {
+	params := ParamsHostGet{
+		Output:                []string{"hostid", "host", "name", "status", "maintenance_status", "maintenance_type"},
+		SelectItems:           "count",
+		SelectTriggers:        "count",
+		SelectInterfaces:      []string{"interfaceid", "type", "main", "ip", "dns", "port"},
+		SelectHostGroups:      []string{"groupid", "name"},
+		SelectParentTemplates: []string{"templateid", "name"},
+		SelectTags:            []string{"tag", "value"},
+	}
+	filtro.Aplicar(&params)
+	return percorrerHosts(ctx, c, params, tamanhoPagina, fn)
+}
+
+// ObterGruposETemplates retorna os grupos de hosts e os templates, ordenados
+// pelo nome, para montar os filtros da listagem de hosts
+func (c *ClienteAPI) ObterGruposETemplates() ([]GrupoHost, []TemplateVinculado, error) {
+	return c.ObterGruposETemplatesCtx(context.Background())
+}
+
+// ObterGruposETemplatesCtx é a variante de ObterGruposETemplates que aceita um contexto
+func (c *ClienteAPI) ObterGruposETemplatesCtx(ctx context.Context) ([]GrupoHost, []TemplateVinculado, error) {
+	lote := c.NovoLote()
+	resultadoGrupos := AdicionarAoLote[[]GrupoHost](lote, "hostgroup.get", ParamsHostGroupGet{
+		Output:    []string{"groupid", "name"},
+		SortField: []string{"name"},
+	})
+	resultadoTemplates := AdicionarAoLote[[]TemplateVinculado](lote, "template.get", ParamsTemplateGet{
+		Output:    []string{"templateid", "name"},
+		SortField: []string{"name"},
+	})
+	if err := lote.ExecutarCtx(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	grupos, err := resultadoGrupos.Obter()
+	if err != nil {
+		return nil, nil, err
+	}
+	templates, err := resultadoTemplates.Obter()
+	if err != nil {
+		return nil, nil, err
+	}
+	return grupos, templates, nil
+}
+
+// percorrerHosts lista primeiro apenas os IDs dos hosts, em ordem, e depois
+// pede os dados completos em faixas consecutivas desses IDs. Assim nenhuma
+// resposta do servidor precisa conter todos os hosts com itens e triggers.
+func percorrerHosts[R any](ctx context.Context, c *ClienteAPI, params ParamsHostGet, tamanhoPagina int, fn func(R) error) error {
+	if tamanhoPagina <= 0 {
+		tamanhoPagina = TamanhoPaginaHostsPadrao
+	}
+
+	// selectHostGroups substituiu selectGroups no Zabbix 6.2
+	if params.SelectHostGroups != nil {
+		versao, err := c.ObterVersaoCtx(ctx)
+		if err != nil {
+			return fmt.Errorf("erro ao negociar versão da API: %w", err)
+		}
+		if !versao.AoMenos(6, 2) {
+			params.SelectGroups = params.SelectHostGroups
+			params.SelectHostGroups = nil
+		}
+	}
+
+	ids, err := c.listarIDsHosts(ctx, params)
+	if err != nil {
+		return err
+	}
+
+	for inicio := 0; inicio < len(ids); inicio += tamanhoPagina {
+		fim := inicio + tamanhoPagina
+		if fim > len(ids) {
+			fim = len(ids)
+		}
+
+		pagina := params
+		pagina.HostIDs = ids[inicio:fim]
+		pagina.SortField = []string{"hostid"}
+		pagina.Limit = fim - inicio
+
+		// As páginas não passam pelo cache: gravá-las manteria o conjunto
+		// completo de hosts em memória, que é o que a paginação evita
+		if err := percorrer(ctx, c, "host.get", pagina, fn, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// listarIDsHosts retorna os IDs dos hosts que atendem aos filtros, em ordem numérica
+func (c *ClienteAPI) listarIDsHosts(ctx context.Context, params ParamsHostGet) ([]string, error) {
+	params.Output = []string{"hostid"}
+	params.SelectItems = nil
+	params.SelectTriggers = nil
+	params.SelectInterfaces = nil
+	params.SelectHostGroups = nil
+	params.SelectGroups = nil
+	params.SelectParentTemplates = nil
+	params.SelectTags = nil
+	params.SelectMacros = nil
+	params.SortField = []string{"hostid"}
+
+	var ids []string
+	err := PercorrerCtx(ctx, c, "host.get", params, func(h struct {
+		ID string `json:"hostid"`
+	}) error {
+		ids = append(ids, h.ID)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// A ordem do servidor não é garantida em todos os bancos
+	ordenarIDs(ids)
+	return ids, nil
+}
+
+// ordenarIDs ordena IDs da API, que são números em texto, pelo valor numérico
+func ordenarIDs(ids []string) {
+	sort.Slice(ids, func(i, j int) bool {
+		a, _ := strconv.ParseUint(ids[i], 10, 64)
+		b, _ := strconv.ParseUint(ids[j], 10, 64)
+		return a < b
+	})
+}
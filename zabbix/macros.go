@@ -0,0 +1,611 @@
+package zabbix
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Tipos de macro de usuário (type), disponíveis a partir do Zabbix 5.0
+const (
+	TipoMacroTexto   = "0"
+	TipoMacroSecreta = "1"
+	TipoMacroVault   = "2" // Zabbix 5.2 ou posterior
+)
+
+// MascaraMacroSecreta é exibida e registrada no lugar do valor de macros secretas
+const MascaraMacroSecreta = "******"
+
+// Níveis da cadeia de herança de uma macro, do que prevalece ao mais geral
+const (
+	NivelMacroHost     = "host"
+	NivelMacroTemplate = "template"
+	NivelMacroGlobal   = "global"
+)
+
+// Ações da alteração em massa de macros
+const (
+	AcaoMacroDefinir      = "definir"      // Cria ou altera a macro em cada objeto
+	AcaoMacroSobrescrever = "sobrescrever" // Cria só onde o valor ainda é herdado
+	AcaoMacroRemover      = "remover"      // Remove do objeto, que volta a herdar o valor
+)
+
+// MacroUsuario é uma macro de usuário de um host, de um template ou global.
+// A API não retorna o valor de macros secretas; ainda assim ele nunca é
+// exibido nem serializado, para não vazar em registros e exportações.
+type MacroUsuario struct {
+	ID        string `json:"hostmacroid,omitempty"`
+	IDGlobal  string `json:"globalmacroid,omitempty"`
+	HostID    string `json:"hostid,omitempty"` // Host ou template; vazio nas globais
+	Macro     string `json:"macro"`
+	Valor     string `json:"value"`
+	Descricao string `json:"description"`
+	Tipo      string `json:"type"` // Ausente antes do Zabbix 5.0
+}
+
+// Secreta informa se o valor da macro é secreto
+func (m MacroUsuario) Secreta() bool {
+	return m.Tipo == TipoMacroSecreta
+}
+
+// ValorExibido retorna o valor da macro, mascarado nas secretas
+func (m MacroUsuario) ValorExibido() string {
+	if m.Secreta() {
+		return MascaraMacroSecreta
+	}
+	return m.Valor
+}
+
+// NomeTipo retorna o nome do tipo da macro, texto quando o servidor não o informa
+func (m MacroUsuario) NomeTipo() string {
+	if m.Tipo == "" {
+		return NomesTiposMacro[TipoMacroTexto]
+	}
+	return NomesTiposMacro[m.Tipo]
+}
+
+// String descreve a macro com o valor mascarado, para mensagens e logs
+func (m MacroUsuario) String() string {
+	return fmt.Sprintf("%s = %q", m.Macro, m.ValorExibido())
+}
+
+// MarshalJSON serializa a macro com o valor mascarado nas secretas
+func (m MacroUsuario) MarshalJSON() ([]byte, error) {
+	type macroJSON MacroUsuario
+	copia := macroJSON(m)
+	copia.Valor = m.ValorExibido()
+	return json.Marshal(copia)
+}
+
+// MacroValida confere se o nome está no formato {$NOME}, com contexto opcional
+// como em {$NOME:"contexto"}
+func MacroValida(macro string) bool {
+	return len(macro) > 3 && strings.HasPrefix(macro, "{$") && strings.HasSuffix(macro, "}")
+}
+
+// ObterMacros retorna as macros de usuário dos hosts ou templates informados.
+// Com macro, retorna apenas as que têm exatamente esse nome.
+func (c *ClienteAPI) ObterMacros(hostIDs []string, macro string) ([]MacroUsuario, error) {
+	return c.ObterMacrosCtx(context.Background(), hostIDs, macro)
+}
+
+// ObterMacrosCtx é a variante de ObterMacros que aceita um contexto
+func (c *ClienteAPI) ObterMacrosCtx(ctx context.Context, hostIDs []string, macro string) ([]MacroUsuario, error) {
+	// Sem hostids, usermacro.get retornaria as macros de todos os hosts
+	if len(hostIDs) == 0 {
+		return nil, nil
+	}
+	params := ParamsUserMacroGet{
+		Output:    "extend",
+		HostIDs:   hostIDs,
+		SortField: []string{"macro"},
+	}
+	if macro != "" {
+		params.Filter = map[string]string{"macro": macro}
+	}
+	return ChamarCtx[[]MacroUsuario](ctx, c, "usermacro.get", params)
+}
+
+// CriarMacros cria macros em hosts ou templates com usermacro.create, em uma
+// única chamada, e retorna os IDs criados
+func (c *ClienteAPI) CriarMacros(macros []ParamsUserMacro) ([]string, error) {
+	return c.CriarMacrosCtx(context.Background(), macros)
+}
+
+// CriarMacrosCtx é a variante de CriarMacros que aceita um contexto
+func (c *ClienteAPI) CriarMacrosCtx(ctx context.Context, macros []ParamsUserMacro) ([]string, error) {
+	resultado, err := ChamarCtx[struct {
+		IDs []string `json:"hostmacroids"`
+	}](ctx, c, "usermacro.create", macros)
+	if err != nil {
+		return nil, err
+	}
+	return resultado.IDs, nil
+}
+
+// AtualizarMacros altera macros de hosts ou templates com usermacro.update
+func (c *ClienteAPI) AtualizarMacros(macros []ParamsUserMacro) error {
+	return c.AtualizarMacrosCtx(context.Background(), macros)
+}
+
+// AtualizarMacrosCtx é a variante de AtualizarMacros que aceita um contexto
+func (c *ClienteAPI) AtualizarMacrosCtx(ctx context.Context, macros []ParamsUserMacro) error {
+	_, err := ChamarCtx[json.RawMessage](ctx, c, "usermacro.update", macros)
+	return err
+}
+
+// RemoverMacros remove macros de hosts ou templates pelos IDs
+func (c *ClienteAPI) RemoverMacros(ids []string) error {
+	return c.RemoverMacrosCtx(context.Background(), ids)
+}
+
+// RemoverMacrosCtx é a variante de RemoverMacros que aceita um contexto
+func (c *ClienteAPI) RemoverMacrosCtx(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return errors.New("nenhuma macro informada")
+	}
+	// usermacro.delete recebe a lista de IDs diretamente, sem objeto
+	_, err := ChamarCtx[json.RawMessage](ctx, c, "usermacro.delete", ids)
+	return err
+}
+
+// ObterMacrosGlobais retorna as macros globais, ordenadas pelo nome
+func (c *ClienteAPI) ObterMacrosGlobais() ([]MacroUsuario, error) {
+	return c.ObterMacrosGlobaisCtx(context.Background())
+}
+
+// ObterMacrosGlobaisCtx é a variante de ObterMacrosGlobais que aceita um contexto
+func (c *ClienteAPI) ObterMacrosGlobaisCtx(ctx context.Context) ([]MacroUsuario, error) {
+	return ChamarCtx[[]MacroUsuario](ctx, c, "usermacro.get", ParamsUserMacroGet{
+		Output:      "extend",
+		GlobalMacro: true,
+		SortField:   []string{"macro"},
+	})
+}
+
+// SalvarMacroGlobal cria a macro global com usermacro.createglobal ou, quando
+// ela tem IDGlobal, altera a existente com usermacro.updateglobal. Ao alterar
+// uma macro secreta, valor vazio mantém o valor atual. Retorna o ID da macro.
+func (c *ClienteAPI) SalvarMacroGlobal(macro MacroUsuario) (string, error) {
+	return c.SalvarMacroGlobalCtx(context.Background(), macro)
+}
+
+// SalvarMacroGlobalCtx é a variante de SalvarMacroGlobal que aceita um contexto
+func (c *ClienteAPI) SalvarMacroGlobalCtx(ctx context.Context, macro MacroUsuario) (string, error) {
+	if !MacroValida(macro.Macro) {
+		return "", fmt.Errorf("nome de macro inválido %q (use o formato {$NOME})", macro.Macro)
+	}
+	tipo, err := c.tipoMacroParaServidor(ctx, macro.Tipo)
+	if err != nil {
+		return "", err
+	}
+
+	params := ParamsUserMacro{
+		Macro:       macro.Macro,
+		Value:       &macro.Valor,
+		Description: &macro.Descricao,
+		Type:        tipo,
+	}
+	if macro.IDGlobal == "" {
+		resultado, err := ChamarCtx[struct {
+			IDs []string `json:"globalmacroids"`
+		}](ctx, c, "usermacro.createglobal", params)
+		if err != nil {
+			return "", err
+		}
+		if len(resultado.IDs) == 0 {
+			return "", errors.New("usermacro.createglobal não retornou o ID da macro")
+		}
+		return resultado.IDs[0], nil
+	}
+
+	params.GlobalMacroID = macro.IDGlobal
+	if macro.Secreta() && macro.Valor == "" {
+		params.Value = nil
+	}
+	_, err = ChamarCtx[json.RawMessage](ctx, c, "usermacro.updateglobal", params)
+	return macro.IDGlobal, err
+}
+
+// RemoverMacrosGlobais remove macros globais pelos IDs
+func (c *ClienteAPI) RemoverMacrosGlobais(ids []string) error {
+	return c.RemoverMacrosGlobaisCtx(context.Background(), ids)
+}
+
+// RemoverMacrosGlobaisCtx é a variante de RemoverMacrosGlobais que aceita um contexto
+func (c *ClienteAPI) RemoverMacrosGlobaisCtx(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return errors.New("nenhuma macro informada")
+	}
+	_, err := ChamarCtx[json.RawMessage](ctx, c, "usermacro.deleteglobal", ids)
+	return err
+}
+
+// tipoMacroParaServidor confere se o servidor aceita o tipo de macro e o
+// retorna como deve ser enviado. Antes do Zabbix 5.0 não há tipos e o campo
+// não pode ser enviado; depois, vazio vira texto.
+func (c *ClienteAPI) tipoMacroParaServidor(ctx context.Context, tipo string) (string, error) {
+	if _, ok := NomesTiposMacro[tipo]; tipo != "" && !ok {
+		return "", fmt.Errorf("tipo de macro inválido: %q", tipo)
+	}
+	versao, err := c.ObterVersaoCtx(ctx)
+	if err != nil {
+		return "", fmt.Errorf("erro ao negociar versão da API: %w", err)
+	}
+	switch {
+	case !versao.AoMenos(5, 0):
+		if tipo != "" && tipo != TipoMacroTexto {
+			return "", fmt.Errorf("macros do tipo %s exigem Zabbix 5.0 ou posterior", NomesTiposMacro[tipo])
+		}
+		return "", nil
+	case tipo == TipoMacroVault && !versao.AoMenos(5, 2):
+		return "", errors.New("macros do tipo vault exigem Zabbix 5.2 ou posterior")
+	case tipo == "":
+		return TipoMacroTexto, nil
+	}
+	return tipo, nil
+}
+
+// DefinicaoMacro é a macro em um nível da cadeia de herança
+type DefinicaoMacro struct {
+	Nivel  string // NivelMacroHost, NivelMacroTemplate ou NivelMacroGlobal
+	Origem string // Nome do host ou do template; vazio no nível global
+	Macro  MacroUsuario
+}
+
+// DescricaoOrigem descreve o nível e o objeto que definem a macro, como
+// "template Linux by Zabbix agent"
+func (d DefinicaoMacro) DescricaoOrigem() string {
+	if d.Origem == "" {
+		return d.Nivel
+	}
+	return d.Nivel + " " + d.Origem
+}
+
+// MacroEfetiva é a cadeia de herança de uma macro em um host, na ordem em que
+// o Zabbix a resolve: o próprio host, os templates vinculados a ele, os
+// templates desses templates e, por fim, a macro global. A primeira definição
+// é a que vale; as demais estão sobrescritas.
+type MacroEfetiva struct {
+	HostID string
+	Host   string
+	Cadeia []DefinicaoMacro
+}
+
+// Definida informa se a macro tem valor no host em algum nível
+func (m MacroEfetiva) Definida() bool {
+	return len(m.Cadeia) > 0
+}
+
+// Efetiva retorna a definição que vale para o host; só deve ser usada quando
+// a macro está definida
+func (m MacroEfetiva) Efetiva() DefinicaoMacro {
+	if len(m.Cadeia) == 0 {
+		return DefinicaoMacro{}
+	}
+	return m.Cadeia[0]
+}
+
+// NoHost informa se o próprio host define a macro
+func (m MacroEfetiva) NoHost() bool {
+	return m.Definida() && m.Cadeia[0].Nivel == NivelMacroHost
+}
+
+// templateHeranca é um template com os templates vinculados a ele
+type templateHeranca struct {
+	ID   string              `json:"templateid"`
+	Nome string              `json:"name"`
+	Pais []TemplateVinculado `json:"parentTemplates"`
+}
+
+// ResolverMacro calcula, para cada host do filtro, a cadeia de herança da
+// macro e o valor efetivo, como o servidor resolve a macro em itens e
+// triggers. Os hosts são retornados em ordem alfabética.
+func (c *ClienteAPI) ResolverMacro(macro string, filtro FiltroHosts, tamanhoPagina int) ([]MacroEfetiva, error) {
+	return c.ResolverMacroCtx(context.Background(), macro, filtro, tamanhoPagina)
+}
+
+// ResolverMacroCtx é a variante de ResolverMacro que aceita um contexto
+func (c *ClienteAPI) ResolverMacroCtx(ctx context.Context, macro string, filtro FiltroHosts, tamanhoPagina int) ([]MacroEfetiva, error) {
+	if !MacroValida(macro) {
+		return nil, fmt.Errorf("nome de macro inválido %q (use o formato {$NOME})", macro)
+	}
+	if tamanhoPagina <= 0 {
+		tamanhoPagina = TamanhoPaginaHostsPadrao
+	}
+
+	params := ParamsHostGet{
+		Output:                []string{"hostid", "host", "name"},
+		SelectParentTemplates: []string{"templateid", "name"},
+	}
+	filtro.Aplicar(&params)
+	var hosts []Host
+	err := c.PercorrerHostsCtx(ctx, params, tamanhoPagina, func(h Host) error {
+		hosts = append(hosts, h)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	templates, err := c.herancaTemplates(ctx, hosts)
+	if err != nil {
+		return nil, err
+	}
+
+	donos := make([]string, 0, len(hosts)+len(templates))
+	for _, h := range hosts {
+		donos = append(donos, h.ID)
+	}
+	for id := range templates {
+		donos = append(donos, id)
+	}
+	porDono := make(map[string]MacroUsuario)
+	for inicio := 0; inicio < len(donos); inicio += tamanhoPagina {
+		fim := inicio + tamanhoPagina
+		if fim > len(donos) {
+			fim = len(donos)
+		}
+		macros, err := c.ObterMacrosCtx(ctx, donos[inicio:fim], macro)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range macros {
+			porDono[m.HostID] = m
+		}
+	}
+
+	globais, err := c.ObterMacrosGlobaisCtx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var global *MacroUsuario
+	for i := range globais {
+		if globais[i].Macro == macro {
+			global = &globais[i]
+		}
+	}
+
+	efetivas := make([]MacroEfetiva, 0, len(hosts))
+	for _, h := range hosts {
+		efetiva := MacroEfetiva{HostID: h.ID, Host: h.NomeExibicao()}
+		if m, ok := porDono[h.ID]; ok {
+			efetiva.Cadeia = append(efetiva.Cadeia, DefinicaoMacro{Nivel: NivelMacroHost, Origem: h.NomeExibicao(), Macro: m})
+		}
+		for _, id := range ordemHeranca(h.Templates, templates) {
+			if m, ok := porDono[id]; ok {
+				efetiva.Cadeia = append(efetiva.Cadeia, DefinicaoMacro{Nivel: NivelMacroTemplate, Origem: templates[id].Nome, Macro: m})
+			}
+		}
+		if global != nil {
+			efetiva.Cadeia = append(efetiva.Cadeia, DefinicaoMacro{Nivel: NivelMacroGlobal, Macro: *global})
+		}
+		efetivas = append(efetivas, efetiva)
+	}
+	sort.SliceStable(efetivas, func(i, j int) bool {
+		return strings.ToLower(efetivas[i].Host) < strings.ToLower(efetivas[j].Host)
+	})
+	return efetivas, nil
+}
+
+// herancaTemplates busca os templates vinculados aos hosts e, nível a nível,
+// os templates vinculados a eles, até o topo da herança
+func (c *ClienteAPI) herancaTemplates(ctx context.Context, hosts []Host) (map[string]templateHeranca, error) {
+	templates := make(map[string]templateHeranca)
+	pendentes := make(map[string]bool)
+	for _, h := range hosts {
+		for _, t := range h.Templates {
+			pendentes[t.ID] = true
+		}
+	}
+
+	for len(pendentes) > 0 {
+		ids := make([]string, 0, len(pendentes))
+		for id := range pendentes {
+			ids = append(ids, id)
+		}
+		pendentes = make(map[string]bool)
+
+		encontrados, err := ChamarCtx[[]templateHeranca](ctx, c, "template.get", ParamsTemplateGet{
+			Output:                []string{"templateid", "name"},
+			TemplateIDs:           ids,
+			SelectParentTemplates: []string{"templateid", "name"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range encontrados {
+			templates[t.ID] = t
+		}
+		for _, t := range encontrados {
+			for _, pai := range t.Pais {
+				if _, conhecido := templates[pai.ID]; !conhecido {
+					pendentes[pai.ID] = true
+				}
+			}
+		}
+	}
+	return templates, nil
+}
+
+// ordemHeranca lista os templates na ordem em que o Zabbix procura as macros:
+// primeiro os vinculados ao host, depois os vinculados a eles e assim por
+// diante, cada nível em ordem de ID. Um template já visto não se repete.
+func ordemHeranca(vinculados []TemplateVinculado, templates map[string]templateHeranca) []string {
+	var ordem []string
+	vistos := make(map[string]bool)
+
+	var nivel []string
+	for _, t := range vinculados {
+		nivel = append(nivel, t.ID)
+	}
+	for len(nivel) > 0 {
+		var atual []string
+		for _, id := range nivel {
+			if !vistos[id] {
+				vistos[id] = true
+				atual = append(atual, id)
+			}
+		}
+		ordenarIDs(atual)
+
+		nivel = nil
+		for _, id := range atual {
+			ordem = append(ordem, id)
+			for _, pai := range templates[id].Pais {
+				nivel = append(nivel, pai.ID)
+			}
+		}
+	}
+	return ordem
+}
+
+// AlteracaoMacro é uma alteração em massa de uma macro no próprio nível de
+// hosts ou templates selecionados
+type AlteracaoMacro struct {
+	Acao      string // AcaoMacroDefinir, AcaoMacroSobrescrever ou AcaoMacroRemover
+	Macro     string
+	Valor     string // Em macros secretas já existentes, vazio mantém o valor atual
+	Descricao string
+	Tipo      string // TipoMacroTexto, TipoMacroSecreta ou TipoMacroVault
+}
+
+// Validar confere a ação, o nome e o tipo da macro
+func (a AlteracaoMacro) Validar() error {
+	switch a.Acao {
+	case AcaoMacroDefinir, AcaoMacroSobrescrever, AcaoMacroRemover:
+	default:
+		return fmt.Errorf("ação inválida: %q", a.Acao)
+	}
+	if !MacroValida(a.Macro) {
+		return fmt.Errorf("nome de macro inválido %q (use o formato {$NOME})", a.Macro)
+	}
+	if _, ok := NomesTiposMacro[a.Tipo]; a.Tipo != "" && !ok {
+		return fmt.Errorf("tipo de macro inválido: %q", a.Tipo)
+	}
+	return nil
+}
+
+// detalhes descreve a alteração para a auditoria, com o valor mascarado nas
+// macros secretas
+func (a AlteracaoMacro) detalhes() map[string]interface{} {
+	macro := MacroUsuario{Macro: a.Macro, Valor: a.Valor, Tipo: a.Tipo}
+	detalhes := map[string]interface{}{"macro": a.Macro}
+	if a.Acao != AcaoMacroRemover {
+		detalhes["valor"] = macro.ValorExibido()
+		detalhes["tipo"] = macro.NomeTipo()
+		if a.Descricao != "" {
+			detalhes["descricao_macro"] = a.Descricao
+		}
+	}
+	return detalhes
+}
+
+// AplicarAlteracaoMacro aplica a alteração nos hosts ou templates informados
+// com usermacro.update, usermacro.create e usermacro.delete, uma chamada de
+// cada, na ordem. A primeira que falhar interrompe as seguintes; as etapas
+// retornadas são as que chegaram a ser enviadas.
+func (c *ClienteAPI) AplicarAlteracaoMacro(ids []string, alteracao AlteracaoMacro) ([]EtapaAlteracao, error) {
+	return c.AplicarAlteracaoMacroCtx(context.Background(), ids, alteracao)
+}
+
+// AplicarAlteracaoMacroCtx é a variante de AplicarAlteracaoMacro que aceita um contexto
+func (c *ClienteAPI) AplicarAlteracaoMacroCtx(ctx context.Context, ids []string, alteracao AlteracaoMacro) ([]EtapaAlteracao, error) {
+	if len(ids) == 0 {
+		return nil, errors.New("nenhum host selecionado")
+	}
+	if err := alteracao.Validar(); err != nil {
+		return nil, err
+	}
+	tipo := ""
+	if alteracao.Acao != AcaoMacroRemover {
+		var err error
+		if tipo, err = c.tipoMacroParaServidor(ctx, alteracao.Tipo); err != nil {
+			return nil, err
+		}
+	}
+
+	existentes, err := c.ObterMacrosCtx(ctx, ids, alteracao.Macro)
+	if err != nil {
+		return nil, err
+	}
+	porDono := make(map[string]MacroUsuario, len(existentes))
+	for _, m := range existentes {
+		porDono[m.HostID] = m
+	}
+
+	var criar, atualizar []ParamsUserMacro
+	var remover, hostsCriar, hostsAtualizar, hostsRemover []string
+	for _, id := range ids {
+		atual, existe := porDono[id]
+		switch {
+		case alteracao.Acao == AcaoMacroRemover:
+			if existe {
+				remover = append(remover, atual.ID)
+				hostsRemover = append(hostsRemover, id)
+			}
+		case !existe:
+			criar = append(criar, ParamsUserMacro{
+				HostID:      id,
+				Macro:       alteracao.Macro,
+				Value:       &alteracao.Valor,
+				Description: &alteracao.Descricao,
+				Type:        tipo,
+			})
+			hostsCriar = append(hostsCriar, id)
+		case alteracao.Acao == AcaoMacroDefinir:
+			params := ParamsUserMacro{
+				HostMacroID: atual.ID,
+				Value:       &alteracao.Valor,
+				Description: &alteracao.Descricao,
+				Type:        tipo,
+			}
+			if alteracao.Tipo == TipoMacroSecreta && atual.Secreta() && alteracao.Valor == "" {
+				params.Value = nil
+			}
+			atualizar = append(atualizar, params)
+			hostsAtualizar = append(hostsAtualizar, id)
+		}
+	}
+
+	var etapas []EtapaAlteracao
+	if len(atualizar) > 0 {
+		etapas = append(etapas, EtapaAlteracao{
+			Metodo:    "usermacro.update",
+			Descricao: "Alterar " + alteracao.Macro,
+			Hosts:     hostsAtualizar,
+			Detalhes:  alteracao.detalhes(),
+			params:    []interface{}{atualizar},
+		})
+	}
+	if len(criar) > 0 {
+		etapas = append(etapas, EtapaAlteracao{
+			Metodo:    "usermacro.create",
+			Descricao: "Definir " + alteracao.Macro,
+			Hosts:     hostsCriar,
+			Detalhes:  alteracao.detalhes(),
+			params:    []interface{}{criar},
+		})
+	}
+	if len(remover) > 0 {
+		etapas = append(etapas, EtapaAlteracao{
+			Metodo:    "usermacro.delete",
+			Descricao: "Remover " + alteracao.Macro,
+			Hosts:     hostsRemover,
+			Detalhes:  alteracao.detalhes(),
+			params:    []interface{}{remover},
+		})
+	}
+
+	for i := range etapas {
+		etapa := &etapas[i]
+		etapa.Err = c.executarEtapa(ctx, *etapa)
+		if etapa.Err != nil {
+			return etapas[:i+1], fmt.Errorf("%s: %w", etapa.Descricao, etapa.Err)
+		}
+	}
+	return etapas, nil
+}
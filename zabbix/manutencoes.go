@@ -0,0 +1,360 @@
+package zabbix
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Tipos de manutenção (maintenance_type)
+const (
+	ManutencaoComColeta = "0"
+	ManutencaoSemColeta = "1"
+)
+
+// Tipos de período de manutenção (timeperiod_type)
+const (
+	PeriodoUnico   = "0"
+	PeriodoDiario  = "2"
+	PeriodoSemanal = "3"
+	PeriodoMensal  = "4"
+)
+
+// Estados de uma manutenção em relação à sua vigência
+const (
+	ManutencaoProxima  = "proxima"
+	ManutencaoAtiva    = "ativa"
+	ManutencaoExpirada = "expirada"
+)
+
+// DiasSemana são os nomes dos dias usados em dayofweek, do bit 1 (segunda) ao 64 (domingo)
+var DiasSemana = []string{"Seg", "Ter", "Qua", "Qui", "Sex", "Sáb", "Dom"}
+
+// Manutencao é uma janela de manutenção. Hosts e grupos dentro dela não geram
+// alertas e, sem coleta, também não recebem dados.
+type Manutencao struct {
+	ID         string              `json:"maintenanceid"`
+	Nome       string              `json:"name"`
+	Tipo       string              `json:"maintenance_type"`
+	Descricao  string              `json:"description"`
+	AtivaDesde Instante            `json:"active_since"`
+	AtivaAte   Instante            `json:"active_till"`
+	Periodos   []PeriodoManutencao `json:"timeperiods"`
+	Hosts      []Host              `json:"hosts"`
+	Grupos     []GrupoHost         `json:"hostgroups"`
+}
+
+// UnmarshalJSON aceita os grupos em "groups", nome usado antes do Zabbix 6.2
+func (m *Manutencao) UnmarshalJSON(dados []byte) error {
+	type manutencaoJSON Manutencao
+	var bruto struct {
+		manutencaoJSON
+		GruposAntigos []GrupoHost `json:"groups"`
+	}
+	if err := json.Unmarshal(dados, &bruto); err != nil {
+		return err
+	}
+
+	*m = Manutencao(bruto.manutencaoJSON)
+	if m.Grupos == nil {
+		m.Grupos = bruto.GruposAntigos
+	}
+	return nil
+}
+
+// Estado informa se a vigência da manutenção ainda não começou, está em curso
+// ou já terminou, como na lista de manutenções do frontend
+func (m Manutencao) Estado(agora time.Time) string {
+	switch {
+	case agora.Before(m.AtivaDesde.Time):
+		return ManutencaoProxima
+	case agora.Before(m.AtivaAte.Time):
+		return ManutencaoAtiva
+	default:
+		return ManutencaoExpirada
+	}
+}
+
+// PeriodoManutencao é um período de uma manutenção: uma janela única ou uma
+// repetição diária, semanal ou mensal dentro da vigência
+type PeriodoManutencao struct {
+	Tipo       string   `json:"timeperiod_type"`
+	ACada      string   `json:"every"`      // A cada N dias ou semanas; semana do mês em períodos mensais
+	Meses      string   `json:"month"`      // Soma dos meses: 1 janeiro ... 2048 dezembro
+	DiasSemana string   `json:"dayofweek"`  // Soma dos dias: 1 segunda ... 64 domingo
+	Dia        string   `json:"day"`        // Dia do mês
+	HoraInicio string   `json:"start_time"` // Segundos desde a meia-noite
+	Duracao    string   `json:"period"`     // Segundos
+	DataInicio Instante `json:"start_date"` // Apenas em períodos únicos
+}
+
+// Descricao resume o período para exibição, ex: "Semanal (Seg, Qua) às 22:00 por 2h"
+func (p PeriodoManutencao) Descricao() string {
+	duracao, _ := strconv.Atoi(p.Duracao)
+	inicio, _ := strconv.Atoi(p.HoraInicio)
+	hora := fmt.Sprintf("%02d:%02d", inicio/3600, inicio%3600/60)
+	porTempo := "por " + FormatarDuracao(time.Duration(duracao)*time.Second)
+
+	switch p.Tipo {
+	case PeriodoUnico:
+		return fmt.Sprintf("Única em %s %s", p.DataInicio.Format("02/01/2006 15:04"), porTempo)
+	case PeriodoDiario:
+		return fmt.Sprintf("Diária (a cada %s dia(s)) às %s %s", p.ACada, hora, porTempo)
+	case PeriodoSemanal:
+		dias, _ := strconv.Atoi(p.DiasSemana)
+		var nomes []string
+		for i, nome := range DiasSemana {
+			if dias&(1<<i) != 0 {
+				nomes = append(nomes, nome)
+			}
+		}
+		return fmt.Sprintf("Semanal (%s, a cada %s semana(s)) às %s %s", strings.Join(nomes, ", "), p.ACada, hora, porTempo)
+	case PeriodoMensal:
+		return fmt.Sprintf("Mensal às %s %s", hora, porTempo)
+	}
+	return "Período desconhecido"
+}
+
+// ManutencaoUnica monta uma manutenção de uma única janela que começa em
+// inicio e dura duracao, para os hosts e grupos informados
+func ManutencaoUnica(nome string, inicio time.Time, duracao time.Duration, comColeta bool, hostIDs, grupoIDs []string) Manutencao {
+	m := Manutencao{
+		Nome:       nome,
+		Tipo:       ManutencaoSemColeta,
+		AtivaDesde: NovoInstante(inicio.Unix()),
+		AtivaAte:   NovoInstante(inicio.Add(duracao).Unix()),
+		Periodos: []PeriodoManutencao{{
+			Tipo:       PeriodoUnico,
+			Duracao:    strconv.FormatInt(int64(duracao/time.Second), 10),
+			DataInicio: NovoInstante(inicio.Unix()),
+		}},
+	}
+	if comColeta {
+		m.Tipo = ManutencaoComColeta
+	}
+	for _, id := range hostIDs {
+		m.Hosts = append(m.Hosts, Host{ID: id})
+	}
+	for _, id := range grupoIDs {
+		m.Grupos = append(m.Grupos, GrupoHost{ID: id})
+	}
+	return m
+}
+
+// ObterManutencoes lista as manutenções com hosts, grupos e períodos, pela
+// ordem de início da vigência
+func (c *ClienteAPI) ObterManutencoes() ([]Manutencao, error) {
+	return c.ObterManutencoesCtx(context.Background())
+}
+
+// ObterManutencoesCtx é a variante de ObterManutencoes que aceita um contexto
+func (c *ClienteAPI) ObterManutencoesCtx(ctx context.Context) ([]Manutencao, error) {
+	versao, err := c.ObterVersaoCtx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao negociar versão da API: %w", err)
+	}
+
+	params := ParamsMaintenanceGet{
+		Output:            SaidaCompleta,
+		SelectHosts:       []string{"hostid", "host", "name"},
+		SelectTimeperiods: SaidaCompleta,
+		SortField:         []string{"name"},
+	}
+	// selectHostGroups substituiu selectGroups no Zabbix 6.2
+	if versao.AoMenos(6, 2) {
+		params.SelectHostGroups = []string{"groupid", "name"}
+	} else {
+		params.SelectGroups = []string{"groupid", "name"}
+	}
+
+	manutencoes, err := ChamarCtx[[]Manutencao](ctx, c, "maintenance.get", params)
+	if err != nil {
+		return nil, err
+	}
+	// maintenance.get não ordena pela vigência
+	sort.SliceStable(manutencoes, func(i, j int) bool {
+		return manutencoes[i].AtivaDesde.Before(manutencoes[j].AtivaDesde.Time)
+	})
+	return manutencoes, nil
+}
+
+// obterManutencao busca uma manutenção pelo ID
+func (c *ClienteAPI) obterManutencao(ctx context.Context, id string) (Manutencao, error) {
+	versao, err := c.ObterVersaoCtx(ctx)
+	if err != nil {
+		return Manutencao{}, fmt.Errorf("erro ao negociar versão da API: %w", err)
+	}
+
+	params := ParamsMaintenanceGet{
+		Output:            SaidaCompleta,
+		MaintenanceIDs:    []string{id},
+		SelectHosts:       []string{"hostid"},
+		SelectTimeperiods: SaidaCompleta,
+	}
+	if versao.AoMenos(6, 2) {
+		params.SelectHostGroups = []string{"groupid"}
+	} else {
+		params.SelectGroups = []string{"groupid"}
+	}
+
+	manutencoes, err := ChamarCtx[[]Manutencao](ctx, c, "maintenance.get", params)
+	if err != nil {
+		return Manutencao{}, err
+	}
+	if len(manutencoes) == 0 {
+		return Manutencao{}, fmt.Errorf("manutenção %s não encontrada", id)
+	}
+	return manutencoes[0], nil
+}
+
+// CriarManutencao cadastra a manutenção e retorna o ID criado
+func (c *ClienteAPI) CriarManutencao(m Manutencao) (string, error) {
+	return c.CriarManutencaoCtx(context.Background(), m)
+}
+
+// CriarManutencaoCtx é a variante de CriarManutencao que aceita um contexto
+func (c *ClienteAPI) CriarManutencaoCtx(ctx context.Context, m Manutencao) (string, error) {
+	m.ID = ""
+	params, err := c.paramsManutencao(ctx, m)
+	if err != nil {
+		return "", err
+	}
+
+	resultado, err := ChamarCtx[struct {
+		IDs []string `json:"maintenanceids"`
+	}](ctx, c, "maintenance.create", params)
+	if err != nil {
+		return "", err
+	}
+	if len(resultado.IDs) == 0 {
+		return "", errors.New("maintenance.create não retornou o ID da manutenção")
+	}
+	return resultado.IDs[0], nil
+}
+
+// AtualizarManutencao substitui os dados da manutenção m.ID, inclusive hosts,
+// grupos e períodos
+func (c *ClienteAPI) AtualizarManutencao(m Manutencao) error {
+	return c.AtualizarManutencaoCtx(context.Background(), m)
+}
+
+// AtualizarManutencaoCtx é a variante de AtualizarManutencao que aceita um contexto
+func (c *ClienteAPI) AtualizarManutencaoCtx(ctx context.Context, m Manutencao) error {
+	if m.ID == "" {
+		return errors.New("manutenção sem ID para atualizar")
+	}
+	params, err := c.paramsManutencao(ctx, m)
+	if err != nil {
+		return err
+	}
+	_, err = ChamarCtx[json.RawMessage](ctx, c, "maintenance.update", params)
+	return err
+}
+
+// EncerrarManutencao antecipa o fim da vigência de uma manutenção em curso
+// para agora, mantendo hosts, grupos e períodos
+func (c *ClienteAPI) EncerrarManutencao(id string, agora time.Time) error {
+	return c.EncerrarManutencaoCtx(context.Background(), id, agora)
+}
+
+// EncerrarManutencaoCtx é a variante de EncerrarManutencao que aceita um contexto
+func (c *ClienteAPI) EncerrarManutencaoCtx(ctx context.Context, id string, agora time.Time) error {
+	m, err := c.obterManutencao(ctx, id)
+	if err != nil {
+		return err
+	}
+	if m.Estado(agora) != ManutencaoAtiva {
+		return fmt.Errorf("a manutenção %q não está em vigor", m.Nome)
+	}
+
+	m.AtivaAte = NovoInstante(agora.Unix())
+	// O fim precisa ser posterior ao início mesmo logo após a criação
+	if !m.AtivaAte.After(m.AtivaDesde.Time) {
+		m.AtivaAte = NovoInstante(m.AtivaDesde.Unix() + 1)
+	}
+	return c.AtualizarManutencaoCtx(ctx, m)
+}
+
+// RemoverManutencoes exclui as manutenções informadas
+func (c *ClienteAPI) RemoverManutencoes(ids []string) error {
+	return c.RemoverManutencoesCtx(context.Background(), ids)
+}
+
+// RemoverManutencoesCtx é a variante de RemoverManutencoes que aceita um contexto
+func (c *ClienteAPI) RemoverManutencoesCtx(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return errors.New("nenhuma manutenção selecionada")
+	}
+	// maintenance.delete recebe a lista de IDs diretamente, sem objeto
+	_, err := ChamarCtx[json.RawMessage](ctx, c, "maintenance.delete", ids)
+	return err
+}
+
+// paramsManutencao converte a manutenção nos parâmetros de create/update. A
+// partir do Zabbix 6.0 hosts e grupos são objetos em "hosts" e "groups"; antes,
+// listas de IDs em "hostids" e "groupids".
+func (c *ClienteAPI) paramsManutencao(ctx context.Context, m Manutencao) (ParamsMaintenance, error) {
+	if strings.TrimSpace(m.Nome) == "" {
+		return ParamsMaintenance{}, errors.New("a manutenção precisa de um nome")
+	}
+	if len(m.Hosts) == 0 && len(m.Grupos) == 0 {
+		return ParamsMaintenance{}, errors.New("a manutenção precisa de ao menos um host ou grupo")
+	}
+	if len(m.Periodos) == 0 {
+		return ParamsMaintenance{}, errors.New("a manutenção precisa de ao menos um período")
+	}
+	if !m.AtivaAte.After(m.AtivaDesde.Time) {
+		return ParamsMaintenance{}, errors.New("o fim da vigência deve ser posterior ao início")
+	}
+
+	versao, err := c.ObterVersaoCtx(ctx)
+	if err != nil {
+		return ParamsMaintenance{}, fmt.Errorf("erro ao negociar versão da API: %w", err)
+	}
+
+	tipo, _ := strconv.Atoi(m.Tipo)
+	params := ParamsMaintenance{
+		MaintenanceID:   m.ID,
+		Name:            m.Nome,
+		MaintenanceType: tipo,
+		Description:     m.Descricao,
+		ActiveSince:     m.AtivaDesde.Segundos(),
+		ActiveTill:      m.AtivaAte.Segundos(),
+	}
+
+	hostIDs := make([]string, len(m.Hosts))
+	for i, h := range m.Hosts {
+		hostIDs[i] = h.ID
+	}
+	grupoIDs := make([]string, len(m.Grupos))
+	for i, g := range m.Grupos {
+		grupoIDs[i] = g.ID
+	}
+	if versao.AoMenos(6, 0) {
+		params.Hosts, params.Groups = objetosID("hostid", hostIDs), objetosID("groupid", grupoIDs)
+	} else {
+		params.HostIDs, params.GroupIDs = hostIDs, grupoIDs
+	}
+
+	for _, p := range m.Periodos {
+		periodo := ParamsTimeperiod{StartDate: p.DataInicio.Segundos()}
+		periodo.TimeperiodType, _ = strconv.Atoi(p.Tipo)
+		periodo.Every, _ = strconv.Atoi(p.ACada)
+		periodo.Month, _ = strconv.Atoi(p.Meses)
+		periodo.DayOfWeek, _ = strconv.Atoi(p.DiasSemana)
+		periodo.Day, _ = strconv.Atoi(p.Dia)
+		periodo.StartTime, _ = strconv.Atoi(p.HoraInicio)
+		periodo.Period, _ = strconv.Atoi(p.Duracao)
+		if periodo.Period < 300 {
+			return ParamsMaintenance{}, errors.New("cada período de manutenção deve durar ao menos 5 minutos")
+		}
+		params.Timeperiods = append(params.Timeperiods, periodo)
+	}
+
+	return params, nil
+}
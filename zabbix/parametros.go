@@ -0,0 +1,365 @@
+package zabbix
+
+// SaidaCompleta solicita todas as propriedades do objeto no parâmetro output
+const SaidaCompleta = "extend"
+
+// Os campos Output e Select* aceitam SaidaCompleta, uma lista de propriedades
+// ou, nos Select*, "count"; por isso são declarados como interface{}.
+
+// ParamsHostGet são os parâmetros de host.get. SelectHostGroups é convertido
+// em selectGroups para servidores anteriores ao 6.2.
+type ParamsHostGet struct {
+	Output                interface{} `json:"output,omitempty"`
+	HostIDs               []string    `json:"hostids,omitempty"`
+	GroupIDs              []string    `json:"groupids,omitempty"`
+	TemplateIDs           []string    `json:"templateids,omitempty"`
+	MonitoredHosts        bool        `json:"monitored_hosts,omitempty"`
+	Tags                  []FiltroTag `json:"tags,omitempty"`
+	Filter                interface{} `json:"filter,omitempty"`
+	SelectItems           interface{} `json:"selectItems,omitempty"`
+	SelectTriggers        interface{} `json:"selectTriggers,omitempty"`
+	SelectInterfaces      interface{} `json:"selectInterfaces,omitempty"`
+	SelectHostGroups      interface{} `json:"selectHostGroups,omitempty"`
+	SelectGroups          interface{} `json:"selectGroups,omitempty"`
+	SelectParentTemplates interface{} `json:"selectParentTemplates,omitempty"`
+	SelectTags            interface{} `json:"selectTags,omitempty"`
+	SelectMacros          interface{} `json:"selectMacros,omitempty"`
+	SortField             []string    `json:"sortfield,omitempty"`
+	Limit                 int         `json:"limit,omitempty"`
+}
+
+// Operadores do filtro de tags
+const (
+	OperadorTagContem = 0 // Valor contém o texto (sem valor: a tag existe)
+	OperadorTagIgual  = 1 // Valor exatamente igual
+)
+
+// FiltroTag filtra objetos pela tag no parâmetro tags dos métodos *.get
+type FiltroTag struct {
+	Tag      string `json:"tag"`
+	Value    string `json:"value"`
+	Operator int    `json:"operator"`
+}
+
+// ParamsHostGroupGet são os parâmetros de hostgroup.get
+type ParamsHostGroupGet struct {
+	Output    interface{} `json:"output,omitempty"`
+	GroupIDs  []string    `json:"groupids,omitempty"`
+	SortField []string    `json:"sortfield,omitempty"`
+}
+
+// ParamsTemplateGet são os parâmetros de template.get
+type ParamsTemplateGet struct {
+	Output                interface{} `json:"output,omitempty"`
+	TemplateIDs           []string    `json:"templateids,omitempty"`
+	SelectTemplateGroups  interface{} `json:"selectTemplateGroups,omitempty"`
+	SelectGroups          interface{} `json:"selectGroups,omitempty"`
+	SelectParentTemplates interface{} `json:"selectParentTemplates,omitempty"`
+	SelectMacros          interface{} `json:"selectMacros,omitempty"`
+	SelectTags            interface{} `json:"selectTags,omitempty"`
+	SortField             []string    `json:"sortfield,omitempty"`
+}
+
+// ParamsProblemGet são os parâmetros de problem.get
+type ParamsProblemGet struct {
+	Output    interface{} `json:"output,omitempty"`
+	ObjectIDs []string    `json:"objectids,omitempty"`
+	HostIDs   []string    `json:"hostids,omitempty"`
+	TimeFrom  int64       `json:"time_from,omitempty"`
+	TimeTill  int64       `json:"time_till,omitempty"`
+	Recent    bool        `json:"recent,omitempty"`
+	SortField []string    `json:"sortfield,omitempty"`
+	SortOrder string      `json:"sortorder,omitempty"`
+
+	Severities         []int       `json:"severities,omitempty"`
+	Acknowledged       *bool       `json:"acknowledged,omitempty"`
+	Suppressed         *bool       `json:"suppressed,omitempty"`
+	SelectAcknowledges interface{} `json:"selectAcknowledges,omitempty"`
+}
+
+// ParamsEventGet são os parâmetros de event.get
+type ParamsEventGet struct {
+	Output              interface{} `json:"output,omitempty"`
+	EventIDs            []string    `json:"eventids,omitempty"`
+	HostIDs             []string    `json:"hostids,omitempty"`
+	Value               []string    `json:"value,omitempty"`
+	Tags                []FiltroTag `json:"tags,omitempty"`
+	TimeFrom            int64       `json:"time_from,omitempty"`
+	TimeTill            int64       `json:"time_till,omitempty"`
+	SelectHosts         interface{} `json:"selectHosts,omitempty"`
+	SelectRelatedObject interface{} `json:"selectRelatedObject,omitempty"`
+	SortField           []string    `json:"sortfield,omitempty"`
+	SortOrder           string      `json:"sortorder,omitempty"`
+}
+
+// ParamsTriggerGet são os parâmetros de trigger.get
+type ParamsTriggerGet struct {
+	Output            interface{} `json:"output,omitempty"`
+	TriggerIDs        []string    `json:"triggerids,omitempty"`
+	HostIDs           []string    `json:"hostids,omitempty"`
+	SelectHosts       interface{} `json:"selectHosts,omitempty"`
+	LastChangeSince   int64       `json:"lastChangeSince,omitempty"`
+	ExpandDescription bool        `json:"expandDescription,omitempty"`
+	SortField         []string    `json:"sortfield,omitempty"`
+	SortOrder         string      `json:"sortorder,omitempty"`
+}
+
+// ParamsItemGet são os parâmetros de item.get
+type ParamsItemGet struct {
+	Output      interface{} `json:"output,omitempty"`
+	ItemIDs     []string    `json:"itemids,omitempty"`
+	HostIDs     []string    `json:"hostids,omitempty"`
+	SelectHosts interface{} `json:"selectHosts,omitempty"`
+	SortField   []string    `json:"sortfield,omitempty"`
+}
+
+// ParamsHistoryGet são os parâmetros de history.get. History é o value_type
+// dos itens e é sempre enviado, pois zero indica valores numéricos.
+type ParamsHistoryGet struct {
+	Output    interface{} `json:"output,omitempty"`
+	History   int         `json:"history"`
+	ItemIDs   []string    `json:"itemids,omitempty"`
+	TimeFrom  int64       `json:"time_from,omitempty"`
+	TimeTill  int64       `json:"time_till,omitempty"`
+	SortField []string    `json:"sortfield,omitempty"`
+	SortOrder string      `json:"sortorder,omitempty"`
+	Limit     int         `json:"limit,omitempty"`
+}
+
+// ParamsTrendGet são os parâmetros de trend.get, que não aceita ordenação
+type ParamsTrendGet struct {
+	Output   interface{} `json:"output,omitempty"`
+	ItemIDs  []string    `json:"itemids,omitempty"`
+	TimeFrom int64       `json:"time_from,omitempty"`
+	TimeTill int64       `json:"time_till,omitempty"`
+	Limit    int         `json:"limit,omitempty"`
+}
+
+// ParamsEventAcknowledge são os parâmetros de event.acknowledge. Severity é
+// um ponteiro porque zero ("Não classificada") é uma severidade válida.
+type ParamsEventAcknowledge struct {
+	EventIDs      []string `json:"eventids"`
+	Action        int      `json:"action"`
+	Message       string   `json:"message,omitempty"`
+	Severity      *int     `json:"severity,omitempty"`
+	SuppressUntil int64    `json:"suppress_until,omitempty"`
+}
+
+// ParamsSLAGet são os parâmetros de sla.get (Zabbix 6.0+)
+type ParamsSLAGet struct {
+	Output    interface{} `json:"output,omitempty"`
+	SLAIDs    []string    `json:"slaids,omitempty"`
+	SortField []string    `json:"sortfield,omitempty"`
+}
+
+// ParamsSLIGet são os parâmetros de sla.getsli
+type ParamsSLIGet struct {
+	SLAID      string   `json:"slaid"`
+	PeriodFrom int64    `json:"period_from,omitempty"`
+	PeriodTo   int64    `json:"period_to,omitempty"`
+	ServiceIDs []string `json:"serviceids,omitempty"`
+}
+
+// ParamsServiceGet são os parâmetros de service.get
+type ParamsServiceGet struct {
+	Output     interface{} `json:"output,omitempty"`
+	ServiceIDs []string    `json:"serviceids,omitempty"`
+}
+
+// ParamsMaintenanceGet são os parâmetros de maintenance.get. Como em host.get,
+// SelectHostGroups existe a partir do Zabbix 6.2; antes, SelectGroups.
+type ParamsMaintenanceGet struct {
+	Output            interface{} `json:"output,omitempty"`
+	MaintenanceIDs    []string    `json:"maintenanceids,omitempty"`
+	HostIDs           []string    `json:"hostids,omitempty"`
+	SelectHosts       interface{} `json:"selectHosts,omitempty"`
+	SelectHostGroups  interface{} `json:"selectHostGroups,omitempty"`
+	SelectGroups      interface{} `json:"selectGroups,omitempty"`
+	SelectTimeperiods interface{} `json:"selectTimeperiods,omitempty"`
+	SortField         []string    `json:"sortfield,omitempty"`
+	SortOrder         string      `json:"sortorder,omitempty"`
+}
+
+// ParamsMaintenance são os parâmetros de maintenance.create e
+// maintenance.update. Hosts e Groups (Zabbix 6.0+) ou HostIDs e GroupIDs
+// (anteriores) são interface{} para que uma lista vazia ainda seja enviada e
+// limpe a associação numa atualização.
+type ParamsMaintenance struct {
+	MaintenanceID   string             `json:"maintenanceid,omitempty"`
+	Name            string             `json:"name"`
+	MaintenanceType int                `json:"maintenance_type"`
+	Description     string             `json:"description"`
+	ActiveSince     int64              `json:"active_since"`
+	ActiveTill      int64              `json:"active_till"`
+	Hosts           interface{}        `json:"hosts,omitempty"`
+	Groups          interface{}        `json:"groups,omitempty"`
+	HostIDs         interface{}        `json:"hostids,omitempty"`
+	GroupIDs        interface{}        `json:"groupids,omitempty"`
+	Timeperiods     []ParamsTimeperiod `json:"timeperiods"`
+}
+
+// ParamsTimeperiod é um período de ParamsMaintenance. Campos zerados ficam com
+// o padrão do Zabbix.
+type ParamsTimeperiod struct {
+	TimeperiodType int   `json:"timeperiod_type"`
+	Every          int   `json:"every,omitempty"`
+	Month          int   `json:"month,omitempty"`
+	DayOfWeek      int   `json:"dayofweek,omitempty"`
+	Day            int   `json:"day,omitempty"`
+	StartTime      int   `json:"start_time,omitempty"`
+	Period         int   `json:"period"`
+	StartDate      int64 `json:"start_date,omitempty"`
+}
+
+// ParamsHostMassa são os parâmetros de host.massadd e host.massupdate. Status
+// é um ponteiro porque zero ("monitorado") é um valor válido.
+type ParamsHostMassa struct {
+	Hosts     []map[string]string `json:"hosts"`
+	Status    *int                `json:"status,omitempty"`
+	Groups    []map[string]string `json:"groups,omitempty"`
+	Templates []map[string]string `json:"templates,omitempty"`
+}
+
+// ParamsHostMassRemove são os parâmetros de host.massremove
+type ParamsHostMassRemove struct {
+	HostIDs     []string `json:"hostids"`
+	GroupIDs    []string `json:"groupids,omitempty"`
+	TemplateIDs []string `json:"templateids,omitempty"`
+}
+
+// ParamsHostUpdate são os parâmetros de host.update usados para trocar as
+// tags de um host. A lista enviada substitui todas as tags.
+type ParamsHostUpdate struct {
+	HostID string `json:"hostid"`
+	Tags   []Tag  `json:"tags"`
+}
+
+// objetosID converte IDs em objetos como {"hostid": "10084"}, a forma pedida
+// pelos métodos que recebem listas de hosts, grupos ou templates
+func objetosID(chave string, ids []string) []map[string]string {
+	objetos := make([]map[string]string, len(ids))
+	for i, id := range ids {
+		objetos[i] = map[string]string{chave: id}
+	}
+	return objetos
+}
+
+// ParamsMapGet são os parâmetros de map.get
+type ParamsMapGet struct {
+	Output    interface{} `json:"output,omitempty"`
+	SortField []string    `json:"sortfield,omitempty"`
+}
+
+// ParamsMediaTypeGet são os parâmetros de mediatype.get
+type ParamsMediaTypeGet struct {
+	Output    interface{} `json:"output,omitempty"`
+	SortField []string    `json:"sortfield,omitempty"`
+}
+
+// ParamsConfigurationExport são os parâmetros de configuration.export. As
+// chaves de Options são os tipos de objeto, como "templates" e "hosts".
+type ParamsConfigurationExport struct {
+	Format  string              `json:"format"`
+	Options map[string][]string `json:"options"`
+}
+
+// ParamsConfigurationImport são os parâmetros de configuration.import
+type ParamsConfigurationImport struct {
+	Format string                     `json:"format"`
+	Source string                     `json:"source"`
+	Rules  map[string]map[string]bool `json:"rules"`
+}
+
+// ParamsUserLogin são os parâmetros de user.login. Antes do Zabbix 5.4 o
+// nome de usuário era enviado em "user"; a partir dele, em "username".
+type ParamsUserLogin struct {
+	Username string `json:"username,omitempty"`
+	User     string `json:"user,omitempty"`
+	Password string `json:"password"`
+}
+
+// ParamsTemplateGroupGet são os parâmetros de templategroup.get, do Zabbix 6.2
+// em diante
+type ParamsTemplateGroupGet struct {
+	Output    interface{} `json:"output,omitempty"`
+	SortField []string    `json:"sortfield,omitempty"`
+}
+
+// ParamsGrupoCreate são os parâmetros de hostgroup.create e templategroup.create
+type ParamsGrupoCreate struct {
+	Name string `json:"name"`
+}
+
+// ParamsHostSalvar são os parâmetros de host.create e host.update usados pelo
+// plano declarativo. As listas são interface{} para que uma lista vazia seja
+// enviada, substituindo a do servidor, e nil seja omitido, mantendo-a.
+type ParamsHostSalvar struct {
+	HostID     string      `json:"hostid,omitempty"`
+	Host       string      `json:"host,omitempty"`
+	Name       string      `json:"name,omitempty"`
+	Status     *int        `json:"status,omitempty"`
+	Groups     interface{} `json:"groups,omitempty"`
+	Templates  interface{} `json:"templates,omitempty"`
+	Macros     interface{} `json:"macros,omitempty"`
+	Tags       interface{} `json:"tags,omitempty"`
+	Interfaces interface{} `json:"interfaces,omitempty"`
+}
+
+// ParamsTemplateSalvar são os parâmetros de template.create e template.update
+// usados pelo plano declarativo, com as listas tratadas como em ParamsHostSalvar
+type ParamsTemplateSalvar struct {
+	TemplateID string      `json:"templateid,omitempty"`
+	Host       string      `json:"host,omitempty"`
+	Name       string      `json:"name,omitempty"`
+	Groups     interface{} `json:"groups,omitempty"`
+	Templates  interface{} `json:"templates,omitempty"`
+	Macros     interface{} `json:"macros,omitempty"`
+	Tags       interface{} `json:"tags,omitempty"`
+}
+
+// ParamsMacro é uma macro de usuário enviada em host.update e template.update.
+// Com ID, altera a macro existente; Value nil mantém o valor, o que permite
+// regravar macros secretas sem conhecê-lo.
+type ParamsMacro struct {
+	ID          string  `json:"hostmacroid,omitempty"`
+	Macro       string  `json:"macro,omitempty"`
+	Value       *string `json:"value,omitempty"`
+	Description string  `json:"description"`
+	Type        string  `json:"type,omitempty"`
+}
+
+// ParamsInterface é uma interface enviada em host.create e host.update. Com
+// ID, altera a interface existente, preservando os itens ligados a ela.
+type ParamsInterface struct {
+	ID      string            `json:"interfaceid,omitempty"`
+	Type    string            `json:"type"`
+	Main    string            `json:"main"`
+	UseIP   string            `json:"useip"`
+	IP      string            `json:"ip"`
+	DNS     string            `json:"dns"`
+	Port    string            `json:"port"`
+	Details map[string]string `json:"details,omitempty"`
+}
+
+// ParamsUserMacroGet são os parâmetros de usermacro.get. Com GlobalMacro, a
+// API retorna as macros globais em vez das de hosts e templates.
+type ParamsUserMacroGet struct {
+	Output      interface{} `json:"output,omitempty"`
+	HostIDs     []string    `json:"hostids,omitempty"`
+	GlobalMacro bool        `json:"globalmacro,omitempty"`
+	Filter      interface{} `json:"filter,omitempty"`
+	SortField   []string    `json:"sortfield,omitempty"`
+}
+
+// ParamsUserMacro é uma macro enviada em usermacro.create e usermacro.update
+// ou nas variantes globais. Na atualização, Value nil mantém o valor atual,
+// o que permite alterar macros secretas sem conhecê-lo.
+type ParamsUserMacro struct {
+	HostMacroID   string  `json:"hostmacroid,omitempty"`
+	GlobalMacroID string  `json:"globalmacroid,omitempty"`
+	HostID        string  `json:"hostid,omitempty"`
+	Macro         string  `json:"macro,omitempty"`
+	Value         *string `json:"value,omitempty"`
+	Description   *string `json:"description,omitempty"`
+	Type          string  `json:"type,omitempty"`
+}
@@ -0,0 +1,1227 @@
+package zabbix
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Ações das operações do plano
+const (
+	AcaoCriar     = "criar"
+	AcaoAtualizar = "atualizar"
+	AcaoRemover   = "remover"
+)
+
+// Tipos de objeto das operações do plano
+const (
+	TipoGrupoHosts     = "grupo de hosts"
+	TipoGrupoTemplates = "grupo de templates"
+	TipoTemplate       = "template"
+	TipoHost           = "host"
+)
+
+// Situação das operações depois de AplicarPlano; vazia antes de aplicar
+const (
+	SituacaoAplicada = "aplicada"
+	SituacaoFalhou   = "falhou"
+	SituacaoPulada   = "pulada"
+)
+
+// NomesTiposMacro mapeia o tipo da macro de usuário para o nome exibido
+var NomesTiposMacro = map[string]string{
+	"0": "texto",
+	"1": "secreta",
+	"2": "vault",
+}
+
+// OperacaoPlano é uma chamada da API que aproxima o servidor da declaração
+type OperacaoPlano struct {
+	Acao     string // AcaoCriar, AcaoAtualizar ou AcaoRemover
+	Tipo     string // TipoGrupoHosts, TipoGrupoTemplates, TipoTemplate ou TipoHost
+	Nome     string
+	Metodo   string
+	Mudancas []string // O que muda no objeto, para exibição
+	Depende  []string // Chaves das operações do plano que precisam ser aplicadas antes
+
+	Situacao string // Preenchida por AplicarPlano
+	Err      error
+
+	// montar gera os parâmetros com os IDs conhecidos no momento da chamada,
+	// incluindo os de objetos criados por operações anteriores
+	montar    func(ids *idsPlano) (interface{}, error)
+	registrar func(ids *idsPlano, id string) // Guarda o ID do objeto criado
+}
+
+// Chave identifica a operação nas dependências, ex: "template:Linux base"
+func (o *OperacaoPlano) Chave() string {
+	return chaveOperacao(o.Tipo, o.Nome)
+}
+
+func chaveOperacao(tipo, nome string) string {
+	return tipo + ":" + nome
+}
+
+// Plano são as operações necessárias para o servidor ficar como a declaração,
+// na ordem de aplicação: grupos, templates (os vinculados antes de quem os
+// vincula), hosts e, por último, as remoções
+type Plano struct {
+	Versao    Versao
+	Operacoes []*OperacaoPlano
+
+	ids *idsPlano
+}
+
+// Vazio informa se o servidor já está como a declaração
+func (p *Plano) Vazio() bool {
+	return len(p.Operacoes) == 0
+}
+
+// Contar retorna quantas operações têm a ação
+func (p *Plano) Contar(acao string) int {
+	total := 0
+	for _, op := range p.Operacoes {
+		if op.Acao == acao {
+			total++
+		}
+	}
+	return total
+}
+
+// ContarSituacao retorna quantas operações terminaram na situação
+func (p *Plano) ContarSituacao(situacao string) int {
+	total := 0
+	for _, op := range p.Operacoes {
+		if op.Situacao == situacao {
+			total++
+		}
+	}
+	return total
+}
+
+// Assinatura resume as operações e mudanças do plano. Dois planos com a mesma
+// assinatura fazem as mesmas alterações, o que permite confirmar que o plano
+// aplicado é o mesmo que foi revisado.
+func (p *Plano) Assinatura() string {
+	resumo := sha256.New()
+	for _, op := range p.Operacoes {
+		fmt.Fprintf(resumo, "%s\x00%s\x00%s\x00%s\n", op.Acao, op.Tipo, op.Nome, strings.Join(op.Mudancas, "\x00"))
+	}
+	return hex.EncodeToString(resumo.Sum(nil))[:16]
+}
+
+// idsPlano são os IDs dos objetos por nome, os do servidor e os criados
+// durante a aplicação
+type idsPlano struct {
+	gruposHosts     map[string]string
+	gruposTemplates map[string]string // O mesmo mapa de gruposHosts antes do Zabbix 6.2
+	templates       map[string]string
+}
+
+// objetosNomes converte nomes em objetos como {"groupid": "2"}
+func objetosNomes(ids map[string]string, chave, tipo string, nomes []string) ([]map[string]string, error) {
+	objetos := make([]map[string]string, 0, len(nomes))
+	for _, nome := range nomes {
+		id := ids[nome]
+		if id == "" {
+			return nil, fmt.Errorf("%s %q não encontrado", tipo, nome)
+		}
+		objetos = append(objetos, map[string]string{chave: id})
+	}
+	return objetos, nil
+}
+
+// objetoServidor é um host ou template como retornado por host.get e
+// template.get, com os campos comparados com a declaração
+type objetoServidor struct {
+	HostID          string              `json:"hostid"`
+	TemplateID      string              `json:"templateid"`
+	Host            string              `json:"host"`
+	Nome            string              `json:"name"`
+	Status          string              `json:"status"`
+	GruposHosts     []GrupoHost         `json:"hostgroups"`
+	GruposTemplates []GrupoHost         `json:"templategroups"`
+	GruposAntigos   []GrupoHost         `json:"groups"` // Antes do Zabbix 6.2
+	Templates       []vinculoServidor   `json:"parentTemplates"`
+	Macros          []macroServidor     `json:"macros"`
+	Tags            []Tag               `json:"tags"`
+	Interfaces      []interfaceServidor `json:"interfaces"`
+}
+
+// id retorna o ID do host ou do template
+func (o objetoServidor) id() string {
+	if o.HostID != "" {
+		return o.HostID
+	}
+	return o.TemplateID
+}
+
+// grupos retorna os grupos do objeto, seja qual for o nome do campo
+func (o objetoServidor) grupos() []GrupoHost {
+	switch {
+	case o.GruposHosts != nil:
+		return o.GruposHosts
+	case o.GruposTemplates != nil:
+		return o.GruposTemplates
+	}
+	return o.GruposAntigos
+}
+
+type vinculoServidor struct {
+	ID   string `json:"templateid"`
+	Host string `json:"host"`
+}
+
+type macroServidor struct {
+	ID        string `json:"hostmacroid"`
+	Macro     string `json:"macro"`
+	Valor     string `json:"value"`
+	Descricao string `json:"description"`
+	Tipo      string `json:"type"` // Ausente antes do Zabbix 5.0
+}
+
+// tipo retorna o tipo da macro, texto quando o servidor não o informa
+func (m macroServidor) tipo() string {
+	if m.Tipo == "" {
+		return "0"
+	}
+	return m.Tipo
+}
+
+type interfaceServidor struct {
+	ID        string            `json:"interfaceid"`
+	Tipo      string            `json:"type"`
+	Principal string            `json:"main"`
+	UsaIP     string            `json:"useip"`
+	IP        string            `json:"ip"`
+	DNS       string            `json:"dns"`
+	Porta     string            `json:"port"`
+	Detalhes  detalhesInterface `json:"details"`
+}
+
+// detalhesInterface aceita a lista vazia que o Zabbix retorna nas interfaces
+// sem detalhes no lugar de um objeto
+type detalhesInterface map[string]string
+
+func (d *detalhesInterface) UnmarshalJSON(dados []byte) error {
+	if len(dados) > 0 && dados[0] == '[' {
+		*d = nil
+		return nil
+	}
+	var detalhes map[string]string
+	if err := json.Unmarshal(dados, &detalhes); err != nil {
+		return err
+	}
+	*d = detalhes
+	return nil
+}
+
+// estadoServidor é o que o plano precisa saber do servidor
+type estadoServidor struct {
+	gruposHosts     map[string]string
+	gruposTemplates map[string]string
+	templates       map[string]objetoServidor // Pelo nome técnico
+	hosts           map[string]objetoServidor // Só os declarados, pelo nome técnico
+	hostsEscopo     []objetoServidor          // Hosts dos grupos de remoção
+	hostsVinculados map[string][]string       // Hosts de cada template dos grupos de remoção, pelo nome técnico
+}
+
+// obterEstado lê os grupos, todos os templates, os hosts declarados, os
+// hosts dos grupos de remoção e os hosts vinculados aos templates que podem
+// ser removidos
+func (c *ClienteAPI) obterEstado(ctx context.Context, versao Versao, d Declaracao) (*estadoServidor, error) {
+	// Grupos de templates foram separados dos grupos de hosts no Zabbix 6.2
+	separados := versao.AoMenos(6, 2)
+
+	paramsTemplates := ParamsTemplateGet{
+		Output:                []string{"templateid", "host", "name"},
+		SelectParentTemplates: []string{"templateid", "host"},
+		SelectMacros:          SaidaCompleta,
+		SelectTags:            []string{"tag", "value"},
+	}
+	if separados {
+		paramsTemplates.SelectTemplateGroups = []string{"groupid", "name"}
+	} else {
+		paramsTemplates.SelectGroups = []string{"groupid", "name"}
+	}
+
+	lote := c.NovoLote()
+	resultadoGrupos := AdicionarAoLote[[]GrupoHost](lote, "hostgroup.get", ParamsHostGroupGet{
+		Output: []string{"groupid", "name"},
+	})
+	var resultadoGruposTemplates *ResultadoLote[[]GrupoHost]
+	if separados {
+		resultadoGruposTemplates = AdicionarAoLote[[]GrupoHost](lote, "templategroup.get", ParamsTemplateGroupGet{
+			Output: []string{"groupid", "name"},
+		})
+	}
+	resultadoTemplates := AdicionarAoLote[[]objetoServidor](lote, "template.get", paramsTemplates)
+	if err := lote.ExecutarCtx(ctx); err != nil {
+		return nil, err
+	}
+
+	mapaGrupos := func(resultado *ResultadoLote[[]GrupoHost]) (map[string]string, error) {
+		grupos, err := resultado.Obter()
+		if err != nil {
+			return nil, err
+		}
+		ids := make(map[string]string, len(grupos))
+		for _, g := range grupos {
+			ids[g.Nome] = g.ID
+		}
+		return ids, nil
+	}
+
+	estado := &estadoServidor{
+		templates: make(map[string]objetoServidor),
+		hosts:     make(map[string]objetoServidor),
+	}
+	var err error
+	if estado.gruposHosts, err = mapaGrupos(resultadoGrupos); err != nil {
+		return nil, err
+	}
+	estado.gruposTemplates = estado.gruposHosts
+	if separados {
+		if estado.gruposTemplates, err = mapaGrupos(resultadoGruposTemplates); err != nil {
+			return nil, err
+		}
+	}
+	templates, err := resultadoTemplates.Obter()
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range templates {
+		estado.templates[t.Host] = t
+	}
+
+	// Remover um template apaga os itens e triggers herdados por todos os hosts
+	// vinculados, inclusive os que não estão nos arquivos
+	if err := c.obterHostsVinculados(ctx, estado, d.RemoverAusentes.GruposTemplates); err != nil {
+		return nil, err
+	}
+
+	if len(d.Hosts) > 0 {
+		nomes := make([]string, len(d.Hosts))
+		for i, h := range d.Hosts {
+			nomes[i] = h.Host
+		}
+		err := percorrerHosts(ctx, c, ParamsHostGet{
+			Output:                []string{"hostid", "host", "name", "status"},
+			Filter:                map[string][]string{"host": nomes},
+			SelectHostGroups:      []string{"groupid", "name"},
+			SelectParentTemplates: []string{"templateid", "host"},
+			SelectMacros:          SaidaCompleta,
+			SelectTags:            []string{"tag", "value"},
+			SelectInterfaces:      SaidaCompleta,
+		}, 0, func(h objetoServidor) error {
+			estado.hosts[h.Host] = h
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var gruposEscopo []string
+	for _, nome := range d.RemoverAusentes.GruposHosts {
+		if id, ok := estado.gruposHosts[nome]; ok {
+			gruposEscopo = append(gruposEscopo, id)
+		}
+	}
+	if len(gruposEscopo) > 0 {
+		err := PercorrerCtx(ctx, c, "host.get", ParamsHostGet{
+			Output:    []string{"hostid", "host"},
+			GroupIDs:  gruposEscopo,
+			SortField: []string{"host"},
+		}, func(h objetoServidor) error {
+			estado.hostsEscopo = append(estado.hostsEscopo, h)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return estado, nil
+}
+
+// obterHostsVinculados lê os hosts vinculados diretamente aos templates dos
+// grupos informados
+func (c *ClienteAPI) obterHostsVinculados(ctx context.Context, estado *estadoServidor, grupos []string) error {
+	escopo := make(map[string]bool, len(grupos))
+	for _, nome := range grupos {
+		escopo[nome] = true
+	}
+	idsEscopo := make(map[string]bool)
+	var ids []string
+	for _, t := range estado.templates {
+		for _, g := range t.grupos() {
+			if escopo[g.Nome] {
+				idsEscopo[t.id()] = true
+				ids = append(ids, t.id())
+				break
+			}
+		}
+	}
+	estado.hostsVinculados = make(map[string][]string)
+	if len(ids) == 0 {
+		// Sem templateids, host.get devolveria todos os hosts
+		return nil
+	}
+	sort.Strings(ids)
+
+	return PercorrerCtx(ctx, c, "host.get", ParamsHostGet{
+		Output:                []string{"hostid", "host"},
+		TemplateIDs:           ids,
+		SelectParentTemplates: []string{"templateid", "host"},
+		SortField:             []string{"host"},
+	}, func(h objetoServidor) error {
+		for _, v := range h.Templates {
+			if idsEscopo[v.ID] {
+				estado.hostsVinculados[v.Host] = append(estado.hostsVinculados[v.Host], h.Host)
+			}
+		}
+		return nil
+	})
+}
+
+// objetoDeclarado reúne os campos comuns de hosts e templates declarados
+type objetoDeclarado struct {
+	tipo        string // TipoTemplate ou TipoHost
+	nome        string
+	nomeVisivel string
+	status      string // Código da API; vazio mantém
+	tipoGrupo   string // TipoGrupoHosts ou TipoGrupoTemplates
+	grupos      []string
+	templates   []string
+	macros      []MacroDeclarada
+	tags        []Tag
+	interfaces  []InterfaceDeclarada
+}
+
+// planejador monta as operações de um plano
+type planejador struct {
+	versao  Versao
+	estado  *estadoServidor
+	plano   *Plano
+	criados map[string]bool // Chaves das operações de criação já planejadas
+}
+
+// adicionar acrescenta a operação ao plano
+func (p *planejador) adicionar(op *OperacaoPlano) {
+	p.plano.Operacoes = append(p.plano.Operacoes, op)
+	if op.Acao == AcaoCriar {
+		p.criados[op.Chave()] = true
+	}
+}
+
+// PlanejarDeclaracao compara a declaração com o servidor e retorna as
+// operações necessárias, sem alterar nada
+func (c *ClienteAPI) PlanejarDeclaracao(d Declaracao) (*Plano, error) {
+	return c.PlanejarDeclaracaoCtx(context.Background(), d)
+}
+
+// PlanejarDeclaracaoCtx é a variante de PlanejarDeclaracao que aceita um contexto
+func (c *ClienteAPI) PlanejarDeclaracaoCtx(ctx context.Context, d Declaracao) (*Plano, error) {
+	if err := d.Validar(); err != nil {
+		return nil, err
+	}
+	versao, err := c.ObterVersaoCtx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao negociar versão da API: %w", err)
+	}
+
+	// O plano precisa do estado atual, não do cache
+	ctx = semCache(ctx)
+	estado, err := c.obterEstado(ctx, versao, d)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := &idsPlano{
+		gruposHosts: copiarMapa(estado.gruposHosts),
+		templates:   make(map[string]string, len(estado.templates)),
+	}
+	ids.gruposTemplates = ids.gruposHosts
+	if versao.AoMenos(6, 2) {
+		ids.gruposTemplates = copiarMapa(estado.gruposTemplates)
+	}
+	for nome, t := range estado.templates {
+		ids.templates[nome] = t.id()
+	}
+
+	p := &planejador{
+		versao:  versao,
+		estado:  estado,
+		plano:   &Plano{Versao: versao, ids: ids},
+		criados: make(map[string]bool),
+	}
+
+	templates, err := ordenarTemplates(d.Templates)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.conferirReferencias(d); err != nil {
+		return nil, err
+	}
+
+	p.planejarGrupos(d)
+
+	var problemas []error
+	for _, t := range templates {
+		declarado := objetoDeclarado{
+			tipo:        TipoTemplate,
+			nome:        t.Template,
+			nomeVisivel: t.Nome,
+			tipoGrupo:   p.tipoGrupoTemplates(),
+			grupos:      t.Grupos,
+			templates:   t.Templates,
+			macros:      t.Macros,
+			tags:        t.Tags,
+		}
+		if err := p.planejarObjeto(declarado, estado.templates); err != nil {
+			problemas = append(problemas, err)
+		}
+	}
+	for _, h := range d.Hosts {
+		declarado := objetoDeclarado{
+			tipo:        TipoHost,
+			nome:        h.Host,
+			nomeVisivel: h.Nome,
+			status:      statusDeclarado[h.Status],
+			tipoGrupo:   TipoGrupoHosts,
+			grupos:      h.Grupos,
+			templates:   h.Templates,
+			macros:      h.Macros,
+			tags:        h.Tags,
+			interfaces:  h.Interfaces,
+		}
+		if err := p.planejarObjeto(declarado, estado.hosts); err != nil {
+			problemas = append(problemas, err)
+		}
+	}
+	if err := p.planejarRemocoes(d); err != nil {
+		problemas = append(problemas, err)
+	}
+	if len(problemas) > 0 {
+		return nil, errors.Join(problemas...)
+	}
+	return p.plano, nil
+}
+
+func copiarMapa(m map[string]string) map[string]string {
+	copia := make(map[string]string, len(m))
+	for chave, valor := range m {
+		copia[chave] = valor
+	}
+	return copia
+}
+
+// tipoGrupoTemplates retorna o tipo dos grupos de templates na versão do servidor
+func (p *planejador) tipoGrupoTemplates() string {
+	if p.versao.AoMenos(6, 2) {
+		return TipoGrupoTemplates
+	}
+	return TipoGrupoHosts
+}
+
+// ordenarTemplates coloca cada template depois dos templates declarados que
+// ele vincula, para que sejam criados antes
+func ordenarTemplates(templates []TemplateDeclarado) ([]TemplateDeclarado, error) {
+	porNome := make(map[string]TemplateDeclarado, len(templates))
+	for _, t := range templates {
+		porNome[t.Template] = t
+	}
+
+	const (
+		visitando = 1
+		visitado  = 2
+	)
+	marcas := make(map[string]int, len(templates))
+	ordenados := make([]TemplateDeclarado, 0, len(templates))
+	var visitar func(nome string, caminho []string) error
+	visitar = func(nome string, caminho []string) error {
+		switch marcas[nome] {
+		case visitado:
+			return nil
+		case visitando:
+			return fmt.Errorf("vínculo circular entre templates: %s", strings.Join(append(caminho, nome), " → "))
+		}
+		marcas[nome] = visitando
+		for _, vinculado := range porNome[nome].Templates {
+			if _, declarado := porNome[vinculado]; declarado {
+				if err := visitar(vinculado, append(caminho, nome)); err != nil {
+					return err
+				}
+			}
+		}
+		marcas[nome] = visitado
+		ordenados = append(ordenados, porNome[nome])
+		return nil
+	}
+	for _, t := range templates {
+		if err := visitar(t.Template, nil); err != nil {
+			return nil, err
+		}
+	}
+	return ordenados, nil
+}
+
+// conferirReferencias confere se os templates vinculados existem no servidor
+// ou nos arquivos
+func (p *planejador) conferirReferencias(d Declaracao) error {
+	declarados := make(map[string]bool, len(d.Templates))
+	for _, t := range d.Templates {
+		declarados[t.Template] = true
+	}
+
+	var problemas []error
+	conferir := func(tipo, nome string, vinculados []string) {
+		for _, vinculado := range vinculados {
+			if _, existe := p.estado.templates[vinculado]; !existe && !declarados[vinculado] {
+				problemas = append(problemas, fmt.Errorf("%s %q vincula o template %q, que não existe no servidor nem nos arquivos", tipo, nome, vinculado))
+			}
+		}
+	}
+	for _, t := range d.Templates {
+		conferir(TipoTemplate, t.Template, t.Templates)
+	}
+	for _, h := range d.Hosts {
+		conferir(TipoHost, h.Host, h.Templates)
+	}
+	return errors.Join(problemas...)
+}
+
+// planejarGrupos cria os grupos citados nos arquivos que não existem
+func (p *planejador) planejarGrupos(d Declaracao) {
+	faltando := func(ids map[string]string, listas ...[]string) []string {
+		vistos := make(map[string]bool)
+		var nomes []string
+		for _, lista := range listas {
+			for _, nome := range lista {
+				if _, existe := ids[nome]; !existe && !vistos[nome] {
+					vistos[nome] = true
+					nomes = append(nomes, nome)
+				}
+			}
+		}
+		sort.Strings(nomes)
+		return nomes
+	}
+
+	var gruposHosts, gruposTemplates [][]string
+	for _, h := range d.Hosts {
+		gruposHosts = append(gruposHosts, h.Grupos)
+	}
+	for _, t := range d.Templates {
+		gruposTemplates = append(gruposTemplates, t.Grupos)
+	}
+	// Antes do Zabbix 6.2 os templates ficam em grupos de hosts
+	if !p.versao.AoMenos(6, 2) {
+		gruposHosts = append(gruposHosts, gruposTemplates...)
+		gruposTemplates = nil
+	}
+
+	for _, nome := range faltando(p.estado.gruposHosts, gruposHosts...) {
+		nome := nome
+		p.adicionar(&OperacaoPlano{
+			Acao:   AcaoCriar,
+			Tipo:   TipoGrupoHosts,
+			Nome:   nome,
+			Metodo: "hostgroup.create",
+			montar: func(*idsPlano) (interface{}, error) {
+				return ParamsGrupoCreate{Name: nome}, nil
+			},
+			registrar: func(ids *idsPlano, id string) {
+				ids.gruposHosts[nome] = id
+			},
+		})
+	}
+	for _, nome := range faltando(p.estado.gruposTemplates, gruposTemplates...) {
+		nome := nome
+		p.adicionar(&OperacaoPlano{
+			Acao:   AcaoCriar,
+			Tipo:   TipoGrupoTemplates,
+			Nome:   nome,
+			Metodo: "templategroup.create",
+			montar: func(*idsPlano) (interface{}, error) {
+				return ParamsGrupoCreate{Name: nome}, nil
+			},
+			registrar: func(ids *idsPlano, id string) {
+				ids.gruposTemplates[nome] = id
+			},
+		})
+	}
+}
+
+// planejarObjeto acrescenta a criação ou a atualização de um host ou template;
+// objetos já como declarados não geram operação
+func (p *planejador) planejarObjeto(o objetoDeclarado, existentes map[string]objetoServidor) error {
+	metodo := "host"
+	if o.tipo == TipoTemplate {
+		metodo = "template"
+	}
+
+	op := &OperacaoPlano{Tipo: o.tipo, Nome: o.nome}
+	atual, existe := existentes[o.nome]
+	if existe {
+		op.Acao = AcaoAtualizar
+		op.Metodo = metodo + ".update"
+		op.Mudancas = p.comparar(o, atual)
+		if len(op.Mudancas) == 0 {
+			return nil
+		}
+	} else {
+		if len(o.grupos) == 0 {
+			return fmt.Errorf("%s %q não existe e precisa de ao menos um grupo para ser criado", o.tipo, o.nome)
+		}
+		op.Acao = AcaoCriar
+		op.Metodo = metodo + ".create"
+		op.Mudancas = descreverCriacao(o)
+	}
+
+	for _, grupo := range o.grupos {
+		if chave := chaveOperacao(o.tipoGrupo, grupo); p.criados[chave] {
+			op.Depende = append(op.Depende, chave)
+		}
+	}
+	for _, template := range o.templates {
+		if chave := chaveOperacao(TipoTemplate, template); p.criados[chave] {
+			op.Depende = append(op.Depende, chave)
+		}
+	}
+
+	var referencia *objetoServidor
+	if existe {
+		referencia = &atual
+	}
+	op.montar = func(ids *idsPlano) (interface{}, error) {
+		return p.parametros(o, referencia, ids)
+	}
+	if o.tipo == TipoTemplate && !existe {
+		op.registrar = func(ids *idsPlano, id string) {
+			ids.templates[o.nome] = id
+		}
+	}
+	p.adicionar(op)
+	return nil
+}
+
+// descreverCriacao lista o que o objeto criado terá
+func descreverCriacao(o objetoDeclarado) []string {
+	var linhas []string
+	if o.nomeVisivel != "" {
+		linhas = append(linhas, "Nome visível: "+o.nomeVisivel)
+	}
+	if o.status != "" {
+		linhas = append(linhas, "Status: "+StatusHost[o.status])
+	}
+	linhas = append(linhas, "Grupos: "+strings.Join(o.grupos, ", "))
+	if len(o.templates) > 0 {
+		linhas = append(linhas, "Templates: "+strings.Join(o.templates, ", "))
+	}
+	for _, m := range o.macros {
+		linhas = append(linhas, "Macro "+descreverMacro(m))
+	}
+	if len(o.tags) > 0 {
+		linhas = append(linhas, "Tags: "+descreverTags(o.tags))
+	}
+	for _, params := range parametrosInterfaces(o.interfaces) {
+		linhas = append(linhas, "Interface "+descreverInterface(params.Type, params.IP, params.DNS, params.Port))
+	}
+	return linhas
+}
+
+// comparar lista as diferenças entre o objeto declarado e o do servidor,
+// apenas nos campos gerenciados
+func (p *planejador) comparar(o objetoDeclarado, atual objetoServidor) []string {
+	var mudancas []string
+	if o.nomeVisivel != "" && o.nomeVisivel != atual.Nome {
+		mudancas = append(mudancas, fmt.Sprintf("Nome visível: %s → %s", atual.Nome, o.nomeVisivel))
+	}
+	if o.status != "" && o.status != atual.Status {
+		mudancas = append(mudancas, fmt.Sprintf("Status: %s → %s", StatusHost[atual.Status], StatusHost[o.status]))
+	}
+
+	if o.grupos != nil {
+		grupos := atual.grupos()
+		nomes := make([]string, len(grupos))
+		for i, g := range grupos {
+			nomes[i] = g.Nome
+		}
+		adicionados, removidos := diferencaNomes(nomes, o.grupos)
+		for _, nome := range adicionados {
+			mudancas = append(mudancas, "Adicionar ao grupo "+nome)
+		}
+		for _, nome := range removidos {
+			mudancas = append(mudancas, "Remover do grupo "+nome)
+		}
+	}
+
+	if o.templates != nil {
+		nomes := make([]string, len(atual.Templates))
+		for i, t := range atual.Templates {
+			nomes[i] = t.Host
+		}
+		adicionados, removidos := diferencaNomes(nomes, o.templates)
+		for _, nome := range adicionados {
+			mudancas = append(mudancas, "Vincular template "+nome)
+		}
+		for _, nome := range removidos {
+			mudancas = append(mudancas, "Desvincular template "+nome)
+		}
+	}
+
+	if o.macros != nil {
+		mudancas = append(mudancas, compararMacros(o.macros, atual.Macros)...)
+	}
+
+	if o.tags != nil {
+		chave := func(t Tag) string { return t.Nome + "\x00" + t.Valor }
+		atuais := make([]string, len(atual.Tags))
+		for i, t := range atual.Tags {
+			atuais[i] = chave(t)
+		}
+		desejadas := make([]string, len(o.tags))
+		for i, t := range o.tags {
+			desejadas[i] = chave(t)
+		}
+		adicionadas, removidas := diferencaNomes(atuais, desejadas)
+		for _, t := range adicionadas {
+			nome, valor, _ := strings.Cut(t, "\x00")
+			mudancas = append(mudancas, "Adicionar tag "+Tag{Nome: nome, Valor: valor}.String())
+		}
+		for _, t := range removidas {
+			nome, valor, _ := strings.Cut(t, "\x00")
+			mudancas = append(mudancas, "Remover tag "+Tag{Nome: nome, Valor: valor}.String())
+		}
+	}
+
+	if o.interfaces != nil {
+		mudancas = append(mudancas, compararInterfaces(parametrosInterfaces(o.interfaces), atual.Interfaces)...)
+	}
+	return mudancas
+}
+
+// diferencaNomes retorna os desejados ausentes dos atuais e os atuais
+// ausentes dos desejados, na ordem das listas
+func diferencaNomes(atuais, desejados []string) (adicionados, removidos []string) {
+	emAtuais := make(map[string]bool, len(atuais))
+	for _, nome := range atuais {
+		emAtuais[nome] = true
+	}
+	emDesejados := make(map[string]bool, len(desejados))
+	for _, nome := range desejados {
+		if !emAtuais[nome] && !emDesejados[nome] {
+			adicionados = append(adicionados, nome)
+		}
+		emDesejados[nome] = true
+	}
+	for _, nome := range atuais {
+		if !emDesejados[nome] {
+			removidos = append(removidos, nome)
+		}
+	}
+	return adicionados, removidos
+}
+
+// descreverMacro formata a macro com o valor, exceto as secretas
+func descreverMacro(m MacroDeclarada) string {
+	if tiposMacroDeclarada[m.Tipo] == "1" {
+		return m.Macro + " (secreta)"
+	}
+	return fmt.Sprintf("%s = %q", m.Macro, m.Valor)
+}
+
+// compararMacros lista as macros adicionadas, alteradas e removidas. O valor
+// de macros secretas não é comparado, pois a API não o retorna.
+func compararMacros(desejadas []MacroDeclarada, atuais []macroServidor) []string {
+	porNome := make(map[string]macroServidor, len(atuais))
+	for _, m := range atuais {
+		porNome[m.Macro] = m
+	}
+
+	var mudancas []string
+	declaradas := make(map[string]bool, len(desejadas))
+	for _, m := range desejadas {
+		declaradas[m.Macro] = true
+		atual, existe := porNome[m.Macro]
+		if !existe {
+			mudancas = append(mudancas, "Adicionar macro "+descreverMacro(m))
+			continue
+		}
+		tipo := tiposMacroDeclarada[m.Tipo]
+		switch {
+		case atual.tipo() != tipo:
+			mudancas = append(mudancas, fmt.Sprintf("Macro %s: tipo %s → %s", m.Macro, NomesTiposMacro[atual.tipo()], NomesTiposMacro[tipo]))
+		case tipo != "1" && atual.Valor != m.Valor:
+			mudancas = append(mudancas, fmt.Sprintf("Macro %s: %q → %q", m.Macro, atual.Valor, m.Valor))
+		}
+		if atual.Descricao != m.Descricao {
+			mudancas = append(mudancas, fmt.Sprintf("Macro %s: descrição %q → %q", m.Macro, atual.Descricao, m.Descricao))
+		}
+	}
+	for _, m := range atuais {
+		if !declaradas[m.Macro] {
+			mudancas = append(mudancas, "Remover macro "+m.Macro)
+		}
+	}
+	return mudancas
+}
+
+// descreverInterface formata a interface como "Agente 10.0.0.1:10050"
+func descreverInterface(tipo, ip, dns, porta string) string {
+	nome := TiposInterface[tipo]
+	if nome == "" {
+		nome = "Tipo " + tipo
+	}
+	return nome + " " + Interface{IP: ip, DNS: dns, Porta: porta}.Endereco()
+}
+
+// parearInterfaces associa cada interface desejada a uma do servidor: a de
+// mesmo tipo e endereço ou, na falta dela, a primeira livre do mesmo tipo.
+// Assim, mudar o IP altera a interface e preserva os itens ligados a ela.
+// Retorna o índice em atuais ou -1 para interfaces novas.
+func parearInterfaces(desejadas []ParamsInterface, atuais []interfaceServidor) []int {
+	pares := make([]int, len(desejadas))
+	usadas := make([]bool, len(atuais))
+	for i, d := range desejadas {
+		pares[i] = -1
+		for j, a := range atuais {
+			if !usadas[j] && a.Tipo == d.Type && a.IP == d.IP && a.DNS == d.DNS && a.Porta == d.Port {
+				pares[i] = j
+				usadas[j] = true
+				break
+			}
+		}
+	}
+	for i, d := range desejadas {
+		if pares[i] >= 0 {
+			continue
+		}
+		for j, a := range atuais {
+			if !usadas[j] && a.Tipo == d.Type {
+				pares[i] = j
+				usadas[j] = true
+				break
+			}
+		}
+	}
+	return pares
+}
+
+// compararInterfaces lista as interfaces adicionadas, alteradas e removidas.
+// Dos detalhes SNMP, só os declarados são comparados.
+func compararInterfaces(desejadas []ParamsInterface, atuais []interfaceServidor) []string {
+	pares := parearInterfaces(desejadas, atuais)
+	usadas := make([]bool, len(atuais))
+
+	var mudancas []string
+	for i, d := range desejadas {
+		novo := descreverInterface(d.Type, d.IP, d.DNS, d.Port)
+		j := pares[i]
+		if j < 0 {
+			mudancas = append(mudancas, "Adicionar interface "+novo)
+			continue
+		}
+		usadas[j] = true
+
+		a := atuais[j]
+		antigo := descreverInterface(a.Tipo, a.IP, a.DNS, a.Porta)
+		diferente := a.IP != d.IP || a.DNS != d.DNS || a.Porta != d.Port || a.Principal != d.Main || a.UsaIP != d.UseIP
+		for chave, valor := range d.Details {
+			if a.Detalhes[chave] != valor {
+				diferente = true
+			}
+		}
+		switch {
+		case antigo != novo:
+			mudancas = append(mudancas, fmt.Sprintf("Alterar interface %s → %s", antigo, novo))
+		case diferente:
+			mudancas = append(mudancas, "Alterar interface "+novo)
+		}
+	}
+	for j, a := range atuais {
+		if !usadas[j] {
+			mudancas = append(mudancas, "Remover interface "+descreverInterface(a.Tipo, a.IP, a.DNS, a.Porta))
+		}
+	}
+	return mudancas
+}
+
+// parametros monta os parâmetros de criação ou atualização do objeto, com os
+// campos gerenciados. Listas gerenciadas substituem as do servidor.
+func (p *planejador) parametros(o objetoDeclarado, atual *objetoServidor, ids *idsPlano) (interface{}, error) {
+	var grupos, templates, macros, tags, interfaces interface{}
+	if o.grupos != nil {
+		mapa := ids.gruposHosts
+		if o.tipoGrupo == TipoGrupoTemplates {
+			mapa = ids.gruposTemplates
+		}
+		lista, err := objetosNomes(mapa, "groupid", "grupo", o.grupos)
+		if err != nil {
+			return nil, err
+		}
+		grupos = lista
+	}
+	if o.templates != nil {
+		lista, err := objetosNomes(ids.templates, "templateid", "template", o.templates)
+		if err != nil {
+			return nil, err
+		}
+		templates = lista
+	}
+	if o.macros != nil {
+		macros = p.parametrosMacros(o.macros, atual)
+	}
+	if o.tags != nil {
+		tags = append([]Tag{}, o.tags...)
+	}
+	if o.interfaces != nil {
+		lista := parametrosInterfaces(o.interfaces)
+		if atual != nil {
+			for i, j := range parearInterfaces(lista, atual.Interfaces) {
+				if j >= 0 {
+					lista[i].ID = atual.Interfaces[j].ID
+				}
+			}
+		}
+		interfaces = lista
+	}
+
+	if o.tipo == TipoTemplate {
+		params := ParamsTemplateSalvar{
+			Name:      o.nomeVisivel,
+			Groups:    grupos,
+			Templates: templates,
+			Macros:    macros,
+			Tags:      tags,
+		}
+		if atual != nil {
+			params.TemplateID = atual.id()
+		} else {
+			params.Host = o.nome
+		}
+		return params, nil
+	}
+
+	params := ParamsHostSalvar{
+		Name:       o.nomeVisivel,
+		Groups:     grupos,
+		Templates:  templates,
+		Macros:     macros,
+		Tags:       tags,
+		Interfaces: interfaces,
+	}
+	if o.status != "" {
+		status, _ := strconv.Atoi(o.status)
+		params.Status = &status
+	}
+	if atual != nil {
+		params.HostID = atual.id()
+	} else {
+		params.Host = o.nome
+	}
+	return params, nil
+}
+
+// parametrosMacros monta a lista de macros. A partir do Zabbix 5.0 as macros
+// existentes são enviadas com o ID, o que permite manter o valor das secretas.
+func (p *planejador) parametrosMacros(desejadas []MacroDeclarada, atual *objetoServidor) []ParamsMacro {
+	porNome := make(map[string]macroServidor)
+	if atual != nil {
+		for _, m := range atual.Macros {
+			porNome[m.Macro] = m
+		}
+	}
+
+	params := make([]ParamsMacro, 0, len(desejadas))
+	for _, m := range desejadas {
+		valor := m.Valor
+		tipo := tiposMacroDeclarada[m.Tipo]
+		macro := ParamsMacro{Macro: m.Macro, Value: &valor, Description: m.Descricao}
+
+		existente, existe := porNome[m.Macro]
+		if existe && p.versao.AoMenos(5, 0) {
+			macro.ID = existente.ID
+			if tipo == "1" && existente.tipo() == "1" {
+				macro.Value = nil
+			}
+		}
+		if tipo != "0" || (existe && existente.tipo() != "0") {
+			macro.Type = tipo
+		}
+		params = append(params, macro)
+	}
+	return params
+}
+
+// planejarRemocoes remove os hosts e templates dos grupos de remoção que não
+// estão nos arquivos. Um template ainda vinculado nos arquivos não é removido.
+func (p *planejador) planejarRemocoes(d Declaracao) error {
+	hostsDeclarados := make(map[string]bool, len(d.Hosts))
+	for _, h := range d.Hosts {
+		hostsDeclarados[h.Host] = true
+	}
+	hostsRemovidos := make(map[string]bool)
+	for _, h := range p.estado.hostsEscopo {
+		if hostsDeclarados[h.Host] {
+			continue
+		}
+		hostsRemovidos[h.Host] = true
+		id := h.id()
+		p.adicionar(&OperacaoPlano{
+			Acao:   AcaoRemover,
+			Tipo:   TipoHost,
+			Nome:   h.Host,
+			Metodo: "host.delete",
+			montar: func(*idsPlano) (interface{}, error) {
+				return []string{id}, nil
+			},
+		})
+	}
+
+	if len(d.RemoverAusentes.GruposTemplates) == 0 {
+		return nil
+	}
+	escopo := make(map[string]bool, len(d.RemoverAusentes.GruposTemplates))
+	for _, nome := range d.RemoverAusentes.GruposTemplates {
+		escopo[nome] = true
+	}
+	templatesDeclarados := make(map[string]bool, len(d.Templates))
+	vinculadoPor := make(map[string]string)
+	for _, t := range d.Templates {
+		templatesDeclarados[t.Template] = true
+		for _, vinculado := range t.Templates {
+			vinculadoPor[vinculado] = fmt.Sprintf("%s %q", TipoTemplate, t.Template)
+		}
+	}
+	for _, h := range d.Hosts {
+		for _, vinculado := range h.Templates {
+			vinculadoPor[vinculado] = fmt.Sprintf("%s %q", TipoHost, h.Host)
+		}
+	}
+
+	nomes := make([]string, 0, len(p.estado.templates))
+	for nome := range p.estado.templates {
+		nomes = append(nomes, nome)
+	}
+	sort.Strings(nomes)
+
+	var problemas []error
+	for _, nome := range nomes {
+		t := p.estado.templates[nome]
+		noEscopo := false
+		for _, g := range t.grupos() {
+			noEscopo = noEscopo || escopo[g.Nome]
+		}
+		if !noEscopo || templatesDeclarados[nome] {
+			continue
+		}
+		if dono, vinculado := vinculadoPor[nome]; vinculado {
+			problemas = append(problemas, fmt.Errorf("o template %q está fora dos arquivos em um grupo de remoção, mas é vinculado por %s", nome, dono))
+			continue
+		}
+		// Hosts declarados seguem os vínculos dos arquivos e os fora deles que
+		// estão nos grupos de remoção também são removidos; os demais perderiam
+		// os itens e triggers do template sem aparecer no plano
+		var externos []string
+		for _, host := range p.estado.hostsVinculados[nome] {
+			if !hostsDeclarados[host] && !hostsRemovidos[host] {
+				externos = append(externos, fmt.Sprintf("%q", host))
+			}
+		}
+		if len(externos) > 0 {
+			problemas = append(problemas, fmt.Errorf("o template %q está fora dos arquivos em um grupo de remoção, mas está vinculado a hosts fora dos arquivos: %s",
+				nome, strings.Join(externos, ", ")))
+			continue
+		}
+		id := t.id()
+		p.adicionar(&OperacaoPlano{
+			Acao:   AcaoRemover,
+			Tipo:   TipoTemplate,
+			Nome:   nome,
+			Metodo: "template.delete",
+			montar: func(*idsPlano) (interface{}, error) {
+				return []string{id}, nil
+			},
+		})
+	}
+	return errors.Join(problemas...)
+}
+
+// respostaGravacao são os IDs retornados pelos métodos create e delete
+type respostaGravacao struct {
+	GrupoIDs    []string `json:"groupids"`
+	TemplateIDs []string `json:"templateids"`
+	HostIDs     []string `json:"hostids"`
+}
+
+// primeiroID retorna o ID do objeto criado
+func (r respostaGravacao) primeiroID() string {
+	for _, ids := range [][]string{r.GrupoIDs, r.TemplateIDs, r.HostIDs} {
+		if len(ids) > 0 {
+			return ids[0]
+		}
+	}
+	return ""
+}
+
+// AplicarPlano executa as operações do plano em ordem. Uma operação que falha
+// não interrompe as demais, mas as que dependem dela são puladas. A situação
+// e o erro de cada operação ficam no próprio plano; o erro retornado resume
+// as falhas.
+func (c *ClienteAPI) AplicarPlano(plano *Plano) error {
+	return c.AplicarPlanoCtx(context.Background(), plano)
+}
+
+// AplicarPlanoCtx é a variante de AplicarPlano que aceita um contexto. Com o
+// contexto cancelado, as operações restantes são puladas.
+func (c *ClienteAPI) AplicarPlanoCtx(ctx context.Context, plano *Plano) error {
+	situacoes := make(map[string]string, len(plano.Operacoes))
+	for _, op := range plano.Operacoes {
+		op.Situacao, op.Err = c.aplicarOperacao(ctx, plano.ids, op, situacoes)
+		situacoes[op.Chave()] = op.Situacao
+	}
+
+	falhas := plano.ContarSituacao(SituacaoFalhou)
+	puladas := plano.ContarSituacao(SituacaoPulada)
+	if falhas > 0 || puladas > 0 {
+		return fmt.Errorf("%d de %d operações falharam e %d foram puladas", falhas, len(plano.Operacoes), puladas)
+	}
+	return nil
+}
+
+// aplicarOperacao executa uma operação se as que ela depende foram aplicadas
+func (c *ClienteAPI) aplicarOperacao(ctx context.Context, ids *idsPlano, op *OperacaoPlano, situacoes map[string]string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return SituacaoPulada, err
+	}
+	for _, chave := range op.Depende {
+		if situacoes[chave] != SituacaoAplicada {
+			return SituacaoPulada, fmt.Errorf("depende de %s, que não foi aplicada", strings.Replace(chave, ":", " ", 1))
+		}
+	}
+
+	params, err := op.montar(ids)
+	if err != nil {
+		return SituacaoFalhou, err
+	}
+	resposta, err := ChamarCtx[respostaGravacao](ctx, c, op.Metodo, params)
+	if err != nil {
+		return SituacaoFalhou, err
+	}
+	if op.registrar != nil {
+		id := resposta.primeiroID()
+		if id == "" {
+			return SituacaoFalhou, fmt.Errorf("%s não retornou o ID criado", op.Metodo)
+		}
+		op.registrar(ids, id)
+	}
+	return SituacaoAplicada, nil
+}
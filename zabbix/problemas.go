@@ -0,0 +1,289 @@
+package zabbix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Ações de event.acknowledge. O parâmetro action é a soma das ações desejadas.
+const (
+	AcaoFechar             = 1
+	AcaoReconhecer         = 2
+	AcaoMensagem           = 4
+	AcaoSeveridade         = 8
+	AcaoDesfazerReconhecer = 16 // Zabbix 5.4+
+	AcaoSuprimir           = 32 // Zabbix 6.4+
+	AcaoRemoverSupressao   = 64 // Zabbix 6.4+
+)
+
+// NomesAcoes descreve cada ação de event.acknowledge, como exibida no histórico
+// de um problema e na trilha de auditoria
+var NomesAcoes = map[int]string{
+	AcaoFechar:             "Fechar",
+	AcaoReconhecer:         "Reconhecer",
+	AcaoMensagem:           "Mensagem",
+	AcaoSeveridade:         "Alterar severidade",
+	AcaoDesfazerReconhecer: "Desfazer reconhecimento",
+	AcaoSuprimir:           "Suprimir",
+	AcaoRemoverSupressao:   "Remover supressão",
+}
+
+// DescreverAcoes lista os nomes das ações presentes em uma soma de ações
+func DescreverAcoes(acoes int) []string {
+	var nomes []string
+	for bit := AcaoFechar; bit <= AcaoRemoverSupressao; bit <<= 1 {
+		if acoes&bit != 0 {
+			nomes = append(nomes, NomesAcoes[bit])
+		}
+	}
+	return nomes
+}
+
+// Reconhecimento é uma atualização feita em um problema: reconhecimento,
+// mensagem, mudança de severidade, supressão ou fechamento
+type Reconhecimento struct {
+	ID       string   `json:"acknowledgeid"`
+	Clock    Instante `json:"clock"`
+	Mensagem string   `json:"message"`
+	Acao     string   `json:"action"`
+	Usuario  string   `json:"username"`
+}
+
+// Acoes retorna as ações registradas na atualização
+func (r Reconhecimento) Acoes() []string {
+	acoes, _ := strconv.Atoi(r.Acao)
+	return DescreverAcoes(acoes)
+}
+
+// ProblemaAtivo é um problema ainda aberto, com os hosts da trigger e as
+// atualizações já feitas pelos operadores
+type ProblemaAtivo struct {
+	Problema
+	Reconhecido       bool
+	Suprimido         bool
+	PermiteFechar     bool // A trigger aceita fechamento manual
+	Reconhecimentos   []Reconhecimento
+	DadosOperacionais string
+}
+
+// Idade retorna há quanto tempo o problema está aberto
+func (p ProblemaAtivo) Idade(agora time.Time) time.Duration {
+	return agora.Sub(p.DataInicio.Time)
+}
+
+// problemaAtivoJSON é um problema como devolvido por problem.get com selectAcknowledges
+type problemaAtivoJSON struct {
+	Problema
+	Reconhecido       string           `json:"acknowledged"`
+	Suprimido         string           `json:"suppressed"`
+	DadosOperacionais string           `json:"opdata"`
+	Reconhecimentos   []Reconhecimento `json:"acknowledges"`
+}
+
+// FiltroProblemas restringe a lista de problemas ativos
+type FiltroProblemas struct {
+	SeveridadeMinima int  // 0 mostra todas
+	NaoReconhecidos  bool // Apenas problemas ainda não reconhecidos
+	Suprimidos       bool // Incluir problemas suprimidos por manutenção ou manualmente
+}
+
+// ObterProblemasAtivos lista os problemas abertos de triggers, do mais recente
+// para o mais antigo, com os hosts de cada trigger
+func (c *ClienteAPI) ObterProblemasAtivos(filtro FiltroProblemas) ([]ProblemaAtivo, error) {
+	return c.ObterProblemasAtivosCtx(context.Background(), filtro)
+}
+
+// ObterProblemasAtivosCtx é a variante de ObterProblemasAtivos que aceita um contexto
+func (c *ClienteAPI) ObterProblemasAtivosCtx(ctx context.Context, filtro FiltroProblemas) ([]ProblemaAtivo, error) {
+	params := ParamsProblemGet{
+		Output:             []string{"eventid", "objectid", "clock", "name", "severity", "acknowledged", "suppressed", "opdata"},
+		SelectAcknowledges: []string{"acknowledgeid", "clock", "message", "action", "username"},
+		SortField:          []string{"eventid"},
+		SortOrder:          "DESC",
+	}
+	for severidade := filtro.SeveridadeMinima; severidade <= 5 && filtro.SeveridadeMinima > 0; severidade++ {
+		params.Severities = append(params.Severities, severidade)
+	}
+	if filtro.NaoReconhecidos {
+		nao := false
+		params.Acknowledged = &nao
+	}
+	if !filtro.Suprimidos {
+		nao := false
+		params.Suppressed = &nao
+	}
+
+	brutos, err := ChamarCtx[[]problemaAtivoJSON](ctx, c, "problem.get", params)
+	if err != nil {
+		return nil, err
+	}
+	if len(brutos) == 0 {
+		return nil, nil
+	}
+
+	// problem.get não informa os hosts; eles vêm das triggers
+	idsTriggers := make([]string, 0, len(brutos))
+	vistos := make(map[string]bool)
+	for _, p := range brutos {
+		if !vistos[p.TriggerID] {
+			vistos[p.TriggerID] = true
+			idsTriggers = append(idsTriggers, p.TriggerID)
+		}
+	}
+	sort.Strings(idsTriggers)
+
+	triggers, err := ChamarCtx[[]struct {
+		ID               string `json:"triggerid"`
+		FechamentoManual string `json:"manual_close"`
+		Hosts            []Host `json:"hosts"`
+	}](ctx, c, "trigger.get", ParamsTriggerGet{
+		Output:      []string{"triggerid", "manual_close"},
+		TriggerIDs:  idsTriggers,
+		SelectHosts: []string{"hostid", "host", "name"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	hosts := make(map[string][]Host, len(triggers))
+	fechamento := make(map[string]bool, len(triggers))
+	for _, t := range triggers {
+		hosts[t.ID] = t.Hosts
+		fechamento[t.ID] = t.FechamentoManual == "1"
+	}
+
+	problemas := make([]ProblemaAtivo, 0, len(brutos))
+	for _, p := range brutos {
+		p.Problema.Hosts = hosts[p.TriggerID]
+		problemas = append(problemas, ProblemaAtivo{
+			Problema:          p.Problema,
+			Reconhecido:       p.Reconhecido == "1",
+			Suprimido:         p.Suprimido == "1",
+			PermiteFechar:     fechamento[p.TriggerID],
+			Reconhecimentos:   p.Reconhecimentos,
+			DadosOperacionais: p.DadosOperacionais,
+		})
+	}
+	return problemas, nil
+}
+
+// AtualizacaoProblemas descreve o que fazer com um ou mais problemas. Cada
+// campo preenchido vira uma ação de event.acknowledge.
+type AtualizacaoProblemas struct {
+	Reconhecer         bool
+	DesfazerReconhecer bool
+	Fechar             bool
+	Mensagem           string
+	Severidade         string    // Nova severidade, de "0" a "5"; vazio mantém
+	Suprimir           bool      // Suprimir até SuprimirAte
+	SuprimirAte        time.Time // Zero suprime por tempo indeterminado
+	RemoverSupressao   bool
+}
+
+// Acoes retorna a soma das ações de event.acknowledge pedidas
+func (a AtualizacaoProblemas) Acoes() int {
+	acoes := 0
+	if a.Fechar {
+		acoes |= AcaoFechar
+	}
+	if a.Reconhecer {
+		acoes |= AcaoReconhecer
+	}
+	if strings.TrimSpace(a.Mensagem) != "" {
+		acoes |= AcaoMensagem
+	}
+	if a.Severidade != "" {
+		acoes |= AcaoSeveridade
+	}
+	if a.DesfazerReconhecer {
+		acoes |= AcaoDesfazerReconhecer
+	}
+	if a.Suprimir {
+		acoes |= AcaoSuprimir
+	}
+	if a.RemoverSupressao {
+		acoes |= AcaoRemoverSupressao
+	}
+	return acoes
+}
+
+// Validar confere se as ações podem ser enviadas juntas
+func (a AtualizacaoProblemas) Validar() error {
+	switch {
+	case a.Acoes() == 0:
+		return errors.New("nenhuma ação escolhida")
+	case a.Reconhecer && a.DesfazerReconhecer:
+		return errors.New("não é possível reconhecer e desfazer o reconhecimento ao mesmo tempo")
+	case a.Suprimir && a.RemoverSupressao:
+		return errors.New("não é possível suprimir e remover a supressão ao mesmo tempo")
+	case a.Suprimir && !a.SuprimirAte.IsZero() && !a.SuprimirAte.After(time.Now()):
+		return errors.New("o fim da supressão deve estar no futuro")
+	}
+	if a.Severidade != "" {
+		if _, ok := NomesSeveridade[a.Severidade]; !ok {
+			return fmt.Errorf("severidade inválida: %q", a.Severidade)
+		}
+	}
+	return nil
+}
+
+// AtualizarProblemas aplica a atualização aos eventos com event.acknowledge e
+// retorna os IDs dos eventos alterados
+func (c *ClienteAPI) AtualizarProblemas(eventIDs []string, atualizacao AtualizacaoProblemas) ([]string, error) {
+	return c.AtualizarProblemasCtx(context.Background(), eventIDs, atualizacao)
+}
+
+// AtualizarProblemasCtx é a variante de AtualizarProblemas que aceita um contexto
+func (c *ClienteAPI) AtualizarProblemasCtx(ctx context.Context, eventIDs []string, atualizacao AtualizacaoProblemas) ([]string, error) {
+	if len(eventIDs) == 0 {
+		return nil, errors.New("nenhum problema selecionado")
+	}
+	if err := atualizacao.Validar(); err != nil {
+		return nil, err
+	}
+
+	acoes := atualizacao.Acoes()
+	if acoes&(AcaoDesfazerReconhecer|AcaoSuprimir|AcaoRemoverSupressao) != 0 {
+		versao, err := c.ObterVersaoCtx(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao negociar versão da API: %w", err)
+		}
+		if acoes&AcaoDesfazerReconhecer != 0 && !versao.AoMenos(5, 4) {
+			return nil, errors.New("desfazer o reconhecimento requer Zabbix 5.4 ou posterior")
+		}
+		if acoes&(AcaoSuprimir|AcaoRemoverSupressao) != 0 && !versao.AoMenos(6, 4) {
+			return nil, errors.New("suprimir problemas requer Zabbix 6.4 ou posterior")
+		}
+	}
+
+	params := ParamsEventAcknowledge{
+		EventIDs: eventIDs,
+		Action:   acoes,
+		Message:  strings.TrimSpace(atualizacao.Mensagem),
+	}
+	if atualizacao.Severidade != "" {
+		severidade, _ := strconv.Atoi(atualizacao.Severidade)
+		params.Severity = &severidade
+	}
+	if atualizacao.Suprimir && !atualizacao.SuprimirAte.IsZero() {
+		params.SuppressUntil = atualizacao.SuprimirAte.Unix()
+	}
+
+	resultado, err := ChamarCtx[struct {
+		EventIDs []interface{} `json:"eventids"`
+	}](ctx, c, "event.acknowledge", params)
+	if err != nil {
+		return nil, err
+	}
+
+	// Os IDs chegam como texto ou número, conforme a versão
+	ids := make([]string, len(resultado.EventIDs))
+	for i, id := range resultado.EventIDs {
+		ids[i] = fmt.Sprint(id)
+	}
+	return ids, nil
+}
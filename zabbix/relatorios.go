@@ -47,9 +47,53 @@ func GerarRelatorioCSVStream(hosts []Host, writer io.Writer) error {
 }
 
 func gerarCSV(hosts []Host, writer io.Writer) error {
+	escritor, err := NovoEscritorRelatorioCSV(writer)
+	if err != nil {
+		return err
+	}
+
+	for _, host := range hosts {
+		if err := escritor.Escrever(host); err != nil {
+			return err
+		}
+	}
+
+	return escritor.Finalizar()
+}
+
+// EscritorRelatorioCSV escreve o relatório CSV um host por vez, permitindo
+// gerar o relatório enquanto os hosts são lidos da API
+type EscritorRelatorioCSV struct {
+	csvWriter *csv.Writer
+
+	// Análise de problemas por host, de onde vem o tempo médio de resolução
+	analises map[string]AnaliseMensal
+	// Disponibilidade apurada de cada host
+	disponibilidades map[string]Disponibilidade
+}
+
+// UsarDisponibilidade informa a disponibilidade apurada de cada host, como a
+// de DisponibilidadeTriggers. Sem ela, a coluna de disponibilidade fica como "N/A".
+func (e *EscritorRelatorioCSV) UsarDisponibilidade(hosts []Disponibilidade) {
+	e.disponibilidades = make(map[string]Disponibilidade, len(hosts))
+	for _, d := range hosts {
+		e.disponibilidades[d.ID] = d
+	}
+}
+
+// UsarAnalises informa a análise de problemas usada nas colunas de resolução.
+// Sem ela, o tempo médio de resolução fica como "N/A".
+func (e *EscritorRelatorioCSV) UsarAnalises(analises []AnaliseMensal) {
+	e.analises = make(map[string]AnaliseMensal, len(analises))
+	for _, a := range analises {
+		e.analises[a.HostID] = a
+	}
+}
+
+// NovoEscritorRelatorioCSV cria o escritor e já escreve a linha de cabeçalhos
+func NovoEscritorRelatorioCSV(writer io.Writer) (*EscritorRelatorioCSV, error) {
 	csvWriter := csv.NewWriter(writer)
 	csvWriter.Comma = ';'
-	defer csvWriter.Flush()
 
 	// Cabeçalhos expandidos
 	cabecalhos := []string{
@@ -60,79 +104,83 @@ func gerarCSV(hosts []Host, writer io.Writer) error {
 		"Problemas Últimas 24h", "Tempo Médio de Resolução",
 		"Performance CPU (%)", "Performance Memória (%)",
 		"Interface Principal", "Tráfego Entrada (avg)", "Tráfego Saída (avg)",
+		"Nome Visível", "Grupos", "Templates", "Tags",
 	}
 
 	if err := csvWriter.Write(cabecalhos); err != nil {
-		return fmt.Errorf("erro ao escrever cabeçalhos: %w", err)
+		return nil, fmt.Errorf("erro ao escrever cabeçalhos: %w", err)
 	}
 
-	for _, host := range hosts {
-		status := StatusHost[host.Status]
-		if status == "" {
-			status = "Desconhecido"
-		}
+	return &EscritorRelatorioCSV{csvWriter: csvWriter}, nil
+}
 
-		// Calcular métricas
-		itemsAtivos := contarItemsAtivos(host.Items)
-		itemsProblema := contarItemsComProblema(host.Items)
-		triggersAtivas := contarTriggersAtivas(host.Triggers)
-		triggersProblema := contarTriggersComProblema(host.Triggers)
-
-		linha := []string{
-			host.ID,
-			host.Nome,
-			status,
-			fmt.Sprintf("%.2f", calcularDisponibilidade(host)),
-			obterUltimaColeta(host).Format("2006-01-02 15:04:05"),
-			fmt.Sprintf("%d", len(host.Items)),
-			fmt.Sprintf("%d", itemsAtivos),
-			fmt.Sprintf("%d", itemsProblema),
-			fmt.Sprintf("%d", len(host.Triggers)),
-			fmt.Sprintf("%d", triggersAtivas),
-			fmt.Sprintf("%d", triggersProblema),
-			fmt.Sprintf("%d", contarProblemasRecentes(host)),
-			calcularTempoMedioResolucao(host),
-			fmt.Sprintf("%.2f", obterPerformanceCPU(host)),
-			fmt.Sprintf("%.2f", obterPerformanceMemoria(host)),
-			obterInterfacePrincipal(host),
-			formatarTrafego(obterTrafegoDados(host, "in")),
-			formatarTrafego(obterTrafegoDados(host, "out")),
-		}
+// Escrever adiciona a linha de um host ao relatório
+func (e *EscritorRelatorioCSV) Escrever(host Host) error {
+	status := StatusHost[host.Status]
+	if status == "" {
+		status = "Desconhecido"
+	}
 
-		if err := csvWriter.Write(linha); err != nil {
-			return fmt.Errorf("erro ao escrever linha: %w", err)
-		}
+	// Calcular métricas
+	itemsAtivos := contarItemsAtivos(host.Items)
+	itemsProblema := contarItemsComProblema(host.Items)
+	triggersAtivas := contarTriggersAtivas(host.Triggers)
+	triggersProblema := contarTriggersComProblema(host.Triggers)
+
+	linha := []string{
+		host.ID,
+		host.Nome,
+		status,
+		e.disponibilidade(host),
+		obterUltimaColeta(host).Format("2006-01-02 15:04:05"),
+		fmt.Sprintf("%d", len(host.Items)),
+		fmt.Sprintf("%d", itemsAtivos),
+		fmt.Sprintf("%d", itemsProblema),
+		fmt.Sprintf("%d", len(host.Triggers)),
+		fmt.Sprintf("%d", triggersAtivas),
+		fmt.Sprintf("%d", triggersProblema),
+		fmt.Sprintf("%d", contarProblemasRecentes(host)),
+		e.tempoMedioResolucao(host),
+		fmt.Sprintf("%.2f", obterPerformanceCPU(host)),
+		fmt.Sprintf("%.2f", obterPerformanceMemoria(host)),
+		obterInterfacePrincipal(host),
+		formatarTrafego(obterTrafegoDados(host, "in")),
+		formatarTrafego(obterTrafegoDados(host, "out")),
+		host.NomeVisivel,
+		juntarNomes(host.Grupos, func(g GrupoHost) string { return g.Nome }),
+		juntarNomes(host.Templates, func(t TemplateVinculado) string { return t.Nome }),
+		juntarNomes(host.Tags, Tag.String),
+	}
+
+	if err := e.csvWriter.Write(linha); err != nil {
+		return fmt.Errorf("erro ao escrever linha: %w", err)
 	}
 
 	return nil
 }
 
+// Finalizar descarrega as linhas pendentes e retorna o primeiro erro de escrita
+func (e *EscritorRelatorioCSV) Finalizar() error {
+	e.csvWriter.Flush()
+	return e.csvWriter.Error()
+}
+
 // Funções auxiliares
-func calcularDisponibilidade(host Host) float64 {
-	problemasRecentes := 0
-	for _, trigger := range host.Triggers {
-		if trigger.Valor == "1" { // problema ativo
-			problemasRecentes++
-		}
-	}
-	total := float64(len(host.Triggers))
-	if total == 0 {
-		return 100.0
+
+// disponibilidade retorna a disponibilidade apurada do host, em porcentagem
+func (e *EscritorRelatorioCSV) disponibilidade(host Host) string {
+	d, ok := e.disponibilidades[host.ID]
+	if !ok {
+		return "N/A"
 	}
-	return 100.0 * (1.0 - float64(problemasRecentes)/total)
+	return fmt.Sprintf("%.2f", d.Percentual)
 }
 
 func obterUltimaColeta(host Host) time.Time {
 	ultimaColeta := time.Time{}
 	for _, item := range host.Items {
-		if item.UltimoValor != "" {
-			timestamp, err := strconv.ParseInt(item.UltimaAlteracao, 10, 64)
-			if err == nil {
-				itemColeta := time.Unix(timestamp, 0)
-				if itemColeta.After(ultimaColeta) {
-					ultimaColeta = itemColeta
-				}
-			}
+		if item.UltimoValor != "" && item.UltimaColeta.After(ultimaColeta) {
+			ultimaColeta = item.UltimaColeta.Time
 		}
 	}
 	return ultimaColeta
@@ -188,24 +236,14 @@ func contarProblemasRecentes(host Host) int {
 	return count
 }
 
-func calcularTempoMedioResolucao(host Host) string {
-	var total time.Duration
-	count := 0
-	for _, trigger := range host.Triggers {
-		if trigger.UltimaAlteracao != "" {
-			timestamp, err := strconv.ParseInt(trigger.UltimaAlteracao, 10, 64)
-			if err == nil {
-				duracao := time.Since(time.Unix(timestamp, 0))
-				total += duracao
-				count++
-			}
-		}
-	}
-	if count == 0 {
+// tempoMedioResolucao retorna o tempo médio até a recuperação dos problemas
+// do host na análise informada ao escritor
+func (e *EscritorRelatorioCSV) tempoMedioResolucao(host Host) string {
+	analise, ok := e.analises[host.ID]
+	if !ok || analise.DuracaoMedia == 0 {
 		return "N/A"
 	}
-	media := total / time.Duration(count)
-	return media.Round(time.Minute).String()
+	return FormatarDuracao(analise.DuracaoMedia)
 }
 
 func obterPerformanceCPU(host Host) float64 {
@@ -230,11 +268,45 @@ func obterPerformanceMemoria(host Host) float64 {
 	return 0
 }
 
+// obterInterfacePrincipal prefere a interface principal do agente, depois
+// qualquer interface principal e, por fim, a primeira da lista
 func obterInterfacePrincipal(host Host) string {
-	if len(host.Interfaces) > 0 {
-		return fmt.Sprintf("%s (%s)", host.Interfaces[0].IP, host.Interfaces[0].DNS)
+	if len(host.Interfaces) == 0 {
+		return "N/A"
+	}
+
+	escolhida := host.Interfaces[0]
+	melhor := 0
+	for _, i := range host.Interfaces {
+		pontos := 0
+		if i.Principal == "1" {
+			pontos += 2
+			if i.Tipo == "1" {
+				pontos++
+			}
+		}
+		if pontos > melhor {
+			escolhida, melhor = i, pontos
+		}
+	}
+
+	switch {
+	case escolhida.IP != "" && escolhida.DNS != "":
+		return fmt.Sprintf("%s (%s)", escolhida.IP, escolhida.DNS)
+	case escolhida.IP != "":
+		return escolhida.IP
+	default:
+		return escolhida.DNS
+	}
+}
+
+// juntarNomes une os nomes de uma lista separados por vírgula
+func juntarNomes[T any](lista []T, nome func(T) string) string {
+	nomes := make([]string, 0, len(lista))
+	for _, item := range lista {
+		nomes = append(nomes, nome(item))
 	}
-	return "N/A"
+	return strings.Join(nomes, ", ")
 }
 
 func obterTrafegoDados(host Host, direcao string) float64 {
@@ -253,6 +325,99 @@ func formatarTrafego(bytes float64) string {
 	return "1.02 MB/s" // Placeholder -  Needs implementation for proper formatting.
 }
 
-
 //Necessary structs moved to tipos.go
-// Estruturas movidas para tipos.go
\ No newline at end of file
+// Estruturas movidas para tipos.go
+
+// EscreverRelatorioDisponibilidadeCSV escreve o relatório de disponibilidade,
+// uma linha por serviço, grupo ou host, comparando cada um com a meta slo
+func EscreverRelatorioDisponibilidadeCSV(writer io.Writer, relatorio RelatorioDisponibilidade, slo float64) error {
+	csvWriter := csv.NewWriter(writer)
+	csvWriter.Comma = ';'
+
+	cabecalhos := []string{
+		"Tipo", "ID", "Nome", "Disponibilidade (%)", "Tempo Ativo", "Tempo Indisponível",
+		"SLO (%)", "Cumpre SLO", "Início", "Fim", "Fonte",
+	}
+	if err := csvWriter.Write(cabecalhos); err != nil {
+		return fmt.Errorf("erro ao escrever cabeçalhos: %w", err)
+	}
+
+	fonte := "Triggers de disponibilidade"
+	if relatorio.SLA != nil {
+		fonte = "SLA " + relatorio.SLA.Nome
+	}
+
+	secoes := []struct {
+		tipo  string
+		itens []Disponibilidade
+	}{
+		{"Serviço", relatorio.Servicos},
+		{"Grupo", relatorio.Grupos},
+		{"Host", relatorio.Hosts},
+	}
+	for _, secao := range secoes {
+		for _, d := range secao.itens {
+			cumpre := "Não"
+			if d.CumpreSLO(slo) {
+				cumpre = "Sim"
+			}
+			linha := []string{
+				secao.tipo,
+				d.ID,
+				d.Nome,
+				fmt.Sprintf("%.3f", d.Percentual),
+				FormatarDuracao(d.TempoAtivo),
+				FormatarDuracao(d.TempoIndisponivel),
+				fmt.Sprintf("%.3f", slo),
+				cumpre,
+				relatorio.Inicio.Format("2006-01-02 15:04:05"),
+				relatorio.Fim.Add(-time.Second).Format("2006-01-02 15:04:05"),
+				fonte,
+			}
+			if err := csvWriter.Write(linha); err != nil {
+				return fmt.Errorf("erro ao escrever linha: %w", err)
+			}
+		}
+	}
+
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// EscreverMacrosEfetivasCSV escreve o valor efetivo de uma macro em cada host,
+// com o nível de onde ele vem e a cadeia de herança completa. Valores de
+// macros secretas saem mascarados.
+func EscreverMacrosEfetivasCSV(writer io.Writer, macro string, efetivas []MacroEfetiva) error {
+	csvWriter := csv.NewWriter(writer)
+	csvWriter.Comma = ';'
+
+	cabecalhos := []string{"Host", "Macro", "Valor Efetivo", "Tipo", "Origem", "Cadeia de Herança"}
+	if err := csvWriter.Write(cabecalhos); err != nil {
+		return fmt.Errorf("erro ao escrever cabeçalhos: %w", err)
+	}
+
+	for _, e := range efetivas {
+		linha := []string{e.Host, macro, "", "", "Não definida", ""}
+		if e.Definida() {
+			efetiva := e.Efetiva()
+			cadeia := make([]string, len(e.Cadeia))
+			for i, d := range e.Cadeia {
+				cadeia[i] = fmt.Sprintf("%s: %s", d.DescricaoOrigem(), d.Macro.ValorExibido())
+			}
+			linha = []string{
+				e.Host,
+				macro,
+				efetiva.Macro.ValorExibido(),
+				efetiva.Macro.NomeTipo(),
+				efetiva.DescricaoOrigem(),
+				strings.Join(cadeia, " > "),
+			}
+		}
+		if err := csvWriter.Write(linha); err != nil {
+			return fmt.Errorf("erro ao escrever linha: %w", err)
+		}
+	}
+
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
@@ -0,0 +1,125 @@
+package zabbix
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Valores padrão para novas tentativas e para o disjuntor
+const (
+	TentativasPadrao     = 3
+	EsperaInicialPadrao  = 500 * time.Millisecond
+	EsperaMaximaPadrao   = 5 * time.Second
+	LimiteFalhasPadrao   = 5
+	TempoDisjuntorPadrao = 30 * time.Second
+)
+
+// metodosLeitura são os métodos que apenas leem dados sem terminar em ".get"
+var metodosLeitura = map[string]bool{
+	"apiinfo.version":          true,
+	"configuration.export":     true,
+	"sla.getsli":               true,
+	"user.checkAuthentication": true,
+}
+
+// metodoIdempotente informa se o método apenas lê dados e pode ser repetido com segurança
+func metodoIdempotente(metodo string) bool {
+	return strings.HasSuffix(metodo, ".get") || metodosLeitura[metodo]
+}
+
+// pedidosIdempotentes informa se todos os pedidos (de um lote) podem ser repetidos
+func pedidosIdempotentes(pedidos []*pedidoRPC) bool {
+	for _, pedido := range pedidos {
+		if !metodoIdempotente(pedido.Metodo) {
+			return false
+		}
+	}
+	return true
+}
+
+// falhaRecuperavel identifica erros transitórios: falhas de rede e as respostas
+// 502/503/504 do balanceador durante reinícios do PHP-FPM
+func falhaRecuperavel(err error) bool {
+	var erroTransporte *ErroTransporte
+	if !errors.As(err, &erroTransporte) {
+		return false
+	}
+
+	switch erroTransporte.StatusCode {
+	case 0:
+		return erroTransporte.Operacao == "requisição"
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// esperaRepeticao calcula o intervalo antes da tentativa seguinte: dobra a cada
+// tentativa até EsperaMaxima, com variação aleatória para não sincronizar clientes
+func (c *ClienteAPI) esperaRepeticao(tentativa int) time.Duration {
+	espera := c.config.EsperaInicial << uint(tentativa)
+	if espera <= 0 || espera > c.config.EsperaMaxima {
+		espera = c.config.EsperaMaxima
+	}
+	metade := espera / 2
+	return metade + time.Duration(rand.Int63n(int64(metade)+1))
+}
+
+// enviarComRepeticao envia o corpo passando pelo disjuntor e repete falhas
+// transitórias quando os métodos são idempotentes. O cancelamento do contexto
+// pelo chamador interrompe as tentativas sem contar como falha do servidor.
+func (c *ClienteAPI) enviarComRepeticao(ctx context.Context, corpo interface{}, tokenBearer string, destino interface{}, idempotente bool) error {
+	// Um perfil mal configurado nem chega ao servidor e não passa pelo disjuntor
+	if c.erroTransporte != nil {
+		return c.erroTransporte
+	}
+
+	tentativas := 1
+	if idempotente {
+		tentativas = c.config.Tentativas
+	}
+
+	var err error
+	for tentativa := 0; tentativa < tentativas; tentativa++ {
+		if tentativa > 0 {
+			espera := c.esperaRepeticao(tentativa - 1)
+			log.Printf("Falha transitória na API do Zabbix (%v), nova tentativa em %s", err, espera.Round(time.Millisecond))
+			select {
+			case <-time.After(espera):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		if err = c.disjuntor.Permitir(); err != nil {
+			return err
+		}
+
+		err = c.enviarRequisicao(ctx, corpo, tokenBearer, destino)
+		if err != nil && ctx.Err() != nil {
+			// O cancelamento não diz nada sobre o servidor
+			c.disjuntor.LiberarTeste()
+			return err
+		}
+		// Só uma resposta 200 com JSON válido, mesmo que seja um erro JSON-RPC,
+		// mostra que o servidor está respondendo. Erros de quem lê a resposta
+		// em fluxo também chegam aqui depois de uma resposta válida.
+		var erroTransporte *ErroTransporte
+		if !errors.As(err, &erroTransporte) {
+			c.disjuntor.RegistrarSucesso()
+			return err
+		}
+
+		c.disjuntor.RegistrarFalha()
+		if !falhaRecuperavel(err) {
+			return err
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,147 @@
+package zabbix
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// atualizar regrava os arquivos .golden com a saída atual:
+//
+//	go test ./zabbix -run TestRespostas -update
+var atualizar = flag.Bool("update", false, "regrava os arquivos .golden em testdata")
+
+// respostasTeste liga cada resposta gravada em testdata/respostas ao tipo em
+// que ela é decodificada pelo cliente
+var respostasTeste = []struct {
+	arquivo string
+	destino func() interface{}
+}{
+	{"host.get-6.0.json", func() interface{} { return &[]Host{} }},
+	{"host.get-6.4.json", func() interface{} { return &[]Host{} }},
+	{"item.get.json", func() interface{} { return &[]Item{} }},
+	{"trigger.get.json", func() interface{} { return &[]Trigger{} }},
+	{"event.get.json", func() interface{} { return &[]Evento{} }},
+	{"problem.get.json", func() interface{} { return &[]Problema{} }},
+	{"history.get.json", func() interface{} { return &[]RegistroHistorico{} }},
+	{"trend.get.json", func() interface{} { return &[]RegistroTendencia{} }},
+}
+
+// TestRespostas decodifica respostas da API e compara o resultado, escrito
+// de volta em JSON, com o arquivo .golden correspondente
+func TestRespostas(t *testing.T) {
+	for _, caso := range respostasTeste {
+		t.Run(caso.arquivo, func(t *testing.T) {
+			caminho := filepath.Join("testdata", "respostas", caso.arquivo)
+			dados, err := os.ReadFile(caminho)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var resposta RespostaAPI
+			if err := json.Unmarshal(dados, &resposta); err != nil {
+				t.Fatalf("resposta inválida: %v", err)
+			}
+			metodo := strings.SplitN(caso.arquivo, ".json", 2)[0]
+			destino := caso.destino()
+			if err := decodificarResultado(metodo, &resposta, destino); err != nil {
+				t.Fatalf("decodificação: %v", err)
+			}
+
+			obtido, err := json.MarshalIndent(destino, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			obtido = append(obtido, '\n')
+
+			arquivoGolden := strings.TrimSuffix(caminho, ".json") + ".golden"
+			if *atualizar {
+				if err := os.WriteFile(arquivoGolden, obtido, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			esperado, err := os.ReadFile(arquivoGolden)
+			if err != nil {
+				t.Fatalf("%v (rode com -update para criar)", err)
+			}
+			if !bytes.Equal(obtido, esperado) {
+				t.Errorf("resultado diferente de %s:\n%s", arquivoGolden, obtido)
+			}
+		})
+	}
+}
+
+func TestInstanteUnmarshalJSON(t *testing.T) {
+	casos := []struct {
+		entrada  string
+		segundos int64
+		invalido bool
+	}{
+		{`"1697447402"`, 1697447402, false},
+		{`1697447402`, 1697447402, false},
+		{`"0"`, 0, false},
+		{`0`, 0, false},
+		{`""`, 0, false},
+		{`null`, 0, false},
+		{`"2023-10-16"`, 0, true},
+		{`1.5`, 0, true},
+	}
+
+	for _, caso := range casos {
+		var instante Instante
+		err := json.Unmarshal([]byte(caso.entrada), &instante)
+		if caso.invalido {
+			if err == nil {
+				t.Errorf("%s: esperado erro, obtido %v", caso.entrada, instante)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: erro inesperado: %v", caso.entrada, err)
+			continue
+		}
+		if instante.Segundos() != caso.segundos {
+			t.Errorf("%s: obtido %d, esperado %d", caso.entrada, instante.Segundos(), caso.segundos)
+		}
+		if caso.segundos == 0 && !instante.IsZero() {
+			t.Errorf("%s: esperado horário não definido, obtido %v", caso.entrada, instante.Time)
+		}
+	}
+}
+
+// TestProblemaHorarios confere que problem.get, que antes falhava ao
+// decodificar clock e r_clock, devolve os horários certos
+func TestProblemaHorarios(t *testing.T) {
+	dados, err := os.ReadFile(filepath.Join("testdata", "respostas", "problem.get.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resposta RespostaAPI
+	if err := json.Unmarshal(dados, &resposta); err != nil {
+		t.Fatal(err)
+	}
+	var problemas []Problema
+	if err := decodificarResultado("problem.get", &resposta, &problemas); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(problemas) != 2 {
+		t.Fatalf("obtidos %d problemas, esperados 2", len(problemas))
+	}
+	if got, want := problemas[0].DataInicio.Time, time.Unix(1697447402, 0); !got.Equal(want) {
+		t.Errorf("início: obtido %v, esperado %v", got, want)
+	}
+	if got, want := problemas[0].DataFim.Sub(problemas[0].DataInicio.Time), 40*time.Minute; got != want {
+		t.Errorf("duração: obtida %v, esperada %v", got, want)
+	}
+	if !problemas[1].DataFim.IsZero() {
+		t.Errorf("problema aberto com fim %v", problemas[1].DataFim.Time)
+	}
+}
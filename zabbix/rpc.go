@@ -0,0 +1,537 @@
+package zabbix
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// pedidoRPC representa uma chamada JSON-RPC 2.0 para a API do Zabbix
+type pedidoRPC struct {
+	Jsonrpc string      `json:"jsonrpc"`
+	Metodo  string      `json:"method"`
+	Params  interface{} `json:"params"`
+	Auth    string      `json:"auth,omitempty"`
+	ID      uint64      `json:"id"`
+}
+
+// novoPedido cria um pedido com um ID crescente e único neste cliente
+func (c *ClienteAPI) novoPedido(metodo string, params interface{}) *pedidoRPC {
+	if params == nil {
+		params = struct{}{}
+	}
+	return &pedidoRPC{
+		Jsonrpc: "2.0",
+		Metodo:  metodo,
+		Params:  params,
+		ID:      c.proximoID.Add(1),
+	}
+}
+
+// Chamar executa um método da API e decodifica o resultado no tipo R.
+// Os parâmetros normalmente são uma das structs Params* deste pacote.
+//
+//	hosts, err := zabbix.Chamar[[]zabbix.Host](cliente, "host.get", zabbix.ParamsHostGet{...})
+func Chamar[R any, P any](c *ClienteAPI, metodo string, params P) (R, error) {
+	return ChamarCtx[R](context.Background(), c, metodo, params)
+}
+
+// ChamarCtx é a variante de Chamar que aceita um contexto. O cancelamento do
+// contexto interrompe a requisição em andamento e as novas tentativas.
+func ChamarCtx[R any, P any](ctx context.Context, c *ClienteAPI, metodo string, params P) (R, error) {
+	var resultado R
+
+	resposta, err := c.executarComCache(ctx, c.novoPedido(metodo, params))
+	if err != nil {
+		return resultado, err
+	}
+
+	err = decodificarResultado(metodo, &resposta, &resultado)
+	return resultado, err
+}
+
+// Percorrer executa um método que retorna uma lista e chama fn para cada
+// elemento à medida que a resposta é lida, sem carregá-la inteira na memória.
+// Um erro retornado por fn interrompe a leitura e é devolvido sem alterações.
+func Percorrer[R any, P any](c *ClienteAPI, metodo string, params P, fn func(R) error) error {
+	return PercorrerCtx(context.Background(), c, metodo, params, fn)
+}
+
+// PercorrerCtx é a variante de Percorrer que aceita um contexto
+func PercorrerCtx[R any, P any](ctx context.Context, c *ClienteAPI, metodo string, params P, fn func(R) error) error {
+	ttl := c.ttlCache(ctx, metodo)
+	chave, ok := c.chaveCache("fluxo", metodo, params)
+	if ttl <= 0 || !ok {
+		return percorrer(ctx, c, metodo, params, fn, nil)
+	}
+
+	if gravada, ok := c.config.Cache.Get(chave); ok {
+		return reproduzir(gravada.(respostaGravada), fn)
+	}
+
+	// O corpo é gravado enquanto é lido; quem aguarda a mesma chamada relê a gravação
+	valor, err, compartilhado := c.chamadas.fazer(ctx, chave, func() (interface{}, error) {
+		gravada := &respostaGravada{}
+		err := percorrer(ctx, c, metodo, params, func(elemento R) error {
+			if err := fn(elemento); err != nil {
+				return &erroConsumidor{err: err}
+			}
+			return nil
+		}, gravada)
+		if err != nil {
+			return nil, err
+		}
+		c.config.Cache.Set(chave, *gravada, ttl)
+		return *gravada, nil
+	})
+
+	if compartilhado {
+		if falhaDoLider(ctx, err) {
+			return percorrer(ctx, c, metodo, params, fn, nil)
+		}
+		if err != nil {
+			return err
+		}
+		return reproduzir(valor.(respostaGravada), fn)
+	}
+
+	var consumidor *erroConsumidor
+	if errors.As(err, &consumidor) {
+		return consumidor.err
+	}
+	return err
+}
+
+// percorrer envia o pedido e lê a resposta em fluxo, renovando a sessão se
+// necessário. Se gravacao não for nil, recebe o corpo da resposta bem-sucedida.
+func percorrer[R any, P any](ctx context.Context, c *ClienteAPI, metodo string, params P, fn func(R) error, gravacao *respostaGravada) error {
+	versao, err := c.ObterVersaoCtx(ctx)
+	if err != nil {
+		return fmt.Errorf("erro ao negociar versão da API: %w", err)
+	}
+
+	token, err := c.tokenAutenticacao(ctx)
+	if err != nil {
+		return err
+	}
+
+	erroRPC, err := percorrerAutenticado(ctx, c, versao, token, metodo, params, fn, gravacao)
+	if err == nil && erroRPC != nil && c.usaSessao() && sessaoEncerrada(erroRPC.Message, erroRPC.Data) {
+		// Uma resposta de erro não traz elementos, então o pedido pode ser refeito
+		log.Printf("Sessão do Zabbix encerrada, realizando novo login")
+		token, err = c.renovarSessao(ctx, token)
+		if err != nil {
+			return err
+		}
+		erroRPC, err = percorrerAutenticado(ctx, c, versao, token, metodo, params, fn, gravacao)
+	}
+	if err != nil {
+		return err
+	}
+	if erroRPC != nil {
+		return erroDaResposta(metodo, &RespostaAPI{Error: erroRPC})
+	}
+
+	return nil
+}
+
+// percorrerAutenticado envia um único pedido e lê a resposta como fluxo
+func percorrerAutenticado[R any, P any](ctx context.Context, c *ClienteAPI, versao Versao, token string, metodo string, params P, fn func(R) error, gravacao *respostaGravada) (*ErroRPC, error) {
+	pedido := c.novoPedido(metodo, params)
+
+	tokenBearer := ""
+	if versao.SuportaBearer() {
+		tokenBearer = token
+	} else {
+		pedido.Auth = token
+	}
+
+	var erroRPC *ErroRPC
+	ler := leitorResposta(func(corpo io.Reader) error {
+		var copia bytes.Buffer
+		if gravacao != nil {
+			corpo = io.TeeReader(corpo, &copia)
+		}
+
+		var err error
+		erroRPC, err = lerRespostaFluxo(corpo, pedido, fn)
+		if err == nil && erroRPC == nil && gravacao != nil {
+			*gravacao = respostaGravada{corpo: copia.Bytes(), id: pedido.ID}
+		}
+		return err
+	})
+
+	err := c.enviarComRepeticao(ctx, pedido, tokenBearer, ler, metodoIdempotente(metodo))
+	return erroRPC, err
+}
+
+// leitorResposta é um destino de enviarRequisicao que lê o corpo diretamente
+// da conexão em vez de decodificá-lo de uma só vez
+type leitorResposta func(corpo io.Reader) error
+
+// lerRespostaFluxo percorre o objeto JSON-RPC token a token, decodificando um
+// elemento de "result" por vez. O ID é conferido ao final, pois o Zabbix o
+// envia depois do resultado.
+func lerRespostaFluxo[R any](corpo io.Reader, pedido *pedidoRPC, fn func(R) error) (*ErroRPC, error) {
+	dec := json.NewDecoder(corpo)
+	falha := func(err error) error {
+		return &ErroTransporte{Operacao: "decodificação da resposta", Err: err}
+	}
+
+	if err := esperarDelimitador(dec, '{'); err != nil {
+		return nil, falha(err)
+	}
+
+	var resposta RespostaAPI
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return nil, falha(err)
+		}
+
+		switch token {
+		case "result":
+			if err := esperarDelimitador(dec, '['); err != nil {
+				return nil, falha(err)
+			}
+			for dec.More() {
+				var elemento R
+				if err := dec.Decode(&elemento); err != nil {
+					return nil, falha(err)
+				}
+				if err := fn(elemento); err != nil {
+					return nil, err
+				}
+			}
+			if err := esperarDelimitador(dec, ']'); err != nil {
+				return nil, falha(err)
+			}
+		case "error":
+			err = dec.Decode(&resposta.Error)
+		case "id":
+			err = dec.Decode(&resposta.ID)
+		default:
+			var ignorado json.RawMessage
+			err = dec.Decode(&ignorado)
+		}
+		if err != nil {
+			return nil, falha(err)
+		}
+	}
+
+	if err := esperarDelimitador(dec, '}'); err != nil {
+		return nil, falha(err)
+	}
+	if err := conferirID(pedido, &resposta); err != nil {
+		return nil, err
+	}
+
+	return resposta.Error, nil
+}
+
+// esperarDelimitador lê o próximo token e confirma que é o delimitador esperado
+func esperarDelimitador(dec *json.Decoder, esperado json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if token != esperado {
+		return fmt.Errorf("esperado %q, encontrado %v", esperado, token)
+	}
+	return nil
+}
+
+// Lote agrupa várias chamadas em uma única requisição JSON-RPC 2.0 (batch)
+type Lote struct {
+	cliente  *ClienteAPI
+	pedidos  []*pedidoRPC
+	destinos []func(*RespostaAPI)
+}
+
+// ResultadoLote recebe o resultado de uma chamada adicionada a um Lote
+// depois que Lote.Executar retorna
+type ResultadoLote[R any] struct {
+	Valor R
+	Err   error
+}
+
+// Obter retorna o valor e o erro da chamada
+func (r *ResultadoLote[R]) Obter() (R, error) {
+	return r.Valor, r.Err
+}
+
+// NovoLote cria um lote vazio de chamadas
+func (c *ClienteAPI) NovoLote() *Lote {
+	return &Lote{cliente: c}
+}
+
+// AdicionarAoLote inclui uma chamada no lote. O resultado só é preenchido
+// depois de Lote.Executar.
+func AdicionarAoLote[R any, P any](l *Lote, metodo string, params P) *ResultadoLote[R] {
+	resultado := &ResultadoLote[R]{}
+	l.pedidos = append(l.pedidos, l.cliente.novoPedido(metodo, params))
+	l.destinos = append(l.destinos, func(resposta *RespostaAPI) {
+		resultado.Err = decodificarResultado(metodo, resposta, &resultado.Valor)
+	})
+	return resultado
+}
+
+// Executar envia todas as chamadas do lote em um único POST. O erro retornado
+// se refere ao transporte; erros de cada chamada ficam em ResultadoLote.Err.
+func (l *Lote) Executar() error {
+	return l.ExecutarCtx(context.Background())
+}
+
+// ExecutarCtx é a variante de Executar que aceita um contexto. Chamadas com
+// resultado em cache não são enviadas ao servidor.
+func (l *Lote) ExecutarCtx(ctx context.Context) error {
+	c := l.cliente
+	respostas := make([]RespostaAPI, len(l.pedidos))
+	chaves := make([]string, len(l.pedidos))
+
+	var pendentes []*pedidoRPC
+	var indices []int
+	for i, pedido := range l.pedidos {
+		if c.ttlCache(ctx, pedido.Metodo) > 0 {
+			chaves[i], _ = c.chaveCache("chamada", pedido.Metodo, pedido.Params)
+			if resultado, ok := c.config.Cache.Get(chaves[i]); chaves[i] != "" && ok {
+				respostas[i] = RespostaAPI{Jsonrpc: "2.0", Result: resultado.(json.RawMessage), ID: pedido.ID}
+				continue
+			}
+		}
+		pendentes = append(pendentes, pedido)
+		indices = append(indices, i)
+	}
+
+	if len(pendentes) > 0 {
+		enviadas, err := c.executar(ctx, pendentes)
+		if err != nil {
+			return err
+		}
+		for j, resposta := range enviadas {
+			i := indices[j]
+			respostas[i] = resposta
+			if chaves[i] != "" && resposta.Error == nil {
+				c.config.Cache.Set(chaves[i], resposta.Result, c.ttlCache(ctx, l.pedidos[i].Metodo))
+			}
+		}
+	}
+
+	for i := range respostas {
+		l.destinos[i](&respostas[i])
+	}
+	return nil
+}
+
+// decodificarResultado converte o campo result da resposta para o destino.
+// Erros JSON-RPC são devolvidos como *ErroAPI.
+func decodificarResultado(metodo string, resposta *RespostaAPI, destino interface{}) error {
+	if resposta.Error != nil {
+		return erroDaResposta(metodo, resposta)
+	}
+
+	if err := json.Unmarshal(resposta.Result, destino); err != nil {
+		return &ErroTransporte{Operacao: "decodificação do resultado de " + metodo, Err: err}
+	}
+
+	return nil
+}
+
+// erroDaResposta converte o objeto error da resposta JSON-RPC em *ErroAPI
+func erroDaResposta(metodo string, resposta *RespostaAPI) *ErroAPI {
+	erro := NovoErroAPI(resposta.Error.Code, resposta.Error.Message, resposta.Error.Data)
+	erro.Metodo = metodo
+	return erro
+}
+
+// executar envia os pedidos autenticados e devolve as respostas na mesma ordem.
+// Servidores 6.4+ recebem o token no cabeçalho Authorization; nos anteriores
+// ele segue no campo "auth" de cada pedido. Em perfis com usuário e senha,
+// uma sessão expirada é renovada automaticamente e os pedidos reenviados.
+func (c *ClienteAPI) executar(ctx context.Context, pedidos []*pedidoRPC) ([]RespostaAPI, error) {
+	versao, err := c.ObterVersaoCtx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao negociar versão da API: %w", err)
+	}
+
+	token, err := c.tokenAutenticacao(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	respostas, err := c.executarAutenticado(ctx, versao, token, pedidos)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.usaSessao() && algumaSessaoEncerrada(respostas) {
+		log.Printf("Sessão do Zabbix encerrada, realizando novo login")
+		token, err = c.renovarSessao(ctx, token)
+		if err != nil {
+			return nil, err
+		}
+		respostas, err = c.executarAutenticado(ctx, versao, token, pedidos)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Alterações feitas no servidor tornam os resultados em cache desatualizados
+	if !pedidosIdempotentes(pedidos) {
+		c.LimparCache()
+	}
+
+	return respostas, nil
+}
+
+// executarAutenticado coloca o token no local adequado à versão do servidor e envia os pedidos
+func (c *ClienteAPI) executarAutenticado(ctx context.Context, versao Versao, token string, pedidos []*pedidoRPC) ([]RespostaAPI, error) {
+	if versao.SuportaBearer() {
+		for _, pedido := range pedidos {
+			pedido.Auth = ""
+		}
+		return c.enviarPedidos(ctx, pedidos, token)
+	}
+
+	for _, pedido := range pedidos {
+		pedido.Auth = token
+	}
+	return c.enviarPedidos(ctx, pedidos, "")
+}
+
+// enviarPedidos envia um pedido isolado ou um lote e correlaciona as respostas pelo ID
+func (c *ClienteAPI) enviarPedidos(ctx context.Context, pedidos []*pedidoRPC, tokenBearer string) ([]RespostaAPI, error) {
+	idempotente := pedidosIdempotentes(pedidos)
+
+	if len(pedidos) == 1 {
+		var resposta RespostaAPI
+		if err := c.enviarComRepeticao(ctx, pedidos[0], tokenBearer, &resposta, idempotente); err != nil {
+			return nil, err
+		}
+		if err := conferirID(pedidos[0], &resposta); err != nil {
+			return nil, err
+		}
+		return []RespostaAPI{resposta}, nil
+	}
+
+	var bruto json.RawMessage
+	if err := c.enviarComRepeticao(ctx, pedidos, tokenBearer, &bruto, idempotente); err != nil {
+		return nil, err
+	}
+
+	// Um lote inválido como um todo é respondido com um único objeto de erro
+	bruto = bytes.TrimSpace(bruto)
+	if len(bruto) > 0 && bruto[0] == '{' {
+		var resposta RespostaAPI
+		if err := json.Unmarshal(bruto, &resposta); err != nil {
+			return nil, &ErroTransporte{Operacao: "decodificação da resposta", Err: err}
+		}
+		if resposta.Error != nil {
+			return nil, erroDaResposta("", &resposta)
+		}
+		return nil, &ErroTransporte{Operacao: "decodificação da resposta", Err: errors.New("resposta inesperada para requisição em lote")}
+	}
+
+	var lista []RespostaAPI
+	if err := json.Unmarshal(bruto, &lista); err != nil {
+		return nil, &ErroTransporte{Operacao: "decodificação da resposta", Err: err}
+	}
+
+	// O servidor pode responder em qualquer ordem; correlacionar pelo ID
+	porID := make(map[uint64]RespostaAPI, len(lista))
+	for _, resposta := range lista {
+		porID[resposta.ID] = resposta
+	}
+
+	respostas := make([]RespostaAPI, len(pedidos))
+	for i, pedido := range pedidos {
+		resposta, ok := porID[pedido.ID]
+		if !ok {
+			return nil, &ErroTransporte{
+				Operacao: "correlação da resposta",
+				Err:      fmt.Errorf("resposta ausente para o pedido %d (%s)", pedido.ID, pedido.Metodo),
+			}
+		}
+		respostas[i] = resposta
+	}
+
+	return respostas, nil
+}
+
+// conferirID garante que a resposta pertence ao pedido enviado. Respostas de
+// erro com ID nulo são aceitas, pois o servidor não conseguiu ler o pedido.
+func conferirID(pedido *pedidoRPC, resposta *RespostaAPI) error {
+	if resposta.ID == pedido.ID || (resposta.ID == 0 && resposta.Error != nil) {
+		return nil
+	}
+	return &ErroTransporte{
+		Operacao: "correlação da resposta",
+		Err:      fmt.Errorf("ID da resposta (%d) não corresponde ao pedido (%d)", resposta.ID, pedido.ID),
+	}
+}
+
+// enviarRequisicao faz o POST do corpo JSON-RPC e decodifica a resposta no destino.
+// Se tokenBearer não for vazio, ele é enviado no cabeçalho Authorization. Quando
+// o contexto não tem prazo, aplica-se o TempoLimite da configuração.
+func (c *ClienteAPI) enviarRequisicao(ctx context.Context, corpo interface{}, tokenBearer string, destino interface{}) error {
+	if _, temPrazo := ctx.Deadline(); !temPrazo {
+		var cancelar context.CancelFunc
+		ctx, cancelar = context.WithTimeout(ctx, c.config.TempoLimite)
+		defer cancelar()
+	}
+
+	// Converter pedido para JSON
+	pedidoBytes, err := json.Marshal(corpo)
+	if err != nil {
+		return fmt.Errorf("erro ao criar pedido JSON: %w", err)
+	}
+
+	// Criar requisição HTTP
+	apiURL := strings.TrimRight(c.config.URL, "/") + "/api_jsonrpc.php"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, bytes.NewBuffer(pedidoBytes))
+	if err != nil {
+		return fmt.Errorf("erro ao criar requisição: %w", err)
+	}
+
+	// Definir cabeçalhos; os extras do perfil não substituem os da API
+	c.aplicarCabecalhosExtras(req)
+	req.Header.Set("Content-Type", "application/json-rpc")
+	if tokenBearer != "" {
+		req.Header.Set("Authorization", "Bearer "+tokenBearer)
+	}
+
+	// Enviar requisição
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return &ErroTransporte{Operacao: "requisição", Err: err}
+	}
+	defer resp.Body.Close()
+
+	// Verificar código de status
+	if resp.StatusCode != http.StatusOK {
+		return &ErroTransporte{
+			Operacao:   "requisição",
+			StatusCode: resp.StatusCode,
+			Err:        errors.New(resp.Status),
+		}
+	}
+
+	// Respostas lidas como fluxo são decodificadas pelo próprio leitor
+	if ler, ok := destino.(leitorResposta); ok {
+		return ler(resp.Body)
+	}
+
+	// Decodificar resposta
+	err = json.NewDecoder(resp.Body).Decode(destino)
+	if err != nil {
+		return &ErroTransporte{Operacao: "decodificação da resposta", Err: err}
+	}
+
+	return nil
+}
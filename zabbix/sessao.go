@@ -0,0 +1,200 @@
+package zabbix
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// usaSessao informa se o cliente autentica com usuário e senha em vez de token
+func (c *ClienteAPI) usaSessao() bool {
+	return c.config.Token == "" && c.config.Usuario != ""
+}
+
+// tokenAutenticacao retorna o token estático ou o ID da sessão atual,
+// realizando o login na primeira vez que for necessário
+func (c *ClienteAPI) tokenAutenticacao(ctx context.Context) (string, error) {
+	if !c.usaSessao() {
+		return c.config.Token, nil
+	}
+
+	c.muSessao.Lock()
+	defer c.muSessao.Unlock()
+
+	if c.sessao == "" {
+		sessao, err := c.login(ctx)
+		if err != nil {
+			return "", err
+		}
+		c.sessao = sessao
+	}
+
+	return c.sessao, nil
+}
+
+// renovarSessao realiza um novo login se a sessão expirada ainda for a atual.
+// Se outra requisição já renovou a sessão, apenas retorna a nova.
+func (c *ClienteAPI) renovarSessao(ctx context.Context, expirada string) (string, error) {
+	c.muSessao.Lock()
+	defer c.muSessao.Unlock()
+
+	if c.sessao != "" && c.sessao != expirada {
+		return c.sessao, nil
+	}
+
+	sessao, err := c.login(ctx)
+	if err != nil {
+		c.sessao = ""
+		return "", err
+	}
+	c.sessao = sessao
+	return sessao, nil
+}
+
+// IniciarSessao realiza o login com usuário e senha. Não tem efeito em perfis com token.
+func (c *ClienteAPI) IniciarSessao() error {
+	return c.IniciarSessaoCtx(context.Background())
+}
+
+// IniciarSessaoCtx é a variante de IniciarSessao que aceita um contexto
+func (c *ClienteAPI) IniciarSessaoCtx(ctx context.Context) error {
+	_, err := c.tokenAutenticacao(ctx)
+	return err
+}
+
+// EncerrarSessao chama user.logout para a sessão atual, se houver
+func (c *ClienteAPI) EncerrarSessao() error {
+	return c.EncerrarSessaoCtx(context.Background())
+}
+
+// EncerrarSessaoCtx é a variante de EncerrarSessao que aceita um contexto
+func (c *ClienteAPI) EncerrarSessaoCtx(ctx context.Context) error {
+	if !c.usaSessao() {
+		return nil
+	}
+
+	c.muSessao.Lock()
+	sessao := c.sessao
+	c.sessao = ""
+	c.muSessao.Unlock()
+
+	if sessao == "" {
+		return nil
+	}
+
+	versao, err := c.ObterVersaoCtx(ctx)
+	if err != nil {
+		return err
+	}
+
+	respostas, err := c.executarAutenticado(ctx, versao, sessao, []*pedidoRPC{c.novoPedido("user.logout", []string{})})
+	if err != nil {
+		return err
+	}
+
+	var encerrada bool
+	if err := decodificarResultado("user.logout", &respostas[0], &encerrada); err != nil {
+		return fmt.Errorf("erro ao encerrar sessão: %w", err)
+	}
+
+	return nil
+}
+
+// UsuarioAutenticado retorna o nome do usuário que faz as chamadas: o do
+// perfil, com usuário e senha, ou o dono do token, consultado uma única vez
+// com user.checkAuthentication
+func (c *ClienteAPI) UsuarioAutenticado() (string, error) {
+	return c.UsuarioAutenticadoCtx(context.Background())
+}
+
+// UsuarioAutenticadoCtx é a variante de UsuarioAutenticado que aceita um contexto
+func (c *ClienteAPI) UsuarioAutenticadoCtx(ctx context.Context) (string, error) {
+	if c.usaSessao() {
+		return c.config.Usuario, nil
+	}
+	if c.config.Token == "" {
+		return "", nil
+	}
+
+	c.muUsuario.Lock()
+	defer c.muUsuario.Unlock()
+	if c.usuario != "" {
+		return c.usuario, nil
+	}
+
+	versao, err := c.ObterVersaoCtx(ctx)
+	if err != nil {
+		return "", fmt.Errorf("erro ao negociar versão da API: %w", err)
+	}
+
+	// Tokens de API são aceitos em "token" a partir do Zabbix 6.0; antes, o
+	// token configurado é o ID de uma sessão
+	params := map[string]string{"sessionid": c.config.Token}
+	if versao.AoMenos(6, 0) {
+		params = map[string]string{"token": c.config.Token}
+	}
+
+	// user.checkAuthentication não aceita o campo auth
+	respostas, err := c.enviarPedidos(ctx, []*pedidoRPC{c.novoPedido("user.checkAuthentication", params)}, "")
+	if err != nil {
+		return "", err
+	}
+
+	// O nome do usuário está em "alias" antes do Zabbix 5.4
+	var usuario struct {
+		Username string `json:"username"`
+		Alias    string `json:"alias"`
+	}
+	if err := decodificarResultado("user.checkAuthentication", &respostas[0], &usuario); err != nil {
+		return "", fmt.Errorf("erro ao identificar o usuário do token: %w", err)
+	}
+	c.usuario = usuario.Username
+	if c.usuario == "" {
+		c.usuario = usuario.Alias
+	}
+	return c.usuario, nil
+}
+
+// login chama user.login e retorna o ID da sessão. Deve ser chamado com muSessao travado.
+func (c *ClienteAPI) login(ctx context.Context) (string, error) {
+	versao, err := c.ObterVersaoCtx(ctx)
+	if err != nil {
+		return "", fmt.Errorf("erro ao negociar versão da API: %w", err)
+	}
+
+	// O parâmetro "user" foi renomeado para "username" no Zabbix 5.4
+	params := ParamsUserLogin{Password: c.config.Senha}
+	if versao.AoMenos(5, 4) {
+		params.Username = c.config.Usuario
+	} else {
+		params.User = c.config.Usuario
+	}
+
+	respostas, err := c.enviarPedidos(ctx, []*pedidoRPC{c.novoPedido("user.login", params)}, "")
+	if err != nil {
+		return "", err
+	}
+
+	var sessao string
+	if err := decodificarResultado("user.login", &respostas[0], &sessao); err != nil {
+		return "", fmt.Errorf("erro no login: %w", err)
+	}
+
+	return sessao, nil
+}
+
+// algumaSessaoEncerrada detecta a resposta do Zabbix para sessões expiradas ou encerradas
+func algumaSessaoEncerrada(respostas []RespostaAPI) bool {
+	for _, resposta := range respostas {
+		if resposta.Error != nil && sessaoEncerrada(resposta.Error.Message, resposta.Error.Data) {
+			return true
+		}
+	}
+	return false
+}
+
+// sessaoEncerrada reconhece a mensagem de sessão expirada do Zabbix
+func sessaoEncerrada(mensagem, detalhes string) bool {
+	return strings.Contains(detalhes, "Session terminated") ||
+		strings.Contains(mensagem, "Session terminated")
+}
@@ -0,0 +1,302 @@
+package zabbix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ErrSLANaoSuportado indica um servidor anterior ao Zabbix 6.0, sem sla.get
+var ErrSLANaoSuportado = errors.New("a API de SLA requer Zabbix 6.0 ou posterior")
+
+// ErrSLANaoEncontrado indica que o SLA pedido não existe ou não é visível
+// para o usuário do perfil
+var ErrSLANaoEncontrado = errors.New("SLA não encontrado")
+
+// Origens dos números de um relatório de disponibilidade
+const (
+	FonteSLA      = "sla"      // sla.getsli de um SLA cadastrado no Zabbix
+	FonteTriggers = "triggers" // Intervalos de problemas das triggers de disponibilidade
+)
+
+// PeriodosSLA mapeia o período de apuração de um SLA (period) para o nome exibido
+var PeriodosSLA = map[string]string{
+	"0": "Diário",
+	"1": "Semanal",
+	"2": "Mensal",
+	"3": "Trimestral",
+	"4": "Anual",
+}
+
+// SLA é um acordo de nível de serviço cadastrado no Zabbix 6.0+
+type SLA struct {
+	ID          string   `json:"slaid"`
+	Nome        string   `json:"name"`
+	Periodo     string   `json:"period"`
+	SLO         string   `json:"slo"`
+	DataEfetiva Instante `json:"effective_date"`
+	FusoHorario string   `json:"timezone"`
+	Status      string   `json:"status"`
+	Descricao   string   `json:"description"`
+}
+
+// MetaSLO retorna o SLO do SLA em porcentagem, ou zero se inválido
+func (s SLA) MetaSLO() float64 {
+	slo, _ := strconv.ParseFloat(s.SLO, 64)
+	return slo
+}
+
+// Servico é um serviço do Zabbix, avaliado pelos SLAs
+type Servico struct {
+	ID   string `json:"serviceid"`
+	Nome string `json:"name"`
+}
+
+// resultadoSLI é a resposta de sla.getsli. SLI tem uma linha por período e,
+// em cada uma, um valor por serviço na ordem de ServicoIDs.
+type resultadoSLI struct {
+	Periodos []struct {
+		Inicio int64 `json:"period_from"`
+		Fim    int64 `json:"period_to"`
+	} `json:"periods"`
+	ServicoIDs []string `json:"serviceids"`
+	SLI        [][]struct {
+		TempoAtivo        int64   `json:"uptime"`
+		TempoIndisponivel int64   `json:"downtime"`
+		SLI               float64 `json:"sli"`
+	} `json:"sli"`
+}
+
+// Disponibilidade é a disponibilidade de um serviço, host ou grupo em um período
+type Disponibilidade struct {
+	ID                string
+	Nome              string
+	Percentual        float64
+	TempoAtivo        time.Duration
+	TempoIndisponivel time.Duration
+	Hosts             int // Hosts considerados, apenas em grupos
+}
+
+// CumpreSLO informa se a disponibilidade atinge a meta, em porcentagem
+func (d Disponibilidade) CumpreSLO(slo float64) bool {
+	return d.Percentual >= slo
+}
+
+// RelatorioDisponibilidade reúne a disponibilidade apurada em [Inicio, Fim).
+// Com FonteSLA são preenchidos SLA e Servicos; com FonteTriggers, Hosts e Grupos.
+type RelatorioDisponibilidade struct {
+	Inicio   time.Time
+	Fim      time.Time
+	Fonte    string
+	SLA      *SLA
+	Servicos []Disponibilidade
+	Hosts    []Disponibilidade
+	Grupos   []Disponibilidade
+}
+
+// ObterSLAs lista os SLAs cadastrados no servidor
+func (c *ClienteAPI) ObterSLAs() ([]SLA, error) {
+	return c.ObterSLAsCtx(context.Background())
+}
+
+// ObterSLAsCtx é a variante de ObterSLAs que aceita um contexto
+func (c *ClienteAPI) ObterSLAsCtx(ctx context.Context) ([]SLA, error) {
+	versao, err := c.ObterVersaoCtx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao negociar versão da API: %w", err)
+	}
+	if !versao.AoMenos(6, 0) {
+		return nil, ErrSLANaoSuportado
+	}
+
+	return ChamarCtx[[]SLA](ctx, c, "sla.get", ParamsSLAGet{
+		Output:    []string{"slaid", "name", "period", "slo", "effective_date", "timezone", "status", "description"},
+		SortField: []string{"name"},
+	})
+}
+
+// DisponibilidadeSLA apura a disponibilidade dos serviços de um SLA em
+// [inicio, fim) com sla.getsli. Os períodos do SLA que tocam o intervalo são
+// somados por inteiro, pois o Zabbix só calcula o SLI de períodos completos.
+func (c *ClienteAPI) DisponibilidadeSLA(slaID string, inicio, fim time.Time) (RelatorioDisponibilidade, error) {
+	return c.DisponibilidadeSLACtx(context.Background(), slaID, inicio, fim)
+}
+
+// DisponibilidadeSLACtx é a variante de DisponibilidadeSLA que aceita um contexto
+func (c *ClienteAPI) DisponibilidadeSLACtx(ctx context.Context, slaID string, inicio, fim time.Time) (RelatorioDisponibilidade, error) {
+	relatorio := RelatorioDisponibilidade{Inicio: inicio, Fim: fim, Fonte: FonteSLA}
+
+	slas, err := c.ObterSLAsCtx(ctx)
+	if err != nil {
+		return relatorio, err
+	}
+	for i := range slas {
+		if slas[i].ID == slaID {
+			relatorio.SLA = &slas[i]
+			break
+		}
+	}
+	if relatorio.SLA == nil {
+		return relatorio, ErrSLANaoEncontrado
+	}
+
+	sli, err := ChamarCtx[resultadoSLI](ctx, c, "sla.getsli", ParamsSLIGet{
+		SLAID:      slaID,
+		PeriodFrom: inicio.Unix(),
+		PeriodTo:   fim.Unix(),
+	})
+	if err != nil {
+		return relatorio, err
+	}
+	if len(sli.ServicoIDs) == 0 {
+		return relatorio, nil
+	}
+
+	servicos, err := ChamarCtx[[]Servico](ctx, c, "service.get", ParamsServiceGet{
+		Output:     []string{"serviceid", "name"},
+		ServiceIDs: sli.ServicoIDs,
+	})
+	if err != nil {
+		return relatorio, err
+	}
+	nomes := make(map[string]string, len(servicos))
+	for _, s := range servicos {
+		nomes[s.ID] = s.Nome
+	}
+
+	for i, servicoID := range sli.ServicoIDs {
+		d := Disponibilidade{ID: servicoID, Nome: nomes[servicoID]}
+		for _, periodo := range sli.SLI {
+			if i >= len(periodo) {
+				continue
+			}
+			d.TempoAtivo += time.Duration(periodo[i].TempoAtivo) * time.Second
+			d.TempoIndisponivel += time.Duration(periodo[i].TempoIndisponivel) * time.Second
+		}
+		d.Percentual = percentualDisponivel(d.TempoAtivo, d.TempoIndisponivel)
+		relatorio.Servicos = append(relatorio.Servicos, d)
+	}
+	ordenarDisponibilidades(relatorio.Servicos)
+
+	return relatorio, nil
+}
+
+// DisponibilidadeTriggers apura a disponibilidade dos hosts monitorados e dos
+// seus grupos em [inicio, fim) a partir dos problemas cujos eventos têm as
+// tags informadas. Um host fica indisponível enquanto houver ao menos um
+// desses problemas aberto. Serve para servidores sem SLAs cadastrados.
+func (c *ClienteAPI) DisponibilidadeTriggers(tags []FiltroTag, inicio, fim time.Time) (RelatorioDisponibilidade, error) {
+	return c.DisponibilidadeTriggersCtx(context.Background(), tags, inicio, fim)
+}
+
+// DisponibilidadeTriggersCtx é a variante de DisponibilidadeTriggers que aceita um contexto
+func (c *ClienteAPI) DisponibilidadeTriggersCtx(ctx context.Context, tags []FiltroTag, inicio, fim time.Time) (RelatorioDisponibilidade, error) {
+	if !fim.After(inicio) {
+		return RelatorioDisponibilidade{}, fmt.Errorf("período de disponibilidade vazio: %s a %s", inicio.Format("02/01/2006 15:04"), fim.Format("02/01/2006 15:04"))
+	}
+
+	var hosts []Host
+	err := c.PercorrerHostsCtx(ctx, ParamsHostGet{
+		Output:           []string{"hostid", "host", "name"},
+		MonitoredHosts:   true,
+		SelectHostGroups: []string{"groupid", "name"},
+	}, 0, func(h Host) error {
+		hosts = append(hosts, h)
+		return nil
+	})
+	if err != nil {
+		return RelatorioDisponibilidade{}, err
+	}
+
+	ocorrencias, err := c.obterOcorrencias(ctx, inicio.Add(-JanelaAnteriorAnalise), fim, tags)
+	if err != nil {
+		return RelatorioDisponibilidade{}, err
+	}
+
+	return DisponibilidadeOcorrencias(hosts, ocorrencias, inicio, fim, time.Now()), nil
+}
+
+// DisponibilidadeOcorrencias calcula a disponibilidade de cada host em
+// [inicio, fim), limitado a agora em períodos ainda em andamento, e a de cada
+// grupo como a média dos seus hosts. Hosts sem ocorrências ficam com 100%.
+func DisponibilidadeOcorrencias(hosts []Host, ocorrencias []OcorrenciaProblema, inicio, fim, agora time.Time) RelatorioDisponibilidade {
+	relatorio := RelatorioDisponibilidade{Inicio: inicio, Fim: fim, Fonte: FonteTriggers}
+
+	limite := fim
+	if agora.Before(limite) {
+		limite = agora
+	}
+	apurado := limite.Sub(inicio)
+	if apurado < 0 {
+		apurado = 0
+	}
+
+	intervalos := make(map[string][][2]time.Time)
+	for _, o := range ocorrencias {
+		if comeco, termino, dentro := o.intervaloNoPeriodo(inicio, fim, agora); dentro {
+			intervalos[o.HostID] = append(intervalos[o.HostID], [2]time.Time{comeco, termino})
+		}
+	}
+
+	type acumuladorGrupo struct {
+		Disponibilidade
+		soma float64
+	}
+	grupos := make(map[string]*acumuladorGrupo)
+
+	for _, h := range hosts {
+		indisponivel := duracaoUniao(intervalos[h.ID])
+		d := Disponibilidade{
+			ID:                h.ID,
+			Nome:              h.NomeExibicao(),
+			TempoAtivo:        apurado - indisponivel,
+			TempoIndisponivel: indisponivel,
+		}
+		d.Percentual = percentualDisponivel(d.TempoAtivo, d.TempoIndisponivel)
+		relatorio.Hosts = append(relatorio.Hosts, d)
+
+		for _, g := range h.Grupos {
+			grupo, ok := grupos[g.ID]
+			if !ok {
+				grupo = &acumuladorGrupo{Disponibilidade: Disponibilidade{ID: g.ID, Nome: g.Nome}}
+				grupos[g.ID] = grupo
+			}
+			grupo.Hosts++
+			grupo.soma += d.Percentual
+			grupo.TempoAtivo += d.TempoAtivo
+			grupo.TempoIndisponivel += d.TempoIndisponivel
+		}
+	}
+
+	for _, grupo := range grupos {
+		grupo.Percentual = grupo.soma / float64(grupo.Hosts)
+		relatorio.Grupos = append(relatorio.Grupos, grupo.Disponibilidade)
+	}
+
+	ordenarDisponibilidades(relatorio.Hosts)
+	ordenarDisponibilidades(relatorio.Grupos)
+	return relatorio
+}
+
+// percentualDisponivel retorna a fração do tempo apurado em que o alvo esteve
+// disponível, em porcentagem; sem tempo apurado o alvo conta como disponível
+func percentualDisponivel(ativo, indisponivel time.Duration) float64 {
+	total := ativo + indisponivel
+	if total <= 0 {
+		return 100
+	}
+	return 100 * float64(ativo) / float64(total)
+}
+
+// ordenarDisponibilidades coloca os alvos menos disponíveis primeiro
+func ordenarDisponibilidades(lista []Disponibilidade) {
+	sort.Slice(lista, func(i, j int) bool {
+		if lista[i].Percentual != lista[j].Percentual {
+			return lista[i].Percentual < lista[j].Percentual
+		}
+		return lista[i].Nome < lista[j].Nome
+	})
+}
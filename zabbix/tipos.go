@@ -1,78 +1,229 @@
-
 package zabbix
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"strconv"
 	"time"
 )
 
+// Instante é um horário devolvido pela API em segundos desde 1970. O Zabbix
+// envia o valor como texto ("1700000000") na maioria dos métodos e como número
+// em alguns; "0", vazio e null indicam que o horário não foi definido e viram
+// o valor zero de time.Time.
+type Instante struct {
+	time.Time
+}
+
+// NovoInstante cria um Instante a partir de segundos desde 1970; 0 é o valor zero
+func NovoInstante(segundos int64) Instante {
+	if segundos == 0 {
+		return Instante{}
+	}
+	return Instante{time.Unix(segundos, 0)}
+}
+
+// UnmarshalJSON aceita o horário como texto ou número de segundos
+func (i *Instante) UnmarshalJSON(dados []byte) error {
+	dados = bytes.TrimSpace(dados)
+	if len(dados) > 0 && dados[0] == '"' {
+		var texto string
+		if err := json.Unmarshal(dados, &texto); err != nil {
+			return err
+		}
+		dados = []byte(texto)
+	}
+
+	texto := string(dados)
+	if texto == "" || texto == "null" {
+		*i = Instante{}
+		return nil
+	}
+
+	segundos, err := strconv.ParseInt(texto, 10, 64)
+	if err != nil {
+		return fmt.Errorf("horário inválido %q: esperados segundos desde 1970", texto)
+	}
+	*i = NovoInstante(segundos)
+	return nil
+}
+
+// MarshalJSON escreve o horário como a API o envia, em segundos como texto
+func (i Instante) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strconv.FormatInt(i.Segundos(), 10))
+}
+
+// Segundos retorna o horário em segundos desde 1970, ou 0 se não definido
+func (i Instante) Segundos() int64 {
+	if i.IsZero() {
+		return 0
+	}
+	return i.Unix()
+}
+
 type Item struct {
-	ID              string `json:"itemid"`
-	Nome            string `json:"name"`
-	Status          string `json:"status"`
-	Estado          string `json:"state"`
-	UltimoValor     string `json:"lastvalue"`
-	UltimaAlteracao string `json:"lastchange"`
+	ID              string   `json:"itemid"`
+	HostID          string   `json:"hostid"`
+	Nome            string   `json:"name"`
+	Chave           string   `json:"key_"`
+	Status          string   `json:"status"`
+	Estado          string   `json:"state"`
+	Erro            string   `json:"error"`
+	UltimoValor     string   `json:"lastvalue"`
+	ValorAnterior   string   `json:"prevvalue"`
+	Unidades        string   `json:"units"`
+	TipoValor       string   `json:"value_type"`
+	UltimaColeta    Instante `json:"lastclock"`
+	UltimaAlteracao Instante `json:"lastchange"`
+	Hosts           []Host   `json:"hosts,omitempty"`
 }
 
 type Trigger struct {
-	ID              string `json:"triggerid"`
-	Nome            string `json:"description"`
-	Status          string `json:"status"`
-	Valor           string `json:"value"`
-	Prioridade      string `json:"priority"`
-	UltimaAlteracao string `json:"lastchange"`
+	ID              string   `json:"triggerid"`
+	Nome            string   `json:"description"`
+	Status          string   `json:"status"`
+	Estado          string   `json:"state"`
+	Erro            string   `json:"error"`
+	Valor           string   `json:"value"`
+	Prioridade      string   `json:"priority"`
+	UltimaAlteracao Instante `json:"lastchange"`
+	Hosts           []Host   `json:"hosts,omitempty"`
 }
 
 type Host struct {
-	ID         string      `json:"hostid"`
-	Nome       string      `json:"host"`
-	Status     string      `json:"status"`
-	Items      []Item      `json:"items"`
-	Triggers   []Trigger   `json:"triggers"`
-	Interfaces []Interface `json:"interfaces"`
+	ID          string              `json:"hostid"`
+	Nome        string              `json:"host"`
+	NomeVisivel string              `json:"name"`
+	Status      string              `json:"status"`
+	Items       []Item              `json:"items"`
+	Triggers    []Trigger           `json:"triggers"`
+	Interfaces  []Interface         `json:"interfaces"`
+	Grupos      []GrupoHost         `json:"hostgroups"`
+	Templates   []TemplateVinculado `json:"parentTemplates"`
+	Tags        []Tag               `json:"tags"`
+}
+
+// UnmarshalJSON aceita os grupos em "groups", nome usado antes do Zabbix 6.2
+func (h *Host) UnmarshalJSON(dados []byte) error {
+	type hostJSON Host
+	var bruto struct {
+		hostJSON
+		GruposAntigos []GrupoHost `json:"groups"`
+	}
+	if err := json.Unmarshal(dados, &bruto); err != nil {
+		return err
+	}
+
+	*h = Host(bruto.hostJSON)
+	if h.Grupos == nil {
+		h.Grupos = bruto.GruposAntigos
+	}
+	return nil
+}
+
+// NomeExibicao retorna o nome visível do host ou, se vazio, o nome técnico
+func (h Host) NomeExibicao() string {
+	if h.NomeVisivel != "" {
+		return h.NomeVisivel
+	}
+	return h.Nome
 }
 
 type Interface struct {
-	ID    string `json:"interfaceid"`
-	Tipo  string `json:"type"`
-	IP    string `json:"ip"`
-	DNS   string `json:"dns"`
-	Porta string `json:"port"`
+	ID        string `json:"interfaceid"`
+	Tipo      string `json:"type"`
+	Principal string `json:"main"`
+	IP        string `json:"ip"`
+	DNS       string `json:"dns"`
+	Porta     string `json:"port"`
+
+	// Disponibilidade da interface: 0 desconhecida, 1 disponível, 2 indisponível.
+	// Antes do Zabbix 5.4 ela era informada no host e é copiada para cá.
+	Disponivel string `json:"available"`
+	Erro       string `json:"error"`
+}
+
+// TiposInterface mapeia o tipo da interface para o nome exibido
+var TiposInterface = map[string]string{
+	"1": "Agente",
+	"2": "SNMP",
+	"3": "IPMI",
+	"4": "JMX",
+}
+
+// Endereco retorna o IP ou o DNS da interface seguido da porta
+func (i Interface) Endereco() string {
+	endereco := i.IP
+	if endereco == "" {
+		endereco = i.DNS
+	}
+	if i.Porta != "" {
+		endereco += ":" + i.Porta
+	}
+	return endereco
+}
+
+// GrupoHost é um grupo de hosts (hostgroup)
+type GrupoHost struct {
+	ID   string `json:"groupid"`
+	Nome string `json:"name"`
+}
+
+// TemplateVinculado é um template ligado diretamente ao host
+type TemplateVinculado struct {
+	ID   string `json:"templateid"`
+	Nome string `json:"name"`
+}
+
+// Tag é uma tag de host, trigger ou evento
+type Tag struct {
+	Nome  string `json:"tag" yaml:"tag"`
+	Valor string `json:"value" yaml:"value"`
+}
+
+// String formata a tag como "nome=valor", ou apenas "nome" sem valor
+func (t Tag) String() string {
+	if t.Valor == "" {
+		return t.Nome
+	}
+	return t.Nome + "=" + t.Valor
 }
 
 type Problema struct {
-	ID          string    `json:"eventid"`
-	Nome        string    `json:"name"`
-	Severidade  string    `json:"severity"`
-	DataInicio  time.Time `json:"clock"`
-	DataFim     time.Time `json:"r_clock"`
-	Duracao     string    `json:"duration"`
-	HostID      string    `json:"hostid"`
-	TriggerID   string    `json:"triggerid"`
-	Valor       string    `json:"value"`
-	Hosts       []Host    `json:"hosts"`
+	ID         string   `json:"eventid"`
+	Nome       string   `json:"name"`
+	Severidade string   `json:"severity"`
+	DataInicio Instante `json:"clock"`
+	DataFim    Instante `json:"r_clock"`
+	Duracao    string   `json:"duration"`
+	HostID     string   `json:"hostid"`
+	TriggerID  string   `json:"objectid"`
+	Valor      string   `json:"value"`
+	Hosts      []Host   `json:"hosts"`
 }
 
 type Evento struct {
 	ID             string          `json:"eventid"`
 	Nome           string          `json:"name"`
-	Clock          string          `json:"clock"`
+	Clock          Instante        `json:"clock"`
 	Valor          string          `json:"value"`
 	Severidade     string          `json:"severity"`
 	Reconhecido    string          `json:"acknowledged"`
 	HostID         string          `json:"hostid"`
 	ObjetoID       string          `json:"objectid"`
+	RecuperacaoID  string          `json:"r_eventid"`
+	Hosts          []Host          `json:"hosts,omitempty"`
 	TipoObjeto     string          `json:"object"`
 	ObjetoRelativo json.RawMessage `json:"relatedObject"`
 }
 
+// AnaliseMensal resume os problemas de um host em um período de análise
 type AnaliseMensal struct {
 	HostID              string
 	HostNome            string
 	TotalProblemas      int
 	ProblemasPorTrigger map[string]int
-	TempoIndisponivel   time.Duration
 	LimitesExcedidos    int
 	PicoTrigger         struct {
 		Nome      string
@@ -80,4 +231,28 @@ type AnaliseMensal struct {
 		Contagem  int
 		Gravidade string
 	}
+
+	// Problemas iniciados no período que continuavam abertos no seu fim
+	ProblemasAbertos int
+	// Tempo até a recuperação dos problemas iniciados e resolvidos no período
+	DuracaoMedia   time.Duration
+	DuracaoMediana time.Duration
+	// Soma das durações dos problemas dentro do período; problemas simultâneos somam
+	TempoTotal time.Duration
+	// Tempo com ao menos um problema aberto dentro do período
+	TempoIndisponivel time.Duration
+	// Métricas de cada trigger, da mais indisponível para a menos
+	Triggers []MetricasTrigger
+}
+
+// MetricasTrigger resume os problemas de uma trigger em um período de análise
+type MetricasTrigger struct {
+	TriggerID         string
+	Nome              string
+	Severidade        string
+	TotalProblemas    int
+	ProblemasAbertos  int
+	DuracaoMedia      time.Duration
+	DuracaoMediana    time.Duration
+	TempoIndisponivel time.Duration
 }
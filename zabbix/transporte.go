@@ -0,0 +1,272 @@
+package zabbix
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// versoesTLS associa os valores aceitos em VersaoTLSMinima às constantes do crypto/tls
+var versoesTLS = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// novoTransporte cria o transporte HTTP do cliente com as opções de TLS e de
+// proxy do perfil. Erros de configuração são devolvidos como *ErroTransporte.
+func novoTransporte(config ConfigAPI) (*http.Transport, error) {
+	configTLS, err := novaConfigTLS(config)
+	if err != nil {
+		return nil, &ErroTransporte{Operacao: "configuração TLS", Err: err}
+	}
+
+	if err := ValidarCabecalhos(config.CabecalhosExtras); err != nil {
+		return nil, &ErroTransporte{Operacao: "configuração dos cabeçalhos", Err: err}
+	}
+
+	transporte := http.DefaultTransport.(*http.Transport).Clone()
+	transporte.TLSClientConfig = configTLS
+
+	if config.Proxy != "" {
+		proxy, err := ValidarProxy(config.Proxy)
+		if err != nil {
+			return nil, &ErroTransporte{Operacao: "configuração do proxy", Err: err}
+		}
+		semProxy := config.SemProxy
+		transporte.Proxy = func(req *http.Request) (*url.URL, error) {
+			if dispensaProxy(req.URL, semProxy) {
+				return nil, nil
+			}
+			return proxy, nil
+		}
+	}
+
+	return transporte, nil
+}
+
+// ValidarProxy interpreta a URL do proxy, aceitando os esquemas suportados pelo net/http
+func ValidarProxy(endereco string) (*url.URL, error) {
+	proxy, err := url.Parse(endereco)
+	if err != nil {
+		return nil, fmt.Errorf("URL do proxy inválida: %w", err)
+	}
+
+	switch proxy.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("esquema de proxy não suportado: %q (use http, https ou socks5)", proxy.Scheme)
+	}
+	if proxy.Host == "" {
+		return nil, errors.New("URL do proxy sem host")
+	}
+
+	return proxy, nil
+}
+
+// dispensaProxy informa se o destino consta da lista de exceções. Cada entrada
+// pode ser "*", um host, um domínio iniciado por "." ou "*.", um IP ou uma rede CIDR.
+func dispensaProxy(destino *url.URL, semProxy []string) bool {
+	host := strings.ToLower(destino.Hostname())
+	ip := net.ParseIP(host)
+
+	for _, entrada := range semProxy {
+		entrada = strings.ToLower(strings.TrimSpace(entrada))
+		switch {
+		case entrada == "":
+			continue
+		case entrada == "*":
+			return true
+		case strings.Contains(entrada, "/"):
+			if _, rede, err := net.ParseCIDR(entrada); err == nil && ip != nil && rede.Contains(ip) {
+				return true
+			}
+		case strings.HasPrefix(entrada, "*."), strings.HasPrefix(entrada, "."):
+			dominio := strings.TrimPrefix(entrada, "*")
+			if strings.HasSuffix(host, dominio) || host == dominio[1:] {
+				return true
+			}
+		default:
+			if entradaIP := net.ParseIP(entrada); entradaIP != nil {
+				if ip != nil && entradaIP.Equal(ip) {
+					return true
+				}
+			} else if host == entrada {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// ValidarCabecalhos confere se os nomes dos cabeçalhos extras são válidos em HTTP
+func ValidarCabecalhos(cabecalhos map[string]string) error {
+	for nome, valor := range cabecalhos {
+		if nome == "" || strings.IndexFunc(nome, func(r rune) bool {
+			return r <= ' ' || r >= 0x7f || strings.ContainsRune("\"(),/:;<=>?@[\\]{}", r)
+		}) >= 0 {
+			return fmt.Errorf("nome de cabeçalho inválido: %q", nome)
+		}
+		if strings.ContainsAny(valor, "\r\n") {
+			return fmt.Errorf("valor do cabeçalho %s contém quebra de linha", nome)
+		}
+	}
+	return nil
+}
+
+// aplicarCabecalhosExtras adiciona à requisição os cabeçalhos configurados no perfil
+func (c *ClienteAPI) aplicarCabecalhosExtras(req *http.Request) {
+	for nome, valor := range c.config.CabecalhosExtras {
+		req.Header.Set(nome, valor)
+	}
+}
+
+// novaConfigTLS carrega a CA, o certificado do cliente e a versão mínima configurados
+func novaConfigTLS(config ConfigAPI) (*tls.Config, error) {
+	configTLS := &tls.Config{
+		InsecureSkipVerify: config.IgnorarVerificacaoTLS,
+	}
+
+	if config.VersaoTLSMinima != "" {
+		versao, ok := versoesTLS[config.VersaoTLSMinima]
+		if !ok {
+			return nil, fmt.Errorf("versão mínima de TLS inválida: %q (use 1.0, 1.1, 1.2 ou 1.3)", config.VersaoTLSMinima)
+		}
+		configTLS.MinVersion = versao
+	}
+
+	if config.ArquivoCA != "" {
+		conteudo, err := os.ReadFile(config.ArquivoCA)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao ler arquivo da CA: %w", err)
+		}
+
+		// A CA do perfil complementa as CAs do sistema
+		cas, err := x509.SystemCertPool()
+		if err != nil {
+			cas = x509.NewCertPool()
+		}
+		if !cas.AppendCertsFromPEM(conteudo) {
+			return nil, fmt.Errorf("nenhum certificado PEM encontrado em %s", config.ArquivoCA)
+		}
+		configTLS.RootCAs = cas
+	}
+
+	if config.CertificadoCliente != "" || config.ChaveCliente != "" {
+		if config.CertificadoCliente == "" || config.ChaveCliente == "" {
+			return nil, errors.New("informe o certificado e a chave do cliente")
+		}
+		certificado, err := tls.LoadX509KeyPair(config.CertificadoCliente, config.ChaveCliente)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao carregar certificado do cliente: %w", err)
+		}
+		configTLS.Certificates = []tls.Certificate{certificado}
+	}
+
+	return configTLS, nil
+}
+
+// TestarTLS verifica a conexão HTTPS com o frontend antes de chamar a API,
+// separando problemas de certificado de erros da própria API
+func (c *ClienteAPI) TestarTLS() error {
+	return c.TestarTLSCtx(context.Background())
+}
+
+// TestarTLSCtx é a variante de TestarTLS que aceita um contexto. Não faz nada
+// quando a URL não usa HTTPS.
+func (c *ClienteAPI) TestarTLSCtx(ctx context.Context) error {
+	if c.erroTransporte != nil {
+		return c.erroTransporte
+	}
+	if !strings.HasPrefix(strings.ToLower(c.config.URL), "https://") {
+		return nil
+	}
+
+	if _, temPrazo := ctx.Deadline(); !temPrazo {
+		var cancelar context.CancelFunc
+		ctx, cancelar = context.WithTimeout(ctx, c.config.TempoLimite)
+		defer cancelar()
+	}
+
+	// Qualquer resposta HTTP indica que o handshake TLS foi concluído
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, c.config.URL, nil)
+	if err != nil {
+		return fmt.Errorf("erro ao criar requisição: %w", err)
+	}
+	c.aplicarCabecalhosExtras(req)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return &ErroTransporte{Operacao: "verificação TLS", Err: err}
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// DescreverErroTLS explica em linguagem direta um erro de certificado ou de
+// handshake TLS. O segundo retorno é falso quando o erro não é de TLS.
+func DescreverErroTLS(err error) (string, bool) {
+	var autoridadeDesconhecida x509.UnknownAuthorityError
+	if errors.As(err, &autoridadeDesconhecida) {
+		emissor := "desconhecido"
+		if autoridadeDesconhecida.Cert != nil {
+			emissor = autoridadeDesconhecida.Cert.Issuer.String()
+		}
+		return fmt.Sprintf("o certificado do servidor foi emitido por uma CA não confiável (%s). Informe o arquivo da CA no perfil.", emissor), true
+	}
+
+	var nomeIncorreto x509.HostnameError
+	if errors.As(err, &nomeIncorreto) {
+		validos := "nenhum nome"
+		if nomeIncorreto.Certificate != nil && len(nomeIncorreto.Certificate.DNSNames) > 0 {
+			validos = strings.Join(nomeIncorreto.Certificate.DNSNames, ", ")
+		}
+		return fmt.Sprintf("o certificado do servidor não é válido para %s (válido para: %s). Use na URL o nome que consta no certificado.", nomeIncorreto.Host, validos), true
+	}
+
+	var certificadoInvalido x509.CertificateInvalidError
+	if errors.As(err, &certificadoInvalido) {
+		if certificadoInvalido.Reason == x509.Expired {
+			return fmt.Sprintf("o certificado do servidor está expirado ou ainda não é válido (%s).", certificadoInvalido.Detail), true
+		}
+		return fmt.Sprintf("o certificado do servidor é inválido: %v.", certificadoInvalido), true
+	}
+
+	// O net/http troca o tls.RecordHeaderError por uma mensagem própria
+	var cabecalhoInvalido tls.RecordHeaderError
+	texto := err.Error()
+	if errors.As(err, &cabecalhoInvalido) || strings.Contains(texto, "server gave HTTP response to HTTPS client") {
+		return "o servidor não respondeu com TLS. Verifique se a URL deveria usar http:// em vez de https://.", true
+	}
+
+	// Alertas enviados pelo servidor não têm tipo exportado para conexões TCP
+	switch {
+	case strings.Contains(texto, "tls: certificate required"):
+		return "o servidor exige um certificado de cliente (TLS mútuo). Informe o certificado e a chave no perfil.", true
+	case strings.Contains(texto, "tls: bad certificate"),
+		strings.Contains(texto, "tls: unknown certificate authority"),
+		strings.Contains(texto, "tls: unknown certificate"):
+		return "o servidor recusou o certificado de cliente informado.", true
+	case strings.Contains(texto, "tls: protocol version not supported"),
+		strings.Contains(texto, "tls: no supported versions"):
+		return "o servidor não aceita a versão mínima de TLS configurada.", true
+	case strings.Contains(texto, "tls: handshake failure"):
+		return "o handshake TLS falhou; o servidor pode exigir certificado de cliente ou cifras diferentes.", true
+	}
+
+	var erroTransporte *ErroTransporte
+	if errors.As(err, &erroTransporte) && erroTransporte.Operacao == "configuração TLS" {
+		return erroTransporte.Err.Error(), true
+	}
+
+	return "", false
+}
@@ -0,0 +1,57 @@
+package zabbix
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Versao representa a versão da API informada por apiinfo.version
+type Versao struct {
+	Maior    int
+	Menor    int
+	Correcao int
+}
+
+// ParseVersao interpreta uma versão no formato "6.4.0" (também aceita "7.0.0rc1")
+func ParseVersao(texto string) (Versao, error) {
+	partes := strings.SplitN(strings.TrimSpace(texto), ".", 3)
+	if len(partes) < 2 {
+		return Versao{}, fmt.Errorf("versão inválida: %q", texto)
+	}
+
+	var numeros [3]int
+	for i, parte := range partes {
+		// Descartar sufixos como "rc1" ou "beta2"
+		fim := 0
+		for fim < len(parte) && parte[fim] >= '0' && parte[fim] <= '9' {
+			fim++
+		}
+		n, err := strconv.Atoi(parte[:fim])
+		if err != nil {
+			return Versao{}, fmt.Errorf("versão inválida: %q", texto)
+		}
+		numeros[i] = n
+	}
+
+	return Versao{Maior: numeros[0], Menor: numeros[1], Correcao: numeros[2]}, nil
+}
+
+// String retorna a versão no formato "maior.menor.correcao"
+func (v Versao) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Maior, v.Menor, v.Correcao)
+}
+
+// AoMenos informa se a versão é igual ou posterior a maior.menor
+func (v Versao) AoMenos(maior, menor int) bool {
+	if v.Maior != maior {
+		return v.Maior > maior
+	}
+	return v.Menor >= menor
+}
+
+// SuportaBearer informa se o servidor aceita o token no cabeçalho Authorization.
+// O Zabbix 6.4 passou a aceitar o cabeçalho e marcou o campo "auth" como obsoleto.
+func (v Versao) SuportaBearer() bool {
+	return v.AoMenos(6, 4)
+}